@@ -7,10 +7,19 @@ package main
 
 import (
 	"commentTree/internal/app"
+	domain "commentTree/internal/app/domain"
 	"commentTree/internal/config"
 	"commentTree/internal/di"
+	"commentTree/internal/events"
+	"commentTree/internal/filters"
+	"commentTree/internal/jobs"
+	"commentTree/internal/notify"
+	"commentTree/internal/outbox"
+	"commentTree/internal/ratelimit"
 	"commentTree/internal/storage/db"
 	"commentTree/internal/web"
+	"fmt"
+	wbfredis "github.com/wb-go/wbf/redis"
 	wbzlog "github.com/wb-go/wbf/zlog"
 	"go.uber.org/fx"
 )
@@ -21,20 +30,201 @@ func main() {
 		fx.Provide(
 			config.NewAppConfig,
 			db.NewPostgres,
+			func(postgres *db.Postgres) di.Database {
+				return postgres
+			},
 
 			func(db *db.Postgres) app.DbProvider {
 				return db
 			},
-			app.NewCommentService,
+			func(store app.DbProvider, cfg *config.AppConfig, postgres *db.Postgres) *app.CommentService {
+				filtersCfg := cfg.FiltersConfig
+				return app.NewCommentService(store, cfg,
+					filters.NewWordFilter(filtersCfg.RejectWords, domain.FilterReject),
+					filters.NewWordFilter(filtersCfg.FlagWords, domain.FilterFlag),
+					filters.NewDomainFilter(filtersCfg.BlockedDomains),
+					filters.NewRepeatFilter(filtersCfg.MaxRepeat),
+					filters.NewDuplicateFilter(postgres, filtersCfg.DuplicateWindow),
+				)
+			},
 
 			func(service *app.CommentService) web.CommentService {
 				return service
 			},
 			web.NewCommentHandler,
+
+			func(db *db.Postgres) app.SavedSearchDbProvider {
+				return db
+			},
+			app.NewSavedSearchService,
+
+			func(service *app.SavedSearchService) web.SavedSearchService {
+				return service
+			},
+			web.NewSavedSearchHandler,
+
+			events.NewHub,
+			func(cfg *config.AppConfig, postgres *db.Postgres) events.Bus {
+				if cfg.EventBusConfig.Driver == "local" {
+					return events.NewLocalBus()
+				}
+				return db.NewNotifyBus(postgres, cfg)
+			},
+			func(hub *events.Hub) web.EventStream {
+				return hub
+			},
+			web.NewStreamHandler,
+
+			func(db *db.Postgres) app.ChangesDbProvider {
+				return db
+			},
+			func(hub *events.Hub) app.ChangeWaiter {
+				return hub
+			},
+			app.NewChangesService,
+			func(service *app.ChangesService) web.ChangesService {
+				return service
+			},
+			web.NewChangesHandler,
+
+			func(db *db.Postgres) app.WebhookDbProvider {
+				return db
+			},
+			app.NewWebhookService,
+			func(service *app.WebhookService) web.WebhookService {
+				return service
+			},
+			fx.Annotate(func(service *app.WebhookService) []jobs.Handler {
+				return service.JobHandlers()
+			}, fx.ResultTags(`group:"job_handlers,flatten"`)),
+			web.NewWebhookHandler,
+
+			func(postgres *db.Postgres) outbox.Store {
+				return postgres
+			},
+			func(store outbox.Store, cfg *config.AppConfig, bus events.Bus, webhooks *app.WebhookService) *outbox.Relay {
+				return outbox.NewRelay(store, cfg,
+					outbox.NewBusSink(bus),
+					outbox.NewWebhookSink(webhooks),
+					outbox.NewLogSink(),
+				)
+			},
+
+			func(postgres *db.Postgres) jobs.Store {
+				return postgres
+			},
+			jobs.NewQueue,
+			fx.Annotate(jobs.NewPurgeHandler, fx.ResultTags(`group:"job_handlers"`)),
+			fx.Annotate(jobs.NewPurgeSchedule, fx.ResultTags(`group:"job_schedules"`)),
+			jobs.NewPool,
+			func(queue *jobs.Queue) web.JobService {
+				return queue
+			},
+			web.NewJobHandler,
+
+			func(db *db.Postgres) app.UserDbProvider {
+				return db
+			},
+			app.NewUserService,
+			func(service *app.UserService) web.UserService {
+				return service
+			},
+			web.NewUserHandler,
+
+			func(db *db.Postgres) app.InboxDbProvider {
+				return db
+			},
+			app.NewInboxService,
+			func(service *app.InboxService) web.InboxService {
+				return service
+			},
+			web.NewInboxHandler,
+
+			func(db *db.Postgres) app.ModerationDbProvider {
+				return db
+			},
+			app.NewModerationService,
+			func(service *app.ModerationService) web.ModerationService {
+				return service
+			},
+			web.NewModerationHandler,
+
+			func(db *db.Postgres) app.ReportDbProvider {
+				return db
+			},
+			app.NewReportService,
+			func(service *app.ReportService) web.ReportService {
+				return service
+			},
+			web.NewReportHandler,
+
+			func(db *db.Postgres) app.BanDbProvider {
+				return db
+			},
+			app.NewBanService,
+			func(service *app.BanService) web.BanService {
+				return service
+			},
+			web.NewBanHandler,
+
+			func(db *db.Postgres) app.ChallengeDbProvider {
+				return db
+			},
+			app.NewChallengeService,
+			func(service *app.ChallengeService) web.ChallengeService {
+				return service
+			},
+			web.NewChallengeHandler,
+
+			func(db *db.Postgres) app.IdempotencyDbProvider {
+				return db
+			},
+			app.NewIdempotencyService,
+			func(service *app.IdempotencyService) web.IdempotencyService {
+				return service
+			},
+			web.NewIdempotency,
+
+			func(cfg *config.AppConfig) ratelimit.Limiter {
+				if cfg.RateLimitConfig.Driver == "redis" {
+					redisCfg := cfg.RedisConfig
+					client := wbfredis.New(fmt.Sprintf("%s:%d", redisCfg.Host, redisCfg.Port), redisCfg.Password, redisCfg.DB)
+					return ratelimit.NewRedisLimiter(client, cfg.RateLimitConfig.Prefix)
+				}
+				return ratelimit.NewMemoryLimiter()
+			},
+			func(service *app.CommentService) web.ThreadResolver {
+				return service
+			},
+			web.NewRateLimiter,
+
+			func(db *db.Postgres) app.NotificationDbProvider {
+				return db
+			},
+			func(queue *jobs.Queue) app.JobQueue {
+				return queue
+			},
+			func(cfg *config.AppConfig) app.Notifier {
+				return notify.NewNotifier(cfg)
+			},
+			app.NewNotificationService,
+			fx.Annotate(func(service *app.NotificationService) []jobs.Handler {
+				return service.JobHandlers()
+			}, fx.ResultTags(`group:"job_handlers,flatten"`)),
+			fx.Annotate(func(service *app.NotificationService) jobs.Schedule {
+				return service.DigestSchedule()
+			}, fx.ResultTags(`group:"job_schedules"`)),
 		),
+		// Порядок важен: OnStop выполняются в обратном порядке, поэтому сервер останавливается первым,
+		// а Postgres закрывается последним, когда пул, relay и шина уже ничего не пишут
 		fx.Invoke(
-			di.StartHTTPServer,
 			di.ClosePostgresOnStop,
+			di.RegisterSavedSearchHook,
+			di.RegisterNotificationHook,
+			di.StartEventBus,
+			di.StartOutboxRelay,
+			di.StartJobPool,
+			di.StartHTTPServer,
 		),
 	)
 
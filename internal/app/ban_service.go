@@ -0,0 +1,140 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+// BanService — баны авторов и адресов от модераторов и журнал аудита
+type BanService struct {
+	db     BanDbProvider
+	ipSalt string
+}
+
+type BanDbProvider interface {
+	SaveBan(ban *app.Ban, actorID string) error
+	RevokeBan(id, actorID string) (*app.Ban, error)
+	GetBans(activeOnly bool, page, pageSize int) ([]app.Ban, error)
+	GetCommentOrigin(id string) (*app.CommentOrigin, error)
+	GetUser(id string) (*app.User, error)
+	GetAuditLog(targetID string, page, pageSize int) ([]app.AuditEntry, error)
+}
+
+func NewBanService(db BanDbProvider, cfg *config.AppConfig) *BanService {
+	return &BanService{
+		db:     db,
+		ipSalt: cfg.BansConfig.IPSalt,
+	}
+}
+
+func banFieldError(field, code, message string) error {
+	return &app.ValidationError{Errors: []app.FieldError{{Field: field, Code: code, Message: message}}}
+}
+
+// origin находит комментарий, на который ссылается поле запроса
+func (s *BanService) origin(field, commentID string) (*app.CommentOrigin, error) {
+	if _, err := uuid.Parse(commentID); err != nil {
+		return nil, banFieldError(field, "invalid", "must be a UUID")
+	}
+	origin, err := s.db.GetCommentOrigin(commentID)
+	if errors.Is(err, app.ErrNotFound) {
+		return nil, banFieldError(field, "not_found", "comment not found")
+	}
+	return origin, err
+}
+
+// CreateBan выдает бан от имени actorID (может быть пустым)
+func (s *BanService) CreateBan(req app.BanRequest, actorID string) (*app.Ban, error) {
+	if req.Reason == "" {
+		return nil, banFieldError("reason", "required", "is required")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, banFieldError("expires_at", "invalid", "must be in the future")
+	}
+	ban := &app.Ban{
+		ID:        uuid.New(),
+		Kind:      req.Kind,
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	var origin *app.CommentOrigin
+	if req.CommentID != "" {
+		var err error
+		if origin, err = s.origin("comment_id", req.CommentID); err != nil {
+			return nil, err
+		}
+	}
+
+	switch req.Kind {
+	case app.BanAuthor:
+		switch {
+		case req.UserID != "":
+			if _, err := uuid.Parse(req.UserID); err != nil {
+				return nil, banFieldError("user_id", "invalid", "must be a UUID")
+			}
+			user, err := s.db.GetUser(req.UserID)
+			if errors.Is(err, app.ErrNotFound) {
+				return nil, banFieldError("user_id", "not_found", "user not found")
+			}
+			if err != nil {
+				return nil, err
+			}
+			ban.AuthorID = &user.ID
+		case origin != nil && origin.AuthorID != nil:
+			ban.AuthorID = origin.AuthorID
+		default:
+			return nil, banFieldError("user_id", "required", "user_id or comment_id of a signed comment is required")
+		}
+	case app.BanIP:
+		switch {
+		case req.IP != "":
+			ban.IPHash = app.HashIP(s.ipSalt, req.IP)
+		case origin != nil && origin.IPHash != "":
+			ban.IPHash = origin.IPHash
+		default:
+			return nil, banFieldError("ip", "required", "ip or comment_id of a comment with a known address is required")
+		}
+	default:
+		return nil, banFieldError("kind", "invalid", "must be author or ip")
+	}
+
+	if req.ThreadID != "" {
+		thread, err := s.origin("thread_id", req.ThreadID)
+		if err != nil {
+			return nil, err
+		}
+		ban.RootID = &thread.RootID
+	}
+
+	if err := s.db.SaveBan(ban, actorID); err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+// RevokeBan снимает бан досрочно
+func (s *BanService) RevokeBan(id, actorID string) (*app.Ban, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, app.ErrNotFound
+	}
+	return s.db.RevokeBan(id, actorID)
+}
+
+func (s *BanService) GetBans(activeOnly bool, page, pageSize int) ([]app.Ban, error) {
+	return s.db.GetBans(activeOnly, page, pageSize)
+}
+
+// GetAuditLog возвращает журнал аудита банов и списков скрытых авторов
+func (s *BanService) GetAuditLog(targetID string, page, pageSize int) ([]app.AuditEntry, error) {
+	if targetID != "" {
+		if _, err := uuid.Parse(targetID); err != nil {
+			return nil, banFieldError("target_id", "invalid", "must be a UUID")
+		}
+	}
+	return s.db.GetAuditLog(targetID, page, pageSize)
+}
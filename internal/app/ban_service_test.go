@@ -0,0 +1,88 @@
+package app
+
+import (
+	domain "commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type MockBanDb struct {
+	mock.Mock
+}
+
+func (m *MockBanDb) SaveBan(ban *domain.Ban, actorID string) error {
+	args := m.Called(ban, actorID)
+	return args.Error(0)
+}
+
+func (m *MockBanDb) RevokeBan(id, actorID string) (*domain.Ban, error) {
+	args := m.Called(id, actorID)
+	return args.Get(0).(*domain.Ban), args.Error(1)
+}
+
+func (m *MockBanDb) GetBans(activeOnly bool, page, pageSize int) ([]domain.Ban, error) {
+	args := m.Called(activeOnly, page, pageSize)
+	return args.Get(0).([]domain.Ban), args.Error(1)
+}
+
+func (m *MockBanDb) GetCommentOrigin(id string) (*domain.CommentOrigin, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.CommentOrigin), args.Error(1)
+}
+
+func (m *MockBanDb) GetUser(id string) (*domain.User, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockBanDb) GetAuditLog(targetID string, page, pageSize int) ([]domain.AuditEntry, error) {
+	args := m.Called(targetID, page, pageSize)
+	return args.Get(0).([]domain.AuditEntry), args.Error(1)
+}
+
+func TestBanService_CreateBan_FromComment(t *testing.T) {
+	mockDb := new(MockBanDb)
+	service := NewBanService(mockDb, &config.AppConfig{})
+
+	author := uuid.New()
+	origin := &domain.CommentOrigin{CommentID: uuid.New(), RootID: uuid.New(), AuthorID: &author, IPHash: "hash"}
+	mockDb.On("GetCommentOrigin", origin.CommentID.String()).Return(origin, nil)
+	mockDb.On("SaveBan", mock.MatchedBy(func(b *domain.Ban) bool {
+		return *b.AuthorID == author && *b.RootID == origin.RootID && b.IPHash == ""
+	}), "moderator").Return(nil)
+
+	expires := time.Now().Add(time.Hour)
+	ban, err := service.CreateBan(domain.BanRequest{
+		Kind:      domain.BanAuthor,
+		CommentID: origin.CommentID.String(),
+		ThreadID:  origin.CommentID.String(),
+		Reason:    "abuse",
+		ExpiresAt: &expires,
+	}, "moderator")
+	assert.NoError(t, err)
+	assert.Equal(t, "abuse", ban.Reason)
+	mockDb.AssertExpectations(t)
+}
+
+func TestBanService_CreateBan_Validation(t *testing.T) {
+	mockDb := new(MockBanDb)
+	service := NewBanService(mockDb, &config.AppConfig{})
+
+	var verr *domain.ValidationError
+	_, err := service.CreateBan(domain.BanRequest{Kind: domain.BanIP, Reason: "spam"}, "")
+	assert.ErrorAs(t, err, &verr)
+
+	past := time.Now().Add(-time.Hour)
+	_, err = service.CreateBan(domain.BanRequest{Kind: domain.BanIP, IP: "203.0.113.7", Reason: "spam", ExpiresAt: &past}, "")
+	assert.ErrorAs(t, err, &verr)
+
+	anonymous := &domain.CommentOrigin{CommentID: uuid.New(), RootID: uuid.New()}
+	mockDb.On("GetCommentOrigin", anonymous.CommentID.String()).Return(anonymous, nil)
+	_, err = service.CreateBan(domain.BanRequest{Kind: domain.BanAuthor, CommentID: anonymous.CommentID.String(), Reason: "spam"}, "")
+	assert.ErrorAs(t, err, &verr)
+	mockDb.AssertNotCalled(t, "SaveBan", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"crypto/rand"
+	"encoding/hex"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// ChallengeService выдает и проверяет задачи proof-of-work для анонимных комментариев и комментариев
+// пользователей ниже уровня доверия min_trust_level. Сложность растет с числом анонимных комментариев за последнее окно.
+type ChallengeService struct {
+	db             ChallengeDbProvider
+	comments       *CommentService
+	minTrustLevel  app.TrustLevel
+	enabled        bool
+	secret         string
+	baseDifficulty int
+	maxDifficulty  int
+	stepComments   int
+	ttl            time.Duration
+	window         time.Duration
+}
+
+type ChallengeDbProvider interface {
+	CountRecentAnonymous(since time.Time) (int, error)
+	RedeemChallenge(id string, expiresAt time.Time) (bool, error)
+}
+
+func NewChallengeService(db ChallengeDbProvider, comments *CommentService, cfg *config.AppConfig) *ChallengeService {
+	c := cfg.PowConfig
+	s := &ChallengeService{
+		db:             db,
+		comments:       comments,
+		minTrustLevel:  app.TrustLevel(c.MinTrustLevel),
+		enabled:        c.Enabled,
+		secret:         c.Secret,
+		baseDifficulty: c.BaseDifficulty,
+		maxDifficulty:  c.MaxDifficulty,
+		stepComments:   c.StepComments,
+		ttl:            c.TTL,
+		window:         c.Window,
+	}
+	if s.baseDifficulty <= 0 {
+		s.baseDifficulty = 16
+	}
+	if s.maxDifficulty < s.baseDifficulty {
+		s.maxDifficulty = s.baseDifficulty
+	}
+	if s.ttl <= 0 {
+		s.ttl = 5 * time.Minute
+	}
+	if s.window <= 0 {
+		s.window = 10 * time.Minute
+	}
+	// на уровне 0 любой только что зарегистрированный пользователь, регистрация ничего не стоит
+	if s.minTrustLevel <= 0 {
+		s.minTrustLevel = 1
+	}
+	if s.secret == "" {
+		raw := make([]byte, 32)
+		_, _ = rand.Read(raw)
+		s.secret = hex.EncodeToString(raw)
+		wbzlog.Logger.Warn().Msg("POW_SECRET is empty: proof-of-work challenges are valid only on this instance")
+	}
+	return s
+}
+
+// Required сообщает, нужна ли комментарию пользователя userID решенная задача; userID пустой для анонима
+func (s *ChallengeService) Required(userID string) (bool, error) {
+	if !s.enabled {
+		return false, nil
+	}
+	if userID == "" {
+		return true, nil
+	}
+	trust, err := s.comments.authorTrust(userID)
+	if err != nil {
+		return false, err
+	}
+	return trust.Level < s.minTrustLevel, nil
+}
+
+// Issue выпускает задачу со сложностью по текущему потоку анонимных комментариев
+func (s *ChallengeService) Issue() (*app.Challenge, error) {
+	difficulty, err := s.difficulty()
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := app.NewChallenge(s.secret, difficulty, s.ttl, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func (s *ChallengeService) difficulty() (int, error) {
+	if s.stepComments <= 0 || s.maxDifficulty == s.baseDifficulty {
+		return s.baseDifficulty, nil
+	}
+	n, err := s.db.CountRecentAnonymous(time.Now().Add(-s.window))
+	if err != nil {
+		return 0, err
+	}
+	return min(s.baseDifficulty+n/s.stepComments, s.maxDifficulty), nil
+}
+
+// Redeem проверяет решение и гасит задачу, чтобы одно решение нельзя было предъявить дважды
+func (s *ChallengeService) Redeem(token, solution string) error {
+	if token == "" {
+		return app.ErrChallengeRequired
+	}
+	challenge, err := app.ParseChallenge(s.secret, token, time.Now())
+	if err != nil {
+		return err
+	}
+	if !challenge.Solved(solution) {
+		return app.ErrChallengeInvalid
+	}
+	fresh, err := s.db.RedeemChallenge(challenge.ID, challenge.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return app.ErrChallengeInvalid
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	domain "commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type MockChallengeDb struct {
+	mock.Mock
+}
+
+func (m *MockChallengeDb) CountRecentAnonymous(since time.Time) (int, error) {
+	args := m.Called(since)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockChallengeDb) RedeemChallenge(id string, expiresAt time.Time) (bool, error) {
+	args := m.Called(id, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func newTestChallengeService(db ChallengeDbProvider) *ChallengeService {
+	return newTrustedChallengeService(db, new(MockDb), nil)
+}
+
+func newTrustedChallengeService(db ChallengeDbProvider, commentsDb *MockDb, levels []config.TrustLevelConfig) *ChallengeService {
+	cfg := &config.AppConfig{}
+	cfg.TrustConfig.Levels = levels
+	cfg.PowConfig.Enabled = true
+	cfg.PowConfig.Secret = "secret"
+	cfg.PowConfig.BaseDifficulty = 4
+	cfg.PowConfig.MaxDifficulty = 6
+	cfg.PowConfig.StepComments = 10
+	return NewChallengeService(db, NewCommentService(commentsDb, cfg), cfg)
+}
+
+func TestChallengeService_DifficultyScalesWithVolume(t *testing.T) {
+	mockDb := new(MockChallengeDb)
+	service := newTestChallengeService(mockDb)
+
+	mockDb.On("CountRecentAnonymous", mock.Anything).Return(15, nil).Once()
+	challenge, err := service.Issue()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, challenge.Difficulty)
+
+	mockDb.On("CountRecentAnonymous", mock.Anything).Return(1000, nil).Once()
+	challenge, err = service.Issue()
+	assert.NoError(t, err)
+	assert.Equal(t, 6, challenge.Difficulty)
+}
+
+func TestChallengeService_Redeem(t *testing.T) {
+	mockDb := new(MockChallengeDb)
+	service := newTestChallengeService(mockDb)
+	mockDb.On("CountRecentAnonymous", mock.Anything).Return(0, nil)
+
+	challenge, err := service.Issue()
+	assert.NoError(t, err)
+	var solution string
+	for i := 0; ; i++ {
+		if solution = strconv.Itoa(i); challenge.Solved(solution) {
+			break
+		}
+	}
+
+	assert.ErrorIs(t, service.Redeem("", ""), domain.ErrChallengeRequired)
+
+	mockDb.On("RedeemChallenge", challenge.ID, challenge.ExpiresAt).Return(true, nil).Once()
+	assert.NoError(t, service.Redeem(challenge.Token, solution))
+
+	// Повторное предъявление того же решения
+	mockDb.On("RedeemChallenge", challenge.ID, challenge.ExpiresAt).Return(false, nil).Once()
+	assert.ErrorIs(t, service.Redeem(challenge.Token, solution), domain.ErrChallengeInvalid)
+	mockDb.AssertExpectations(t)
+}
+
+func TestChallengeService_RequiredForLowTrustUsers(t *testing.T) {
+	commentsDb := new(MockDb)
+	service := newTrustedChallengeService(new(MockChallengeDb), commentsDb, []config.TrustLevelConfig{{}, {MinApproved: 5}})
+
+	newbie, veteran := uuid.New(), uuid.New()
+	commentsDb.On("GetTrustSignals", newbie.String()).Return(&domain.TrustSignals{UserID: newbie, MemberSince: time.Now()}, nil)
+	commentsDb.On("GetTrustSignals", veteran.String()).Return(&domain.TrustSignals{UserID: veteran, ApprovedComments: 5}, nil)
+
+	for userID, want := range map[string]bool{"": true, newbie.String(): true, veteran.String(): false} {
+		required, err := service.Required(userID)
+		assert.NoError(t, err)
+		assert.Equal(t, want, required, "user %q", userID)
+	}
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"commentTree/internal/events"
+	"context"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+type ChangesService struct {
+	db      ChangesDbProvider
+	waiter  ChangeWaiter
+	timeout time.Duration
+}
+
+type ChangesDbProvider interface {
+	GetChanges(parentId string, since int64, after time.Time) (*app.ChangeSet, error)
+}
+
+// ChangeWaiter будит ожидающие запросы, когда в ветке появляются изменения
+type ChangeWaiter interface {
+	Subscribe(rootID *uuid.UUID, lastEventID uint64) (*events.Subscription, []events.Message, error)
+	Unsubscribe(sub *events.Subscription)
+}
+
+func NewChangesService(db ChangesDbProvider, waiter ChangeWaiter, cfg *config.AppConfig) *ChangesService {
+	timeout := cfg.LongPollConfig.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &ChangesService{
+		db:      db,
+		waiter:  waiter,
+		timeout: timeout,
+	}
+}
+
+// WaitChanges возвращает изменения ветки после ревизии since.
+// Если изменений нет, ждет их появления не дольше настроенного таймаута.
+func (s *ChangesService) WaitChanges(ctx context.Context, parentId string, since int64) (*app.ChangeSet, error) {
+	pID, err := uuid.Parse(parentId)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("invalid parent id")
+		return nil, err
+	}
+
+	// подписываемся до первого запроса, чтобы не пропустить изменение между запросом и ожиданием
+	sub, _, err := s.waiter.Subscribe(&pID, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		s.waiter.Unsubscribe(sub)
+	}()
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	for {
+		set, err := s.db.GetChanges(parentId, since, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		set.Redact()
+		if len(set.Changes) > 0 {
+			return set, nil
+		}
+
+		select {
+		case <-sub.C:
+		case <-sub.Done:
+			// подписка закрыта из-за переполнения — проверяем журнал и заново подписываемся
+			s.waiter.Unsubscribe(sub)
+			if sub, _, err = s.waiter.Subscribe(&pID, 0); err != nil {
+				return nil, err
+			}
+		case <-timer.C:
+			return set, nil
+		case <-ctx.Done():
+			return set, nil
+		}
+	}
+}
+
+// GetDelta возвращает изменения ветки после ревизии since (или момента after) в виде дельты дерева
+func (s *ChangesService) GetDelta(parentId string, since int64, after time.Time) (*app.TreeDelta, error) {
+	if _, err := uuid.Parse(parentId); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("invalid parent id")
+		return nil, err
+	}
+	set, err := s.db.GetChanges(parentId, since, after)
+	if err != nil {
+		return nil, err
+	}
+	set.Redact()
+	delta := app.NewTreeDelta(set)
+	return &delta, nil
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	domain "commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"commentTree/internal/events"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type MockChangesDb struct {
+	mock.Mock
+}
+
+func (m *MockChangesDb) GetChanges(parentId string, since int64, after time.Time) (*domain.ChangeSet, error) {
+	args := m.Called(parentId, since, after)
+	return args.Get(0).(*domain.ChangeSet), args.Error(1)
+}
+
+func newChangesService(db ChangesDbProvider, timeout time.Duration) (*ChangesService, *events.Hub) {
+	cfg := &config.AppConfig{}
+	cfg.LongPollConfig.Timeout = timeout
+	hub := events.NewHub(cfg)
+	return NewChangesService(db, hub, cfg), hub
+}
+
+func TestChangesService_WaitChanges_ReturnsImmediately(t *testing.T) {
+	mockDb := new(MockChangesDb)
+	service, _ := newChangesService(mockDb, time.Minute)
+
+	parentID := uuid.New()
+	set := &domain.ChangeSet{Revision: 3, Changes: []domain.CommentChange{{Revision: 3, CommentID: parentID}}}
+	mockDb.On("GetChanges", parentID.String(), int64(2), time.Time{}).Return(set, nil).Once()
+
+	result, err := service.WaitChanges(context.Background(), parentID.String(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, set, result)
+	mockDb.AssertExpectations(t)
+}
+
+func TestChangesService_WaitChanges_WakesUpOnEvent(t *testing.T) {
+	mockDb := new(MockChangesDb)
+	service, hub := newChangesService(mockDb, time.Minute)
+
+	parentID := uuid.New()
+	childID := uuid.New()
+	empty := &domain.ChangeSet{Revision: 1, Changes: []domain.CommentChange{}}
+	changed := &domain.ChangeSet{Revision: 2, Changes: []domain.CommentChange{{Revision: 2, CommentID: childID}}}
+	mockDb.On("GetChanges", parentID.String(), int64(1), time.Time{}).Return(empty, nil).Once()
+	mockDb.On("GetChanges", parentID.String(), int64(1), time.Time{}).Return(changed, nil).Once()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		event := domain.NewCommentEvent(domain.EventCommentCreated, childID, nil)
+		event.Path = []uuid.UUID{parentID, childID}
+		hub.Publish(event)
+	}()
+
+	result, err := service.WaitChanges(context.Background(), parentID.String(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, changed, result)
+	mockDb.AssertExpectations(t)
+}
+
+func TestChangesService_WaitChanges_Timeout(t *testing.T) {
+	mockDb := new(MockChangesDb)
+	service, _ := newChangesService(mockDb, 50*time.Millisecond)
+
+	parentID := uuid.New()
+	empty := &domain.ChangeSet{Revision: 1, Changes: []domain.CommentChange{}}
+	mockDb.On("GetChanges", parentID.String(), int64(1), time.Time{}).Return(empty, nil).Once()
+
+	result, err := service.WaitChanges(context.Background(), parentID.String(), 1)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Changes)
+	assert.Equal(t, int64(1), result.Revision)
+}
+
+func TestChangesService_WaitChanges_InvalidParent(t *testing.T) {
+	service, _ := newChangesService(new(MockChangesDb), time.Minute)
+
+	_, err := service.WaitChanges(context.Background(), "invalid-uuid", 0)
+	assert.Error(t, err)
+}
+
+func TestChangesService_GetDelta(t *testing.T) {
+	mockDb := new(MockChangesDb)
+	service, _ := newChangesService(mockDb, time.Minute)
+
+	parentID := uuid.New()
+	childID := uuid.New()
+	after := time.Now().Add(-time.Hour)
+	set := &domain.ChangeSet{Revision: 4, Changes: []domain.CommentChange{
+		{Revision: 4, Type: domain.EventCommentCreated, CommentID: childID, ParentID: &parentID,
+			Comment: &domain.Comment{ID: childID, Text: "Reply", Status: domain.StatusActive, ParentID: &parentID}},
+	}}
+	mockDb.On("GetChanges", parentID.String(), int64(0), after).Return(set, nil).Once()
+
+	delta, err := service.GetDelta(parentID.String(), 0, after)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), delta.Revision)
+	assert.Len(t, delta.Created, 1)
+	assert.Equal(t, childID, delta.Created[0].ID)
+	mockDb.AssertExpectations(t)
+}
+
+func TestChangesService_HidesUnpublishedComments(t *testing.T) {
+	mockDb := new(MockChangesDb)
+	service, _ := newChangesService(mockDb, time.Minute)
+
+	// журнал после создания и скрытия: запись created ссылается на текущую, уже скрытую строку комментария
+	parentID, hiddenID := uuid.New(), uuid.New()
+	changes := func() *domain.ChangeSet {
+		return &domain.ChangeSet{Revision: 2, Changes: []domain.CommentChange{
+			{Revision: 1, Type: domain.EventCommentCreated, CommentID: hiddenID, ParentID: &parentID,
+				Comment: &domain.Comment{ID: hiddenID, Text: "leaked", Status: domain.StatusHidden, ParentID: &parentID}},
+			{Revision: 2, Type: domain.EventCommentDeleted, CommentID: hiddenID, ParentID: &parentID},
+		}}
+	}
+	mockDb.On("GetChanges", parentID.String(), int64(0), time.Time{}).Return(changes(), nil).Once()
+	mockDb.On("GetChanges", parentID.String(), int64(0), time.Time{}).Return(changes(), nil).Once()
+
+	set, err := service.WaitChanges(context.Background(), parentID.String(), 0)
+	assert.NoError(t, err)
+	assert.Len(t, set.Changes, 2)
+	for _, ch := range set.Changes {
+		assert.Nil(t, ch.Comment)
+	}
+
+	delta, err := service.GetDelta(parentID.String(), 0, time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, delta.Created)
+	assert.Empty(t, delta.Updated)
+	mockDb.AssertExpectations(t)
+}
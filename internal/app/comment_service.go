@@ -2,37 +2,257 @@ package app
 
 import (
 	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
 	"github.com/google/uuid"
 	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
 )
 
 type CommentService struct {
-	db DbProvider
+	db      DbProvider
+	hooks   []EventHook
+	policy  app.ContentPolicy
+	filters []ContentFilter
+	// premoderateAll и holdFlagged отправляют новые комментарии в очередь модерации
+	premoderateAll bool
+	holdFlagged    bool
+	// trust — уровни доверия авторов; пустой список отключает ограничения по уровню
+	trust  app.TrustPolicy
+	ipSalt string
 }
 
+// EventHook вызывается асинхронно после каждого изменения комментариев
+type EventHook func(event app.CommentEvent)
+
 type DbProvider interface {
-	SaveComment(text, parentID string) (*app.Comment, error)
-	GetComments(parentId string, sortAsc string, page, pageSize int) ([]app.Comment, error)
-	SearchComments(text string, sortAsc string, page, pageSize int) ([]app.Comment, error)
+	SaveComment(text, parentID, authorID, ipHash string, status app.CommentStatus) (*app.Comment, error)
+	GetComments(parentId, viewerID string, sortAsc string, page, pageSize int) ([]app.Comment, error)
+	SearchComments(text, viewerID string, sortAsc string, page, pageSize int) ([]app.Comment, error)
 	DeleteComments(parentId string) error
+	GetAncestorIDs(id string) ([]uuid.UUID, error)
+	GetComment(id string) (*app.Comment, error)
+	UpdateComment(id, text string) (*app.Comment, error)
+	GetUnreadIDs(userID string, commentIDs []uuid.UUID) ([]uuid.UUID, error)
+	MarkThreadsRead(userID string, commentIDs []uuid.UUID) error
+	GetFirstUnread(userID, parentID string) (*uuid.UUID, error)
+	SaveFilterHits(hits []app.FilterHit) error
+	IsPremoderated(parentID string) (bool, error)
+	GetTrustSignals(userID string) (*app.TrustSignals, error)
+	CountRecentComments(authorID string, since time.Time) (int, error)
+	GetActiveBan(authorID, ipHash, parentID string) (*app.Ban, error)
+	GetMutedAuthors(userID string) ([]uuid.UUID, error)
+	GetSlowMode(parentID, authorID, ipHash string) (*app.SlowMode, error)
+	GetCommentPath(id, viewerID string) ([]app.Comment, error)
+	GetDescendants(id, viewerID string, depth, limit int) ([]app.Comment, error)
+	GetThreadRedirect(rootID string) (*uuid.UUID, error)
 }
 
-func NewCommentService(db DbProvider) *CommentService {
+// Ограничения постоянной ссылки: сколько предков и уровней ответов показывать вокруг комментария
+const (
+	maxContextUp    = 50
+	maxContextDown  = 10
+	maxContextNodes = 500
+)
+
+// NewCommentService создает сервис; filters проверяются по порядку при создании и редактировании
+func NewCommentService(db DbProvider, cfg *config.AppConfig, filters ...ContentFilter) *CommentService {
+	policy := app.ContentPolicy{
+		MinLength: cfg.ContentConfig.MinLength,
+		MaxLength: cfg.ContentConfig.MaxLength,
+		MaxLines:  cfg.ContentConfig.MaxLines,
+		MaxLinks:  cfg.ContentConfig.MaxLinks,
+	}
+	if policy.MinLength < 1 {
+		policy.MinLength = 1
+	}
 	return &CommentService{
-		db: db,
+		db:             db,
+		policy:         policy,
+		filters:        filters,
+		premoderateAll: cfg.ModerationConfig.PremoderateAll,
+		holdFlagged:    cfg.ModerationConfig.HoldFlagged,
+		trust:          newTrustPolicy(cfg),
+		ipSalt:         cfg.BansConfig.IPSalt,
+	}
+}
+
+// Subscribe регистрирует хук, который будет получать события об изменении комментариев
+func (s *CommentService) Subscribe(hook EventHook) {
+	s.hooks = append(s.hooks, hook)
+}
+
+func (s *CommentService) publish(event app.CommentEvent) {
+	if len(s.hooks) == 0 {
+		return
+	}
+	go func() {
+		ancestors, err := s.db.GetAncestorIDs(event.CommentID.String())
+		if err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("failed to resolve event path")
+		} else {
+			event.Path = append(ancestors, event.CommentID)
+		}
+		for _, hook := range s.hooks {
+			go hook(event)
+		}
+	}()
+}
+
+// CreateComment сохраняет комментарий от authorID (пустой — аноним), отправленный с адреса clientIP
+func (s *CommentService) CreateComment(text, parentID, authorID, clientIP string) (*app.Comment, error) {
+	ipHash := app.HashIP(s.ipSalt, clientIP)
+	ban, err := s.db.GetActiveBan(authorID, ipHash, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if ban != nil {
+		return nil, &app.BannedError{Ban: *ban}
+	}
+	trust, err := s.authorTrust(authorID)
+	if err != nil {
+		return nil, err
+	}
+	text, err = s.contentPolicy(trust).Apply("text", text)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRate(authorID, trust); err != nil {
+		return nil, err
+	}
+	if err := s.checkSlowMode(parentID, authorID, ipHash); err != nil {
+		return nil, err
+	}
+	input := app.FilterInput{AuthorID: authorID, ParentID: parentID, Text: text}
+	verdicts, err := s.checkContent(input)
+	if err != nil {
+		return nil, err
+	}
+	status, err := s.initialStatus(parentID, trust, verdicts)
+	if err != nil {
+		return nil, err
 	}
+	comment, err := s.db.SaveComment(text, parentID, authorID, ipHash, status)
+	if err != nil {
+		return nil, err
+	}
+	s.recordHits(&comment.ID, input, verdicts)
+	if comment.Status == app.StatusActive {
+		s.publish(app.NewCommentEvent(app.EventCommentCreated, comment.ID, comment))
+	}
+	return comment, nil
+}
+
+// initialStatus решает, публикуется ли новый комментарий сразу или ждет модерации
+func (s *CommentService) initialStatus(parentID string, trust app.Trust, verdicts []app.FilterVerdict) (app.CommentStatus, error) {
+	if s.premoderateAll || trust.Privileges.Premoderate || (s.holdFlagged && len(verdicts) > 0) {
+		return app.StatusPending, nil
+	}
+	if parentID == "" {
+		return app.StatusActive, nil
+	}
+	premoderated, err := s.db.IsPremoderated(parentID)
+	if err != nil {
+		return "", err
+	}
+	if premoderated {
+		return app.StatusPending, nil
+	}
+	return app.StatusActive, nil
+}
+
+// checkSlowMode не дает автору писать в ветку с медленным режимом чаще заданного интервала
+func (s *CommentService) checkSlowMode(parentID, authorID, ipHash string) error {
+	if parentID == "" {
+		return nil
+	}
+	mode, err := s.db.GetSlowMode(parentID, authorID, ipHash)
+	if err != nil {
+		return err
+	}
+	if wait := mode.Wait(time.Now()); wait > 0 {
+		return &app.SlowModeError{Interval: mode.Interval, RetryAfter: wait}
+	}
+	return nil
 }
 
-func (s *CommentService) CreateComment(text, parentID string) (*app.Comment, error) {
-	return s.db.SaveComment(text, parentID)
+// ThreadRoot возвращает id корня ветки комментария; для корня и неизвестного id — сам id
+func (s *CommentService) ThreadRoot(commentID string) (string, error) {
+	if _, err := uuid.Parse(commentID); err != nil {
+		return "", err
+	}
+	ancestors, err := s.db.GetAncestorIDs(commentID)
+	if err != nil {
+		return "", err
+	}
+	if len(ancestors) == 0 {
+		return commentID, nil
+	}
+	return ancestors[0].String(), nil
+}
+
+// ThreadRedirect возвращает ветку, в которую слита ветка parentID, или пусто, если слияния не было
+func (s *CommentService) ThreadRedirect(parentID string) (string, error) {
+	if _, err := uuid.Parse(parentID); err != nil {
+		return "", nil
+	}
+	target, err := s.db.GetThreadRedirect(parentID)
+	if err != nil || target == nil {
+		return "", err
+	}
+	return target.String(), nil
+}
+
+// EditComment меняет текст комментария; редактировать может только автор
+func (s *CommentService) EditComment(id, text, editorID string) (*app.Comment, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, app.ErrNotFound
+	}
+	comment, err := s.db.GetComment(id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID == nil || comment.AuthorID.String() != editorID {
+		return nil, app.ErrForbidden
+	}
+	trust, err := s.authorTrust(editorID)
+	if err != nil {
+		return nil, err
+	}
+	text, err = s.contentPolicy(trust).Apply("text", text)
+	if err != nil {
+		return nil, err
+	}
+	var parentID string
+	if comment.ParentID != nil {
+		parentID = comment.ParentID.String()
+	}
+	input := app.FilterInput{CommentID: &comment.ID, AuthorID: editorID, ParentID: parentID, Text: text}
+	verdicts, err := s.checkContent(input)
+	if err != nil {
+		return nil, err
+	}
+	comment, err = s.db.UpdateComment(id, text)
+	if err != nil {
+		return nil, err
+	}
+	s.recordHits(&comment.ID, input, verdicts)
+	if comment.Status == app.StatusActive {
+		s.publish(app.NewCommentEvent(app.EventCommentEdited, comment.ID, comment))
+	}
+	return comment, nil
 }
 
 func (s *CommentService) GetComments(parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
+	return s.getComments(parentId, "", sortAsc, page, pageSize)
+}
+
+// getComments строит дерево, видимое viewerID: ожидающие модерации комментарии видны только автору
+func (s *CommentService) getComments(parentId, viewerID string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
 	var comments []app.Comment
 	var err error
 
 	if parentId == "" {
-		comments, err = s.db.GetComments("", sortAsc, page, pageSize)
+		comments, err = s.db.GetComments("", viewerID, sortAsc, page, pageSize)
 		if err != nil {
 			return nil, err
 		}
@@ -46,7 +266,7 @@ func (s *CommentService) GetComments(parentId string, sortAsc string, page, page
 		return nil, err
 	}
 
-	comments, err = s.db.GetComments(parentId, sortAsc, page, pageSize)
+	comments, err = s.db.GetComments(parentId, viewerID, sortAsc, page, pageSize)
 	if err != nil {
 		wbzlog.Logger.Error().Err(err).Msg("failed to get comments from db")
 		return nil, err
@@ -71,17 +291,124 @@ func (s *CommentService) GetComments(parentId string, sortAsc string, page, page
 	return []app.CommentNode{node}, nil
 }
 
-func (s *CommentService) SearchComments(text string, parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
+// GetComment возвращает комментарий, если он и его предки видны viewerID
+func (s *CommentService) GetComment(id, viewerID string) (*app.Comment, error) {
+	path, err := s.commentPath(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return &path[len(path)-1], nil
+}
+
+// GetAncestors возвращает предков комментария от корня ветки к родителю
+func (s *CommentService) GetAncestors(id, viewerID string) ([]app.Comment, error) {
+	path, err := s.commentPath(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return path[:len(path)-1], nil
+}
+
+// GetContext — дерево для постоянной ссылки: up ближайших предков, сам комментарий с флагом Target
+// и down уровней ответов на него
+func (s *CommentService) GetContext(id, viewerID string, up, down int) (*app.CommentNode, error) {
+	var errs []app.FieldError
+	if up < 0 {
+		errs = append(errs, app.FieldError{Field: "up", Code: "invalid", Message: "must not be negative"})
+	}
+	if down < 0 {
+		errs = append(errs, app.FieldError{Field: "down", Code: "invalid", Message: "must not be negative"})
+	}
+	if len(errs) > 0 {
+		return nil, &app.ValidationError{Errors: errs}
+	}
+	path, err := s.commentPath(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if up = min(up, maxContextUp); len(path) > up+1 {
+		path = path[len(path)-up-1:]
+	}
+	var descendants []app.Comment
+	if down = min(down, maxContextDown); down > 0 {
+		descendants, err = s.db.GetDescendants(id, viewerID, down, maxContextNodes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	node := app.BuildContext(path, descendants)
+	return &node, nil
+}
+
+func (s *CommentService) commentPath(id, viewerID string) ([]app.Comment, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, app.ErrNotFound
+	}
+	return s.db.GetCommentPath(id, viewerID)
+}
+
+// GetCommentsForUser — GetComments с флагом IsNew для комментариев, появившихся после прошлого визита,
+// и свернутыми комментариями скрытых пользователем авторов. Показанные комментарии сдвигают отметку прочтения их веток.
+func (s *CommentService) GetCommentsForUser(userID, parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
+	nodes, err := s.getComments(parentId, userID, sortAsc, page, pageSize)
+	if err != nil || userID == "" {
+		return nodes, err
+	}
+	ids := app.TreeIDs(nodes)
+	if len(ids) == 0 {
+		return nodes, nil
+	}
+	unreadIDs, err := s.db.GetUnreadIDs(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	unread := make(map[uuid.UUID]bool, len(unreadIDs))
+	for _, id := range unreadIDs {
+		unread[id] = true
+	}
+	app.MarkNew(nodes, unread)
+
+	mutedIDs, err := s.db.GetMutedAuthors(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(mutedIDs) > 0 {
+		muted := make(map[uuid.UUID]bool, len(mutedIDs))
+		for _, id := range mutedIDs {
+			muted[id] = true
+		}
+		app.CollapseAuthors(nodes, muted)
+	}
+
+	if err := s.db.MarkThreadsRead(userID, ids); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("failed to update read markers")
+	}
+	return nodes, nil
+}
+
+// FirstUnread возвращает самый ранний непрочитанный комментарий в ветке parentId или nil
+func (s *CommentService) FirstUnread(userID, parentId string) (*uuid.UUID, error) {
+	if _, err := uuid.Parse(parentId); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("invalid parent id")
+		return nil, err
+	}
+	return s.db.GetFirstUnread(userID, parentId)
+}
+
+func (s *CommentService) SearchComments(text string, parentId, viewerID string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
 	var nodes []app.CommentNode
 
 	if parentId == "" {
-		comments, err := s.db.SearchComments(text, sortAsc, page, pageSize)
+		comments, err := s.db.SearchComments(text, viewerID, sortAsc, page, pageSize)
 		if err != nil {
 			return nil, err
 		}
+		if len(comments) == 0 {
+			return nodes, nil
+		}
 		nodes = app.BuildTree(comments, comments[0].ParentID)
 	} else {
-		tree, err := s.GetComments(parentId, sortAsc, page, pageSize)
+		tree, err := s.getComments(parentId, viewerID, sortAsc, page, pageSize)
 		if err != nil {
 			return nil, err
 		}
@@ -90,11 +417,23 @@ func (s *CommentService) SearchComments(text string, parentId string, sortAsc st
 	return nodes, nil
 }
 
-func (s *CommentService) DeleteComments(id string) error {
-	_, err := uuid.Parse(id)
+// DeleteComments удаляет комментарий с ответами; удалить может автор или модератор
+func (s *CommentService) DeleteComments(id string, actor *app.User) error {
+	commentID, err := uuid.Parse(id)
 	if err != nil {
 		wbzlog.Logger.Error().Err(err).Msg("invalid id")
 		return err
 	}
-	return s.db.DeleteComments(id)
+	comment, err := s.db.GetComment(id)
+	if err != nil {
+		return err
+	}
+	if !actor.IsModerator() && (comment.AuthorID == nil || *comment.AuthorID != actor.ID) {
+		return app.ErrForbidden
+	}
+	if err := s.db.DeleteComments(id); err != nil {
+		return err
+	}
+	s.publish(app.NewCommentEvent(app.EventCommentDeleted, commentID, nil))
+	return nil
 }
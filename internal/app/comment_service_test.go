@@ -2,29 +2,31 @@ package app
 
 import (
 	domain "commentTree/internal/app/domain"
+	"commentTree/internal/config"
 	"errors"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 )
 
 type MockDb struct {
 	mock.Mock
 }
 
-func (m *MockDb) SaveComment(text, parentID string) (*domain.Comment, error) {
-	args := m.Called(text, parentID)
+func (m *MockDb) SaveComment(text, parentID, authorID, ipHash string, status domain.CommentStatus) (*domain.Comment, error) {
+	args := m.Called(text, parentID, authorID, ipHash, status)
 	return args.Get(0).(*domain.Comment), args.Error(1)
 }
 
-func (m *MockDb) GetComments(parentId string, sortAsc string, page, pageSize int) ([]domain.Comment, error) {
-	args := m.Called(parentId, sortAsc, page, pageSize)
+func (m *MockDb) GetComments(parentId, viewerID string, sortAsc string, page, pageSize int) ([]domain.Comment, error) {
+	args := m.Called(parentId, viewerID, sortAsc, page, pageSize)
 	return args.Get(0).([]domain.Comment), args.Error(1)
 }
 
-func (m *MockDb) SearchComments(text string, sortAsc string, page, pageSize int) ([]domain.Comment, error) {
-	args := m.Called(text, sortAsc, page, pageSize)
+func (m *MockDb) SearchComments(text, viewerID string, sortAsc string, page, pageSize int) ([]domain.Comment, error) {
+	args := m.Called(text, viewerID, sortAsc, page, pageSize)
 	return args.Get(0).([]domain.Comment), args.Error(1)
 }
 
@@ -33,30 +35,181 @@ func (m *MockDb) DeleteComments(parentId string) error {
 	return args.Error(0)
 }
 
+func (m *MockDb) GetAncestorIDs(id string) ([]uuid.UUID, error) {
+	args := m.Called(id)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockDb) GetComment(id string) (*domain.Comment, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.Comment), args.Error(1)
+}
+
+func (m *MockDb) UpdateComment(id, text string) (*domain.Comment, error) {
+	args := m.Called(id, text)
+	return args.Get(0).(*domain.Comment), args.Error(1)
+}
+
+func (m *MockDb) GetUnreadIDs(userID string, commentIDs []uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(userID, commentIDs)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockDb) MarkThreadsRead(userID string, commentIDs []uuid.UUID) error {
+	args := m.Called(userID, commentIDs)
+	return args.Error(0)
+}
+
+func (m *MockDb) GetFirstUnread(userID, parentID string) (*uuid.UUID, error) {
+	args := m.Called(userID, parentID)
+	return args.Get(0).(*uuid.UUID), args.Error(1)
+}
+
+func (m *MockDb) SaveFilterHits(hits []domain.FilterHit) error {
+	args := m.Called(hits)
+	return args.Error(0)
+}
+
+func (m *MockDb) IsPremoderated(parentID string) (bool, error) {
+	args := m.Called(parentID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDb) GetTrustSignals(userID string) (*domain.TrustSignals, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*domain.TrustSignals), args.Error(1)
+}
+
+func (m *MockDb) GetActiveBan(authorID, ipHash, parentID string) (*domain.Ban, error) {
+	args := m.Called(authorID, ipHash, parentID)
+	return args.Get(0).(*domain.Ban), args.Error(1)
+}
+
+func (m *MockDb) GetMutedAuthors(userID string) ([]uuid.UUID, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockDb) GetCommentPath(id, viewerID string) ([]domain.Comment, error) {
+	args := m.Called(id, viewerID)
+	return args.Get(0).([]domain.Comment), args.Error(1)
+}
+
+func (m *MockDb) GetDescendants(id, viewerID string, depth, limit int) ([]domain.Comment, error) {
+	args := m.Called(id, viewerID, depth, limit)
+	return args.Get(0).([]domain.Comment), args.Error(1)
+}
+
+func (m *MockDb) GetThreadRedirect(rootID string) (*uuid.UUID, error) {
+	args := m.Called(rootID)
+	target, _ := args.Get(0).(*uuid.UUID)
+	return target, args.Error(1)
+}
+
+func (m *MockDb) GetSlowMode(parentID, authorID, ipHash string) (*domain.SlowMode, error) {
+	args := m.Called(parentID, authorID, ipHash)
+	return args.Get(0).(*domain.SlowMode), args.Error(1)
+}
+
+// allowPosting — бана нет ни на автора, ни на адрес, медленный режим выключен
+func (m *MockDb) allowPosting() {
+	m.On("GetActiveBan", mock.Anything, mock.Anything, mock.Anything).Return((*domain.Ban)(nil), nil)
+	m.On("GetSlowMode", mock.Anything, mock.Anything, mock.Anything).Return(&domain.SlowMode{}, nil).Maybe()
+}
+
+func (m *MockDb) CountRecentComments(authorID string, since time.Time) (int, error) {
+	args := m.Called(authorID, since)
+	return args.Int(0), args.Error(1)
+}
+
+// stubFilter возвращает заданный вердикт
+type stubFilter struct {
+	name    string
+	verdict domain.FilterVerdict
+}
+
+func (f stubFilter) Name() string { return f.name }
+
+func (f stubFilter) Check(input domain.FilterInput) (domain.FilterVerdict, error) {
+	return f.verdict, nil
+}
+
 func TestCommentService_CreateComment(t *testing.T) {
 	mockDb := new(MockDb)
-	service := NewCommentService(mockDb)
+	mockDb.allowPosting()
+	service := NewCommentService(mockDb, &config.AppConfig{})
 
 	comment := &domain.Comment{ID: uuid.New(), Text: "Test comment"}
 
-	mockDb.On("SaveComment", "Test comment", "").Return(comment, nil)
+	mockDb.On("SaveComment", "Test comment", "", "", "", domain.StatusActive).Return(comment, nil)
 
-	result, err := service.CreateComment("Test comment", "")
+	result, err := service.CreateComment("Test comment", "", "", "")
 	assert.NoError(t, err)
 	assert.Equal(t, comment, result)
 	mockDb.AssertExpectations(t)
 }
 
+func TestCommentService_CreateComment_Validation(t *testing.T) {
+	mockDb := new(MockDb)
+	mockDb.allowPosting()
+	cfg := &config.AppConfig{}
+	cfg.ContentConfig.MaxLength = 5
+	service := NewCommentService(mockDb, cfg)
+
+	comment := &domain.Comment{ID: uuid.New(), Text: "Hi"}
+	mockDb.On("SaveComment", "Hi", "", "", "", domain.StatusActive).Return(comment, nil)
+
+	_, err := service.CreateComment("  Hi\x07 \n", "", "", "")
+	assert.NoError(t, err)
+
+	_, err = service.CreateComment(" \t\n ", "", "", "")
+	var verr *domain.ValidationError
+	assert.ErrorAs(t, err, &verr)
+
+	_, err = service.CreateComment("Too long", "", "", "")
+	assert.ErrorAs(t, err, &verr)
+	mockDb.AssertNumberOfCalls(t, "SaveComment", 1)
+}
+
+func TestCommentService_CreateComment_PublishesEvent(t *testing.T) {
+	mockDb := new(MockDb)
+	mockDb.allowPosting()
+	service := NewCommentService(mockDb, &config.AppConfig{})
+
+	parentID := uuid.New()
+	comment := &domain.Comment{ID: uuid.New(), Text: "Reply", ParentID: &parentID, Status: domain.StatusActive}
+
+	mockDb.On("IsPremoderated", parentID.String()).Return(false, nil)
+	mockDb.On("SaveComment", "Reply", parentID.String(), "", "", domain.StatusActive).Return(comment, nil)
+	mockDb.On("GetAncestorIDs", comment.ID.String()).Return([]uuid.UUID{parentID}, nil)
+
+	received := make(chan domain.CommentEvent, 1)
+	service.Subscribe(func(event domain.CommentEvent) {
+		received <- event
+	})
+
+	_, err := service.CreateComment("Reply", parentID.String(), "", "")
+	assert.NoError(t, err)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, domain.EventCommentCreated, event.Type)
+		assert.Equal(t, []uuid.UUID{parentID, comment.ID}, event.Path)
+	case <-time.After(time.Second):
+		t.Fatal("event was not published")
+	}
+}
+
 func TestCommentService_GetComments(t *testing.T) {
 	mockDb := new(MockDb)
-	service := NewCommentService(mockDb)
+	service := NewCommentService(mockDb, &config.AppConfig{})
 
 	rootID := uuid.New()
 	comments := []domain.Comment{
 		{ID: rootID, Text: "Root comment"},
 	}
 
-	mockDb.On("GetComments", rootID.String(), "asc", 1, 10).Return(comments, nil)
+	mockDb.On("GetComments", rootID.String(), "", "asc", 1, 10).Return(comments, nil)
 
 	result, err := service.GetComments(rootID.String(), "asc", 1, 10)
 	assert.NoError(t, err)
@@ -67,15 +220,15 @@ func TestCommentService_GetComments(t *testing.T) {
 
 func TestCommentService_SearchComments(t *testing.T) {
 	mockDb := new(MockDb)
-	service := NewCommentService(mockDb)
+	service := NewCommentService(mockDb, &config.AppConfig{})
 
 	comments := []domain.Comment{
 		{ID: uuid.New(), Text: "Hello world"},
 	}
 
-	mockDb.On("SearchComments", "hello", "asc", 1, 10).Return(comments, nil)
+	mockDb.On("SearchComments", "hello", "", "asc", 1, 10).Return(comments, nil)
 
-	result, err := service.SearchComments("hello", "", "asc", 1, 10)
+	result, err := service.SearchComments("hello", "", "", "asc", 1, 10)
 	assert.NoError(t, err)
 	assert.Len(t, result, 1)
 	assert.Equal(t, "Hello world", result[0].Text)
@@ -84,27 +237,47 @@ func TestCommentService_SearchComments(t *testing.T) {
 
 func TestCommentService_DeleteComments(t *testing.T) {
 	mockDb := new(MockDb)
-	service := NewCommentService(mockDb)
+	service := NewCommentService(mockDb, &config.AppConfig{})
 
+	author := &domain.User{ID: uuid.New(), Role: domain.RoleUser}
 	id := uuid.New().String()
+	mockDb.On("GetComment", id).Return(&domain.Comment{AuthorID: &author.ID}, nil)
 	mockDb.On("DeleteComments", id).Return(nil)
 
-	err := service.DeleteComments(id)
+	err := service.DeleteComments(id, author)
+	assert.NoError(t, err)
+	mockDb.AssertExpectations(t)
+}
+
+func TestCommentService_DeleteComments_OnlyAuthorOrModerator(t *testing.T) {
+	mockDb := new(MockDb)
+	service := NewCommentService(mockDb, &config.AppConfig{})
+
+	authorID := uuid.New()
+	id := uuid.New().String()
+	mockDb.On("GetComment", id).Return(&domain.Comment{AuthorID: &authorID}, nil)
+	mockDb.On("DeleteComments", id).Return(nil).Once()
+
+	err := service.DeleteComments(id, &domain.User{ID: uuid.New(), Role: domain.RoleUser})
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+	mockDb.AssertNotCalled(t, "DeleteComments", id)
+
+	err = service.DeleteComments(id, &domain.User{ID: uuid.New(), Role: domain.RoleModerator})
 	assert.NoError(t, err)
 	mockDb.AssertExpectations(t)
 }
 
 func TestCommentService_DeleteComments_InvalidUUID(t *testing.T) {
 	mockDb := new(MockDb)
-	service := NewCommentService(mockDb)
+	service := NewCommentService(mockDb, &config.AppConfig{})
 
-	err := service.DeleteComments("invalid-uuid")
+	err := service.DeleteComments("invalid-uuid", &domain.User{ID: uuid.New()})
 	assert.Error(t, err)
 }
 
 func TestCommentService_SearchComments_WithParentID(t *testing.T) {
 	mockDb := new(MockDb)
-	service := NewCommentService(mockDb)
+	service := NewCommentService(mockDb, &config.AppConfig{})
 
 	parentID := uuid.New().String()
 	childID := uuid.New()
@@ -113,9 +286,9 @@ func TestCommentService_SearchComments_WithParentID(t *testing.T) {
 	rootComment := domain.Comment{ID: uuid.MustParse(parentID), Text: "Root comment"}
 	childComment := domain.Comment{ID: uuid.MustParse(childID.String()), Text: "Child filter me", ParentID: &rootComment.ID}
 
-	mockDb.On("GetComments", parentID, "asc", 1, 10).Return([]domain.Comment{rootComment, childComment}, nil)
+	mockDb.On("GetComments", parentID, "", "asc", 1, 10).Return([]domain.Comment{rootComment, childComment}, nil)
 
-	result, err := service.SearchComments("filter", parentID, "asc", 1, 10)
+	result, err := service.SearchComments("filter", parentID, "", "asc", 1, 10)
 
 	assert.NoError(t, err)
 	assert.Len(t, result, 1) // должен вернуть корневой узел
@@ -128,15 +301,273 @@ func TestCommentService_SearchComments_WithParentID(t *testing.T) {
 
 func TestCommentService_SearchComments_WithParentID_Error(t *testing.T) {
 	mockDb := new(MockDb)
-	service := NewCommentService(mockDb)
+	service := NewCommentService(mockDb, &config.AppConfig{})
 
 	parentID := uuid.New().String()
 
-	mockDb.On("GetComments", parentID, "asc", 1, 10).Return([]domain.Comment{}, errors.New("db error"))
+	mockDb.On("GetComments", parentID, "", "asc", 1, 10).Return([]domain.Comment{}, errors.New("db error"))
 
-	result, err := service.SearchComments("filter", parentID, "asc", 1, 10)
+	result, err := service.SearchComments("filter", parentID, "", "asc", 1, 10)
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	mockDb.AssertExpectations(t)
 }
+
+func TestCommentService_EditComment(t *testing.T) {
+	authorID := uuid.New()
+	comment := &domain.Comment{ID: uuid.New(), Text: "Old", AuthorID: &authorID}
+
+	t.Run("Author edits", func(t *testing.T) {
+		mockDb := new(MockDb)
+		service := NewCommentService(mockDb, &config.AppConfig{})
+		edited := &domain.Comment{ID: comment.ID, Text: "New", AuthorID: &authorID}
+
+		mockDb.On("GetComment", comment.ID.String()).Return(comment, nil)
+		mockDb.On("UpdateComment", comment.ID.String(), "New").Return(edited, nil)
+
+		result, err := service.EditComment(comment.ID.String(), "New", authorID.String())
+		assert.NoError(t, err)
+		assert.Equal(t, edited, result)
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("Other user is forbidden", func(t *testing.T) {
+		mockDb := new(MockDb)
+		service := NewCommentService(mockDb, &config.AppConfig{})
+
+		mockDb.On("GetComment", comment.ID.String()).Return(comment, nil)
+
+		_, err := service.EditComment(comment.ID.String(), "New", uuid.New().String())
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		mockDb.AssertNotCalled(t, "UpdateComment", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCommentService_GetCommentsForUser_MarksNew(t *testing.T) {
+	mockDb := new(MockDb)
+	service := NewCommentService(mockDb, &config.AppConfig{})
+
+	root := domain.Comment{ID: uuid.New(), Text: "Root"}
+	seen := domain.Comment{ID: uuid.New(), Text: "Seen", ParentID: &root.ID}
+	fresh := domain.Comment{ID: uuid.New(), Text: "Fresh", ParentID: &root.ID}
+	comments := []domain.Comment{root, seen, fresh}
+	ids := []uuid.UUID{root.ID, seen.ID, fresh.ID}
+
+	mockDb.On("GetComments", root.ID.String(), "user", "asc", 1, 10).Return(comments, nil)
+	mockDb.On("GetUnreadIDs", "user", ids).Return([]uuid.UUID{fresh.ID}, nil)
+	mockDb.On("MarkThreadsRead", "user", ids).Return(nil)
+	mockDb.On("GetMutedAuthors", "user").Return([]uuid.UUID{}, nil)
+
+	nodes, err := service.GetCommentsForUser("user", root.ID.String(), "asc", 1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 1)
+	assert.False(t, nodes[0].IsNew)
+	assert.False(t, nodes[0].Children[0].IsNew)
+	assert.True(t, nodes[0].Children[1].IsNew)
+	mockDb.AssertExpectations(t)
+}
+
+func TestCommentService_CreateComment_FilterReject(t *testing.T) {
+	mockDb := new(MockDb)
+	mockDb.allowPosting()
+	service := NewCommentService(mockDb, &config.AppConfig{},
+		stubFilter{name: "repeat", verdict: domain.FilterVerdict{Action: domain.FilterFlag, Reason: "repeat"}},
+		stubFilter{name: "domains", verdict: domain.FilterVerdict{Action: domain.FilterReject, Reason: "blocked"}},
+	)
+
+	mockDb.On("SaveFilterHits", mock.MatchedBy(func(hits []domain.FilterHit) bool {
+		return len(hits) == 2 && hits[0].CommentID == nil && hits[1].Filter == "domains"
+	})).Return(nil)
+
+	_, err := service.CreateComment("spam", "", "", "")
+	var rerr *domain.RejectedError
+	assert.ErrorAs(t, err, &rerr)
+	assert.Equal(t, "domains", rerr.Verdict.Filter)
+	mockDb.AssertNotCalled(t, "SaveComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockDb.AssertExpectations(t)
+}
+
+func TestCommentService_CreateComment_FilterFlag(t *testing.T) {
+	mockDb := new(MockDb)
+	mockDb.allowPosting()
+	service := NewCommentService(mockDb, &config.AppConfig{},
+		stubFilter{name: "allow", verdict: domain.Allow()},
+		stubFilter{name: "words", verdict: domain.FilterVerdict{Action: domain.FilterFlag, Reason: "word"}},
+	)
+
+	comment := &domain.Comment{ID: uuid.New(), Text: "Flagged"}
+	mockDb.On("SaveComment", "Flagged", "", "", "", domain.StatusActive).Return(comment, nil)
+	mockDb.On("SaveFilterHits", mock.MatchedBy(func(hits []domain.FilterHit) bool {
+		return len(hits) == 1 && *hits[0].CommentID == comment.ID && hits[0].Filter == "words"
+	})).Return(nil)
+
+	result, err := service.CreateComment("Flagged", "", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, comment, result)
+	mockDb.AssertExpectations(t)
+}
+
+func TestCommentService_CreateComment_Premoderated(t *testing.T) {
+	mockDb := new(MockDb)
+	mockDb.allowPosting()
+	service := NewCommentService(mockDb, &config.AppConfig{})
+
+	parentID := uuid.New()
+	comment := &domain.Comment{ID: uuid.New(), Text: "Reply", ParentID: &parentID, Status: domain.StatusPending}
+	mockDb.On("IsPremoderated", parentID.String()).Return(true, nil)
+	mockDb.On("SaveComment", "Reply", parentID.String(), "", "", domain.StatusPending).Return(comment, nil)
+
+	published := false
+	service.Subscribe(func(event domain.CommentEvent) { published = true })
+
+	result, err := service.CreateComment("Reply", parentID.String(), "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusPending, result.Status)
+	mockDb.AssertNotCalled(t, "GetAncestorIDs", mock.Anything)
+	assert.False(t, published)
+	mockDb.AssertExpectations(t)
+}
+
+func TestCommentService_CreateComment_HoldFlagged(t *testing.T) {
+	mockDb := new(MockDb)
+	mockDb.allowPosting()
+	cfg := &config.AppConfig{}
+	cfg.ModerationConfig.HoldFlagged = true
+	service := NewCommentService(mockDb, cfg,
+		stubFilter{name: "words", verdict: domain.FilterVerdict{Action: domain.FilterFlag, Reason: "word"}},
+	)
+
+	comment := &domain.Comment{ID: uuid.New(), Text: "Flagged", Status: domain.StatusPending}
+	mockDb.On("SaveComment", "Flagged", "", "", "", domain.StatusPending).Return(comment, nil)
+	mockDb.On("SaveFilterHits", mock.Anything).Return(nil)
+
+	_, err := service.CreateComment("Flagged", "", "", "")
+	assert.NoError(t, err)
+	mockDb.AssertExpectations(t)
+}
+
+func TestCommentService_CreateComment_TrustLevel(t *testing.T) {
+	mockDb := new(MockDb)
+	mockDb.allowPosting()
+	cfg := &config.AppConfig{}
+	cfg.TrustConfig.Levels = []config.TrustLevelConfig{
+		{Premoderate: true, MaxLinks: 1, MaxPerHour: 2},
+		{MinAge: 24 * time.Hour, MinApproved: 5, MaxLinks: 5},
+	}
+	service := NewCommentService(mockDb, cfg)
+
+	newbie := uuid.New()
+	mockDb.On("GetTrustSignals", newbie.String()).Return(&domain.TrustSignals{UserID: newbie, MemberSince: time.Now()}, nil)
+
+	var verr *domain.ValidationError
+	_, err := service.CreateComment("see http://a.example and http://b.example", "", newbie.String(), "")
+	assert.ErrorAs(t, err, &verr)
+
+	mockDb.On("CountRecentComments", newbie.String(), mock.Anything).Return(2, nil).Once()
+	_, err = service.CreateComment("Hello", "", newbie.String(), "")
+	assert.ErrorIs(t, err, domain.ErrRateLimited)
+
+	comment := &domain.Comment{ID: uuid.New(), Text: "Hello", Status: domain.StatusPending}
+	mockDb.On("CountRecentComments", newbie.String(), mock.Anything).Return(1, nil).Once()
+	mockDb.On("SaveComment", "Hello", "", newbie.String(), "", domain.StatusPending).Return(comment, nil)
+	_, err = service.CreateComment("Hello", "", newbie.String(), "")
+	assert.NoError(t, err)
+
+	veteran := uuid.New()
+	mockDb.On("GetTrustSignals", veteran.String()).
+		Return(&domain.TrustSignals{UserID: veteran, MemberSince: time.Now().Add(-48 * time.Hour), ApprovedComments: 5}, nil)
+	trust, err := service.Trust(veteran.String())
+	assert.NoError(t, err)
+	assert.Equal(t, domain.TrustLevel(1), trust.Level)
+	mockDb.AssertExpectations(t)
+}
+
+func TestCommentService_CreateComment_Banned(t *testing.T) {
+	mockDb := new(MockDb)
+	cfg := &config.AppConfig{}
+	cfg.BansConfig.IPSalt = "salt"
+	service := NewCommentService(mockDb, cfg)
+
+	ipHash := domain.HashIP("salt", "203.0.113.7")
+	ban := &domain.Ban{ID: uuid.New(), Kind: domain.BanIP, IPHash: ipHash, Reason: "spam"}
+	mockDb.On("GetActiveBan", "", ipHash, "").Return(ban, nil)
+
+	_, err := service.CreateComment("Hello", "", "", "203.0.113.7")
+	var banned *domain.BannedError
+	assert.ErrorAs(t, err, &banned)
+	assert.Equal(t, "spam", banned.Ban.Reason)
+	mockDb.AssertNotCalled(t, "SaveComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCommentService_GetCommentsForUser_CollapsesMuted(t *testing.T) {
+	mockDb := new(MockDb)
+	service := NewCommentService(mockDb, &config.AppConfig{})
+
+	troll := uuid.New()
+	root := domain.Comment{ID: uuid.New(), Text: "Root"}
+	noisy := domain.Comment{ID: uuid.New(), Text: "Noisy", ParentID: &root.ID, AuthorID: &troll}
+	reply := domain.Comment{ID: uuid.New(), Text: "Reply", ParentID: &noisy.ID}
+	ids := []uuid.UUID{root.ID, noisy.ID, reply.ID}
+
+	mockDb.On("GetComments", root.ID.String(), "user", "asc", 1, 10).Return([]domain.Comment{root, noisy, reply}, nil)
+	mockDb.On("GetUnreadIDs", "user", ids).Return([]uuid.UUID{}, nil)
+	mockDb.On("MarkThreadsRead", "user", ids).Return(nil)
+	mockDb.On("GetMutedAuthors", "user").Return([]uuid.UUID{troll}, nil)
+
+	nodes, err := service.GetCommentsForUser("user", root.ID.String(), "asc", 1, 10)
+	assert.NoError(t, err)
+	assert.False(t, nodes[0].Collapsed)
+	assert.True(t, nodes[0].Children[0].Collapsed)
+	assert.False(t, nodes[0].Children[0].Children[0].Collapsed)
+	mockDb.AssertExpectations(t)
+}
+
+func TestCommentService_CreateComment_SlowMode(t *testing.T) {
+	mockDb := new(MockDb)
+	mockDb.On("GetActiveBan", mock.Anything, mock.Anything, mock.Anything).Return((*domain.Ban)(nil), nil)
+	service := NewCommentService(mockDb, &config.AppConfig{})
+
+	parentID := uuid.New().String()
+	last := time.Now().Add(-10 * time.Second)
+	mockDb.On("GetSlowMode", parentID, "author", "").Return(&domain.SlowMode{Interval: time.Minute, LastPostedAt: &last}, nil)
+
+	_, err := service.CreateComment("Hello", parentID, "author", "")
+	var slow *domain.SlowModeError
+	assert.ErrorAs(t, err, &slow)
+	assert.Equal(t, time.Minute, slow.Interval)
+	assert.InDelta(t, 50*time.Second, slow.RetryAfter, float64(time.Second))
+	mockDb.AssertNotCalled(t, "SaveComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCommentService_GetContext(t *testing.T) {
+	mockDb := new(MockDb)
+	service := NewCommentService(mockDb, &config.AppConfig{})
+
+	root := domain.Comment{ID: uuid.New(), Text: "Root"}
+	parent := domain.Comment{ID: uuid.New(), Text: "Parent", ParentID: &root.ID}
+	target := domain.Comment{ID: uuid.New(), Text: "Target", ParentID: &parent.ID}
+	reply := domain.Comment{ID: uuid.New(), Text: "Reply", ParentID: &target.ID}
+	id := target.ID.String()
+
+	mockDb.On("GetCommentPath", id, "viewer").Return([]domain.Comment{root, parent, target}, nil)
+	mockDb.On("GetDescendants", id, "viewer", maxContextDown, maxContextNodes).Return([]domain.Comment{reply}, nil)
+
+	node, err := service.GetContext(id, "viewer", 1, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, parent.ID, node.ID)
+	assert.True(t, node.Children[0].Target)
+	assert.Equal(t, reply.ID, node.Children[0].Children[0].ID)
+
+	ancestors, err := service.GetAncestors(id, "viewer")
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Comment{root, parent}, ancestors)
+
+	var verr *domain.ValidationError
+	_, err = service.GetContext(id, "viewer", -1, 0)
+	assert.ErrorAs(t, err, &verr)
+
+	_, err = service.GetComment("not-a-uuid", "")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	mockDb.AssertExpectations(t)
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+// ContentFilter проверяет текст комментария перед сохранением.
+// Ошибка фильтра не блокирует комментарий: фильтр пропускается с записью в лог.
+type ContentFilter interface {
+	Name() string
+	Check(input app.FilterInput) (app.FilterVerdict, error)
+}
+
+// checkContent прогоняет текст через все фильтры и возвращает вердикты flag/reject.
+// Если хотя бы один фильтр отклонил комментарий, срабатывания сохраняются сразу
+// и возвращается *app.RejectedError с первым отказом.
+func (s *CommentService) checkContent(input app.FilterInput) ([]app.FilterVerdict, error) {
+	var verdicts []app.FilterVerdict
+	var rejected *app.FilterVerdict
+	for _, f := range s.filters {
+		verdict, err := f.Check(input)
+		if err != nil {
+			wbzlog.Logger.Error().Err(err).Str("filter", f.Name()).Msg("content filter failed")
+			continue
+		}
+		if verdict.Action == "" || verdict.Action == app.FilterAllow {
+			continue
+		}
+		if verdict.Filter == "" {
+			verdict.Filter = f.Name()
+		}
+		verdicts = append(verdicts, verdict)
+		if verdict.Action == app.FilterReject && rejected == nil {
+			rejected = &verdicts[len(verdicts)-1]
+		}
+	}
+	if rejected != nil {
+		reject := *rejected
+		s.recordHits(input.CommentID, input, verdicts)
+		return nil, &app.RejectedError{Verdict: reject}
+	}
+	return verdicts, nil
+}
+
+// recordHits сохраняет срабатывания для модераторов; сбой записи не отменяет сохранение комментария
+func (s *CommentService) recordHits(commentID *uuid.UUID, input app.FilterInput, verdicts []app.FilterVerdict) {
+	if len(verdicts) == 0 {
+		return
+	}
+	hits := make([]app.FilterHit, len(verdicts))
+	for i, v := range verdicts {
+		hits[i] = app.NewFilterHit(commentID, input.AuthorID, input.Text, v)
+	}
+	if err := s.db.SaveFilterHits(hits); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("failed to save filter hits")
+	}
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+// BanKind — кого касается бан: автора или IP-адрес (по хэшу)
+type BanKind string
+
+const (
+	BanAuthor BanKind = "author"
+	BanIP     BanKind = "ip"
+)
+
+// Ban — запрет комментировать. RootID задает ветку, пустой — бан на весь сервис.
+// ExpiresAt пуст у бессрочного бана.
+type Ban struct {
+	ID        uuid.UUID  `json:"id"`
+	Kind      BanKind    `json:"kind"`
+	AuthorID  *uuid.UUID `json:"author_id,omitempty"`
+	IPHash    string     `json:"ip_hash,omitempty"`
+	RootID    *uuid.UUID `json:"thread_id,omitempty"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active сообщает, действует ли бан в момент now
+func (b *Ban) Active(now time.Time) bool {
+	return b.RevokedAt == nil && (b.ExpiresAt == nil || b.ExpiresAt.After(now))
+}
+
+// BanRequest — бан от модератора. Автора и адрес можно взять из комментария CommentID,
+// ThreadID — любой комментарий ветки; пустой ThreadID — бан на весь сервис.
+type BanRequest struct {
+	Kind      BanKind
+	UserID    string
+	IP        string
+	CommentID string
+	ThreadID  string
+	Reason    string
+	ExpiresAt *time.Time
+}
+
+// BannedError — автор или адрес забанен
+type BannedError struct {
+	Ban Ban
+}
+
+func (e *BannedError) Error() string {
+	return "banned: " + e.Ban.Reason
+}
+
+// HashIP возвращает хэш адреса: сами адреса не хранятся
+func HashIP(salt, ip string) string {
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte("ip:" + ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CommentOrigin — кто и откуда оставил комментарий; нужен модератору, чтобы забанить автора или адрес
+type CommentOrigin struct {
+	CommentID uuid.UUID
+	RootID    uuid.UUID
+	AuthorID  *uuid.UUID
+	IPHash    string
+}
+
+// Mute — автор, комментарии которого пользователь видит свернутыми
+type Mute struct {
+	UserID    uuid.UUID `json:"user_id"`
+	MutedID   uuid.UUID `json:"muted_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Действия журнала аудита
+const (
+	AuditBanCreated   = "ban.created"
+	AuditBanRevoked   = "ban.revoked"
+	AuditMuteAdded    = "mute.added"
+	AuditMuteRemoved  = "mute.removed"
+	AuditCommentMoved = "comment.moved"
+)
+
+// AuditEntry — запись журнала аудита: кто, что и над чем сделал. Data — состояние объекта после действия.
+type AuditEntry struct {
+	ID        uuid.UUID       `json:"id"`
+	ActorID   *uuid.UUID      `json:"actor_id,omitempty"`
+	Action    string          `json:"action"`
+	TargetID  uuid.UUID       `json:"target_id"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// NewAuditEntry готовит запись; actorID пуст, если действие выполнено без X-User-Id
+func NewAuditEntry(actorID, action string, targetID uuid.UUID, data interface{}) (AuditEntry, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return AuditEntry{}, err
+	}
+	entry := AuditEntry{
+		ID:        uuid.New(),
+		Action:    action,
+		TargetID:  targetID,
+		Data:      raw,
+		CreatedAt: time.Now(),
+	}
+	if id, err := uuid.Parse(actorID); err == nil {
+		entry.ActorID = &id
+	}
+	return entry, nil
+}
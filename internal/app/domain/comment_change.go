@@ -0,0 +1,32 @@
+package app
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// CommentChange — запись журнала изменений ветки
+type CommentChange struct {
+	Revision  int64      `json:"revision"`
+	Type      EventType  `json:"type"`
+	CommentID uuid.UUID  `json:"comment_id"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+	Comment   *Comment   `json:"comment,omitempty"`
+	ChangedAt time.Time  `json:"changed_at"`
+}
+
+// ChangeSet — изменения ветки после указанной ревизии и текущая ревизия ветки
+type ChangeSet struct {
+	Revision int64           `json:"revision"`
+	Changes  []CommentChange `json:"changes"`
+}
+
+// Redact убирает содержимое комментариев, которые сейчас не опубликованы. Журнал ссылается на текущую строку
+// комментария, поэтому ранняя запись created или edited иначе отдала бы текст скрытого, отклоненного или удаленного.
+func (s *ChangeSet) Redact() {
+	for i := range s.Changes {
+		if c := s.Changes[i].Comment; c != nil && c.Status != StatusActive {
+			s.Changes[i].Comment = nil
+		}
+	}
+}
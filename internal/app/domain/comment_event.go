@@ -0,0 +1,71 @@
+package app
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+// ErrEventUndeliverable — событие не доставлено за отведенное число попыток; outbox помечает его failedAt
+// и переходит к следующим, чтобы одно событие не блокировало очередь
+var ErrEventUndeliverable = errors.New("event delivery attempts exhausted")
+
+type EventType string
+
+const (
+	EventCommentCreated EventType = "comment.created"
+	EventCommentEdited  EventType = "comment.edited"
+	EventCommentDeleted EventType = "comment.deleted"
+	// EventCommentMoved — комментарий с ответами перенесен под другого родителя
+	EventCommentMoved EventType = "comment.moved"
+
+	// События решений модерации; дерево меняют comment.created и comment.deleted, опубликованные вместе с ними
+	EventCommentApproved EventType = "comment.approved"
+	EventCommentHidden   EventType = "comment.hidden"
+	EventCommentRejected EventType = "comment.rejected"
+)
+
+// Moderation сообщает, что событие — решение модерации. Такие события несут текст скрытых и отклоненных
+// комментариев и причину решения, поэтому уходят только в вебхуки, а не в публичный поток.
+func (t EventType) Moderation() bool {
+	return t == EventCommentApproved || t == EventCommentHidden || t == EventCommentRejected
+}
+
+// CommentEvent — изменение комментария, рассылаемое подписчикам.
+// ID не меняется при повторной доставке, по нему получатели отбрасывают дубликаты.
+type CommentEvent struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      EventType   `json:"type"`
+	CommentID uuid.UUID   `json:"comment_id"`
+	Comment   *Comment    `json:"comment,omitempty"`
+	Path      []uuid.UUID `json:"path"`
+	// PreviousPath — путь до переноса, только у comment.moved
+	PreviousPath []uuid.UUID `json:"previous_path,omitempty"`
+	Revision     int64       `json:"revision,omitempty"`
+	OccurredAt   time.Time   `json:"occurred_at"`
+}
+
+func NewCommentEvent(eventType EventType, commentID uuid.UUID, comment *Comment) CommentEvent {
+	return CommentEvent{
+		ID:         uuid.New(),
+		Type:       eventType,
+		CommentID:  commentID,
+		Comment:    comment,
+		Path:       []uuid.UUID{commentID},
+		OccurredAt: time.Now(),
+	}
+}
+
+// InSubtree сообщает, затрагивает ли событие ветку с корнем rootID.
+// Path содержит предков комментария от корня и сам комментарий последним;
+// перенос затрагивает и ветку, из которой комментарий ушел.
+func (e CommentEvent) InSubtree(rootID uuid.UUID) bool {
+	for _, path := range [][]uuid.UUID{e.Path, e.PreviousPath} {
+		for _, id := range path {
+			if id == rootID {
+				return true
+			}
+		}
+	}
+	return false
+}
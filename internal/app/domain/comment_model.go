@@ -1,18 +1,39 @@
 package app
 
 import (
+	"commentTree/internal/markdown"
 	"errors"
 	"github.com/google/uuid"
 	wbzlog "github.com/wb-go/wbf/zlog"
 	"time"
 )
 
+// CommentStatus — состояние комментария в жизненном цикле модерации
+type CommentStatus string
+
+const (
+	// StatusPending — ждет модерации, виден только автору
+	StatusPending CommentStatus = "pending"
+	StatusActive  CommentStatus = "active"
+	// StatusHidden — скрыт модератором после публикации
+	StatusHidden   CommentStatus = "hidden"
+	StatusRejected CommentStatus = "rejected"
+	StatusDeleted  CommentStatus = "deleted"
+)
+
 // TODO: add dto, entity separation
 type Comment struct {
-	ID        uuid.UUID  `json:"id"`
-	Text      string     `json:"text"`
-	CreatedAt time.Time  `json:"created_at"`
-	ParentID  *uuid.UUID `json:"parent_id"`
+	ID   uuid.UUID `json:"id"`
+	Text string    `json:"text"`
+	// HTML — Text, отрендеренный из Markdown и очищенный; хранится вместе с комментарием
+	HTML      string        `json:"html"`
+	CreatedAt time.Time     `json:"created_at"`
+	ParentID  *uuid.UUID    `json:"parent_id"`
+	AuthorID  *uuid.UUID    `json:"author_id,omitempty"`
+	Mentions  []Mention     `json:"mentions,omitempty"`
+	Status    CommentStatus `json:"status"`
+	// ModerationReason — причина последнего решения модератора
+	ModerationReason string `json:"moderation_reason,omitempty"`
 }
 
 func NewComment(parentid string, text string) (*Comment, error) {
@@ -32,8 +53,30 @@ func NewComment(parentid string, text string) (*Comment, error) {
 		wbzlog.Logger.Error().Err(err)
 		return nil, err
 	}
-	c.Text = text
+	c.SetText(text)
 	c.ID = uuid.New()
 	c.CreatedAt = time.Now()
+	c.Status = StatusActive
 	return &c, nil
 }
+
+// SetAuthor задает автора комментария; пустой authorid означает анонимный комментарий
+func (c *Comment) SetAuthor(authorid string) error {
+	if authorid == "" {
+		c.AuthorID = nil
+		return nil
+	}
+	authoruuid, err := uuid.Parse(authorid)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("bad author id")
+		return err
+	}
+	c.AuthorID = &authoruuid
+	return nil
+}
+
+// SetText задает исходный текст и пересчитывает HTML
+func (c *Comment) SetText(text string) {
+	c.Text = text
+	c.HTML = markdown.Render(text)
+}
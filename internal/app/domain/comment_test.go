@@ -29,6 +29,13 @@ func TestNewComment(t *testing.T) {
 		assert.NotEqual(t, uuid.Nil, comment.ID)
 	})
 
+	t.Run("Render markdown into html", func(t *testing.T) {
+		comment, err := NewComment("", "**bold** <i>")
+		assert.NoError(t, err)
+		assert.Equal(t, "**bold** <i>", comment.Text)
+		assert.Equal(t, "<p><strong>bold</strong> &lt;i&gt;</p>", comment.HTML)
+	})
+
 	t.Run("Fail on empty text", func(t *testing.T) {
 		comment, err := NewComment("", "")
 		assert.Error(t, err)
@@ -93,3 +100,53 @@ func TestFilterTreeByText(t *testing.T) {
 	filteredNone := FilterTreeByText(tree, "nonexistent")
 	assert.Len(t, filteredNone, 0)
 }
+
+func TestModerationAction_Transition(t *testing.T) {
+	from, to, err := ModerationApprove.Transition()
+	assert.NoError(t, err)
+	assert.Contains(t, from, StatusPending)
+	assert.Contains(t, from, StatusHidden)
+	assert.Equal(t, StatusActive, to)
+
+	from, to, err = ModerationHide.Transition()
+	assert.NoError(t, err)
+	assert.Equal(t, []CommentStatus{StatusActive}, from)
+	assert.Equal(t, StatusHidden, to)
+
+	_, _, err = ModerationAction("delete").Transition()
+	assert.ErrorIs(t, err, ErrInvalidModerationAction)
+}
+
+func TestCollapseAuthors(t *testing.T) {
+	muted := uuid.New()
+	nodes := []CommentNode{{
+		Comment:  Comment{ID: uuid.New(), AuthorID: &muted},
+		Children: []CommentNode{{Comment: Comment{ID: uuid.New()}}},
+	}}
+	CollapseAuthors(nodes, map[uuid.UUID]bool{muted: true})
+	assert.True(t, nodes[0].Collapsed)
+	assert.False(t, nodes[0].Children[0].Collapsed)
+
+	assert.Equal(t, HashIP("salt", "203.0.113.7"), HashIP("salt", "203.0.113.7"))
+	assert.NotEqual(t, HashIP("salt", "203.0.113.7"), HashIP("other", "203.0.113.7"))
+	assert.Empty(t, HashIP("salt", ""))
+}
+
+func TestBuildContext(t *testing.T) {
+	root := Comment{ID: uuid.New()}
+	parent := Comment{ID: uuid.New(), ParentID: &root.ID}
+	target := Comment{ID: uuid.New(), ParentID: &parent.ID}
+	reply := Comment{ID: uuid.New(), ParentID: &target.ID}
+	nested := Comment{ID: uuid.New(), ParentID: &reply.ID}
+
+	node := BuildContext([]Comment{parent, target}, []Comment{reply, nested})
+	assert.Equal(t, parent.ID, node.ID)
+	assert.False(t, node.Target)
+	assert.Len(t, node.Children, 1)
+	assert.True(t, node.Children[0].Target)
+	assert.Equal(t, nested.ID, node.Children[0].Children[0].Children[0].ID)
+
+	alone := BuildContext([]Comment{target}, nil)
+	assert.True(t, alone.Target)
+	assert.Empty(t, alone.Children)
+}
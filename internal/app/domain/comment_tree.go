@@ -8,6 +8,12 @@ import (
 type CommentNode struct {
 	Comment
 	Children []CommentNode
+	// IsNew — комментарий появился после последнего визита пользователя в ветку
+	IsNew bool `json:"is_new,omitempty"`
+	// Collapsed — автор скрыт пользователем, клиент показывает комментарий свернутым
+	Collapsed bool `json:"collapsed,omitempty"`
+	// Target — комментарий, на который ведет постоянная ссылка
+	Target bool `json:"target,omitempty"`
 }
 
 func BuildTree(comments []Comment, parentID *uuid.UUID) []CommentNode {
@@ -37,3 +43,46 @@ func FilterTreeByText(nodes []CommentNode, text string) []CommentNode {
 	}
 	return result
 }
+
+// TreeIDs возвращает id всех комментариев дерева
+func TreeIDs(nodes []CommentNode) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+		ids = append(ids, TreeIDs(n.Children)...)
+	}
+	return ids
+}
+
+// MarkNew проставляет IsNew узлам, id которых есть в unread
+func MarkNew(nodes []CommentNode, unread map[uuid.UUID]bool) {
+	for i := range nodes {
+		nodes[i].IsNew = unread[nodes[i].ID]
+		MarkNew(nodes[i].Children, unread)
+	}
+}
+
+// CollapseAuthors сворачивает узлы, автор которых есть в muted; ответы остаются развернутыми
+func CollapseAuthors(nodes []CommentNode, muted map[uuid.UUID]bool) {
+	for i := range nodes {
+		nodes[i].Collapsed = nodes[i].AuthorID != nil && muted[*nodes[i].AuthorID]
+		CollapseAuthors(nodes[i].Children, muted)
+	}
+}
+
+// BuildContext строит цепочку path (от верхнего предка к цели) и поддерево цели из descendants
+func BuildContext(path []Comment, descendants []Comment) CommentNode {
+	target := path[len(path)-1]
+	node := CommentNode{
+		Comment:  target,
+		Children: BuildTree(descendants, &target.ID),
+		Target:   true,
+	}
+	for i := len(path) - 2; i >= 0; i-- {
+		node = CommentNode{
+			Comment:  path[i],
+			Children: []CommentNode{node},
+		}
+	}
+	return node
+}
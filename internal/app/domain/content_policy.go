@@ -0,0 +1,86 @@
+package app
+
+import (
+	"fmt"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ContentPolicy — ограничения на текст комментария. Нулевые MaxLength, MaxLines и MaxLinks означают «без ограничения».
+type ContentPolicy struct {
+	MinLength int
+	MaxLength int
+	MaxLines  int
+	MaxLinks  int
+}
+
+// FieldError — ошибка проверки одного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError собирает все нарушения, чтобы клиент мог показать их сразу
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, f := range e.Errors {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// linkPattern находит ссылки в тексте, в том числе адреса внутри Markdown-ссылок
+var linkPattern = regexp.MustCompile(`(?i)(?:https?://|mailto:|www\.)[^\s)]+`)
+
+// NormalizeText приводит текст к NFC, переводит переносы строк в \n, удаляет управляющие
+// символы (кроме \n и \t) и невалидный UTF-8, обрезает пробелы по краям
+func NormalizeText(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == '\r':
+			return '\n'
+		case unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r):
+			return -1
+		}
+		return r
+	}, text)
+	return strings.TrimSpace(norm.NFC.String(text))
+}
+
+// Apply нормализует текст и проверяет его по политике. Возвращает нормализованный текст
+// или *ValidationError для поля field.
+func (p ContentPolicy) Apply(field, text string) (string, error) {
+	text = NormalizeText(text)
+	var errs []FieldError
+	length := utf8.RuneCountInString(text)
+	switch {
+	case length == 0:
+		errs = append(errs, FieldError{field, "required", "must not be empty"})
+	case length < p.MinLength:
+		errs = append(errs, FieldError{field, "too_short", fmt.Sprintf("must be at least %d characters", p.MinLength)})
+	case p.MaxLength > 0 && length > p.MaxLength:
+		errs = append(errs, FieldError{field, "too_long", fmt.Sprintf("must be at most %d characters", p.MaxLength)})
+	}
+	if p.MaxLines > 0 && strings.Count(text, "\n")+1 > p.MaxLines {
+		errs = append(errs, FieldError{field, "too_many_lines", fmt.Sprintf("must have at most %d lines", p.MaxLines)})
+	}
+	if p.MaxLinks > 0 && len(linkPattern.FindAllStringIndex(text, p.MaxLinks+1)) > p.MaxLinks {
+		errs = append(errs, FieldError{field, "too_many_links", fmt.Sprintf("must contain at most %d links", p.MaxLinks)})
+	}
+	if len(errs) > 0 {
+		return "", &ValidationError{Errors: errs}
+	}
+	return text, nil
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	assert.Equal(t, "hello", NormalizeText("  hello \n\t"))
+	assert.Equal(t, "a\nb\nc", NormalizeText("a\r\nb\rc"))
+	assert.Equal(t, "ab\tc", NormalizeText("a\x00b\tc\u202e"))
+	// e + combining acute -> é
+	assert.Equal(t, "\u00e9", NormalizeText("e\u0301"))
+	assert.Equal(t, "ab", NormalizeText("a\xffb"))
+}
+
+func TestContentPolicy_Apply(t *testing.T) {
+	policy := ContentPolicy{MinLength: 2, MaxLength: 10, MaxLines: 2, MaxLinks: 1}
+
+	text, err := policy.Apply("text", "  ok  ")
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", text)
+
+	codes := func(err error) []string {
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			return nil
+		}
+		var res []string
+		for _, f := range verr.Errors {
+			assert.Equal(t, "text", f.Field)
+			res = append(res, f.Code)
+		}
+		return res
+	}
+
+	_, err = policy.Apply("text", "  \x01 ")
+	assert.Equal(t, []string{"required"}, codes(err))
+	_, err = policy.Apply("text", "x")
+	assert.Equal(t, []string{"too_short"}, codes(err))
+	// длина в символах, а не байтах
+	_, err = policy.Apply("text", strings.Repeat("я", 10))
+	assert.NoError(t, err)
+	_, err = policy.Apply("text", strings.Repeat("я", 11))
+	assert.Equal(t, []string{"too_long"}, codes(err))
+	_, err = policy.Apply("text", "a\nb\nc")
+	assert.Equal(t, []string{"too_many_lines"}, codes(err))
+	_, err = policy.Apply("text", "www.a.b http://c")
+	assert.Equal(t, []string{"too_long", "too_many_links"}, codes(err))
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// FilterAction — решение фильтра содержимого
+type FilterAction string
+
+const (
+	FilterAllow  FilterAction = "allow"
+	FilterFlag   FilterAction = "flag"
+	FilterReject FilterAction = "reject"
+)
+
+// FilterInput — то, что проверяют фильтры. CommentID задан при редактировании.
+type FilterInput struct {
+	CommentID *uuid.UUID
+	AuthorID  string
+	ParentID  string
+	Text      string
+}
+
+// FilterVerdict — решение одного фильтра с причиной для модератора
+type FilterVerdict struct {
+	Action FilterAction `json:"action"`
+	Filter string       `json:"filter"`
+	Reason string       `json:"reason"`
+}
+
+// Allow — вердикт «пропустить»
+func Allow() FilterVerdict {
+	return FilterVerdict{Action: FilterAllow}
+}
+
+// RejectedError — комментарий отклонен фильтром
+type RejectedError struct {
+	Verdict FilterVerdict
+}
+
+func (e *RejectedError) Error() string {
+	return "rejected by " + e.Verdict.Filter + ": " + e.Verdict.Reason
+}
+
+// FilterHit — срабатывание фильтра, сохраненное для модераторов.
+// CommentID пуст, если комментарий был отклонен и не сохранился.
+type FilterHit struct {
+	ID        uuid.UUID    `json:"id"`
+	CommentID *uuid.UUID   `json:"comment_id"`
+	AuthorID  *uuid.UUID   `json:"author_id,omitempty"`
+	Filter    string       `json:"filter"`
+	Action    FilterAction `json:"action"`
+	Reason    string       `json:"reason"`
+	Excerpt   string       `json:"excerpt"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func NewFilterHit(commentID *uuid.UUID, authorID, text string, verdict FilterVerdict) FilterHit {
+	hit := FilterHit{
+		ID:        uuid.New(),
+		CommentID: commentID,
+		Filter:    verdict.Filter,
+		Action:    verdict.Action,
+		Reason:    verdict.Reason,
+		Excerpt:   Excerpt(text, 200),
+		CreatedAt: time.Now(),
+	}
+	if id, err := uuid.Parse(authorID); err == nil {
+		hit.AuthorID = &id
+	}
+	return hit
+}
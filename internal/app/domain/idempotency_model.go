@@ -0,0 +1,34 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	ErrIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyInFlight = errors.New("request with this idempotency key is in progress")
+)
+
+// IdempotentResponse — сохраненный ответ на запрос с ключом идемпотентности
+type IdempotentResponse struct {
+	Status int
+	Body   []byte
+}
+
+// IdempotencyRecord — ключ клиента scope, хеш запроса и ответ, если запрос уже выполнен
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	RequestHash string
+	Response    *IdempotentResponse
+}
+
+// RequestHash — отпечаток запроса, с которым сравниваются повторы по тому же ключу
+func RequestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
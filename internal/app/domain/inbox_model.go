@@ -0,0 +1,16 @@
+package app
+
+import "time"
+
+// InboxItem — новый комментарий в ветке, на которую подписан пользователь
+type InboxItem struct {
+	Comment   Comment    `json:"comment"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+// Inbox — страница ленты и число непрочитанных во всей ленте
+type Inbox struct {
+	Items       []InboxItem `json:"items"`
+	UnreadCount int64       `json:"unread_count"`
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"github.com/google/uuid"
+	"unicode/utf8"
+)
+
+// Mention — упоминание пользователя в тексте комментария.
+// Offset и Length считаются в символах (рунах) и включают "@".
+type Mention struct {
+	Offset int       `json:"offset"`
+	Length int       `json:"length"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// MentionToken — найденное в тексте "@name" до сопоставления с пользователем
+type MentionToken struct {
+	Name   string
+	Offset int
+	Length int
+}
+
+// ParseMentions находит упоминания "@name" в тексте. Имя подчиняется тем же правилам, что и имя пользователя;
+// "@" внутри слова (например, в email) упоминанием не считается.
+func ParseMentions(text string) []MentionToken {
+	var tokens []MentionToken
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && (isNameRune(runes[i-1]) || runes[i-1] == '@')) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isNameRune(runes[end]) {
+			end++
+		}
+		name := string(runes[i+1 : end])
+		if userNamePattern.MatchString(name) {
+			tokens = append(tokens, MentionToken{Name: name, Offset: i, Length: end - i})
+		}
+		i = end - 1
+	}
+	return tokens
+}
+
+// ResolveMentions оставляет упоминания существующих пользователей; users — id по имени
+func ResolveMentions(tokens []MentionToken, users map[string]uuid.UUID) []Mention {
+	var mentions []Mention
+	for _, t := range tokens {
+		if id, ok := users[t.Name]; ok {
+			mentions = append(mentions, Mention{Offset: t.Offset, Length: t.Length, UserID: id})
+		}
+	}
+	return mentions
+}
+
+// MentionNames возвращает имена из токенов без повторов
+func MentionNames(tokens []MentionToken) []string {
+	seen := make(map[string]bool, len(tokens))
+	var names []string
+	for _, t := range tokens {
+		if !seen[t.Name] {
+			seen[t.Name] = true
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+func isNameRune(r rune) bool {
+	return r < utf8.RuneSelf && (r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+}
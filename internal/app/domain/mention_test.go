@@ -0,0 +1,22 @@
+package app
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tokens := ParseMentions("привет @alice, mail bob@example.com и @x и @@carol @dave_2!")
+
+	var names []string
+	for _, tok := range tokens {
+		names = append(names, tok.Name)
+	}
+	assert.Equal(t, []string{"alice", "dave_2"}, names)
+	assert.Equal(t, MentionToken{Name: "alice", Offset: 7, Length: 6}, tokens[0])
+
+	aliceID := uuid.New()
+	mentions := ResolveMentions(tokens, map[string]uuid.UUID{"alice": aliceID})
+	assert.Equal(t, []Mention{{Offset: 7, Length: 6, UserID: aliceID}}, mentions)
+}
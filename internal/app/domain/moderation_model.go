@@ -0,0 +1,58 @@
+package app
+
+import (
+	"errors"
+	"github.com/google/uuid"
+)
+
+// ModerationAction — решение модератора по комментарию
+type ModerationAction string
+
+const (
+	// ModerationApprove публикует ожидающий, скрытый или отклоненный комментарий
+	ModerationApprove ModerationAction = "approve"
+	// ModerationReject отклоняет ожидающий комментарий
+	ModerationReject ModerationAction = "reject"
+	// ModerationHide скрывает опубликованный комментарий
+	ModerationHide ModerationAction = "hide"
+)
+
+var ErrInvalidModerationAction = errors.New("action must be approve, reject or hide")
+
+// Transition возвращает состояния, из которых действие допустимо, и итоговое состояние
+func (a ModerationAction) Transition() ([]CommentStatus, CommentStatus, error) {
+	switch a {
+	case ModerationApprove:
+		return []CommentStatus{StatusPending, StatusHidden, StatusRejected}, StatusActive, nil
+	case ModerationReject:
+		return []CommentStatus{StatusPending}, StatusRejected, nil
+	case ModerationHide:
+		return []CommentStatus{StatusActive}, StatusHidden, nil
+	}
+	return nil, "", ErrInvalidModerationAction
+}
+
+// Event — событие вебхуков о решении
+func (a ModerationAction) Event() EventType {
+	switch a {
+	case ModerationApprove:
+		return EventCommentApproved
+	case ModerationReject:
+		return EventCommentRejected
+	case ModerationHide:
+		return EventCommentHidden
+	}
+	return ""
+}
+
+// ModerationResult — итог массового решения. Skipped — комментарии, которых нет
+// или которые в неподходящем для действия состоянии.
+type ModerationResult struct {
+	Moderated []uuid.UUID `json:"moderated"`
+	Skipped   []uuid.UUID `json:"skipped"`
+}
+
+// ValidQueueStatus проверяет состояние, по которому можно смотреть очередь модерации
+func ValidQueueStatus(status CommentStatus) bool {
+	return status == StatusPending || status == StatusHidden || status == StatusRejected
+}
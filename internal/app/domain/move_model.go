@@ -0,0 +1,32 @@
+package app
+
+import (
+	"errors"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMoveCycle          = errors.New("cannot move a comment under itself or its replies")
+	ErrMoveParentNotFound = errors.New("new parent comment not found")
+)
+
+// CommentMove — перенос комментария вместе с ответами, он же данные записи аудита.
+// Нулевой ToParentID — комментарий стал корнем новой ветки.
+type CommentMove struct {
+	CommentID    uuid.UUID   `json:"comment_id"`
+	FromParentID *uuid.UUID  `json:"from_parent_id"`
+	ToParentID   *uuid.UUID  `json:"to_parent_id"`
+	FromRootID   uuid.UUID   `json:"from_root_id"`
+	ToRootID     uuid.UUID   `json:"to_root_id"`
+	Moved        int         `json:"moved"`
+	Comment      *Comment    `json:"comment,omitempty"`
+	PreviousPath []uuid.UUID `json:"-"`
+}
+
+// Noop — комментарий уже находится под этим родителем
+func (m *CommentMove) Noop() bool {
+	if m.FromParentID == nil || m.ToParentID == nil {
+		return m.FromParentID == nil && m.ToParentID == nil
+	}
+	return *m.FromParentID == *m.ToParentID
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	NotificationReply   = "reply"
+	NotificationMention = "mention"
+)
+
+// excerptLength — сколько символов комментария попадает в уведомление
+const excerptLength = 200
+
+// Notification — уведомление пользователя о событии с комментарием.
+// Пока SentAt пуст, уведомление ждет отправки (немедленной или в дайджесте).
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Kind      string     `json:"kind"`
+	CommentID uuid.UUID  `json:"comment_id"`
+	ActorID   *uuid.UUID `json:"actor_id"`
+	Excerpt   string     `json:"excerpt"`
+	CreatedAt time.Time  `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at"`
+}
+
+func NewNotification(kind string, userID uuid.UUID, c *Comment) *Notification {
+	return &Notification{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Kind:      kind,
+		CommentID: c.ID,
+		ActorID:   c.AuthorID,
+		Excerpt:   Excerpt(c.Text, excerptLength),
+		CreatedAt: time.Now(),
+	}
+}
+
+// Excerpt обрезает текст до limit символов, не разрывая руны
+func Excerpt(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
+
+// Message — письмо, которое Notifier доставляет пользователю
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	Headers map[string]string
+}
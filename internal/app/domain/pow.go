@@ -0,0 +1,99 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrChallengeRequired = errors.New("proof of work required")
+	ErrChallengeInvalid  = errors.New("invalid or expired proof of work")
+)
+
+// maxSolutionLength ограничивает решение, чтобы не хешировать произвольно длинные строки
+const maxSolutionLength = 64
+
+// Challenge — задача hashcash: найти решение s, при котором sha256(token + ":" + s)
+// начинается с Difficulty нулевых бит. Токен подписан сервером и содержит id, сложность и срок.
+type Challenge struct {
+	ID         string    `json:"-"`
+	Token      string    `json:"token"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// NewChallenge выпускает задачу сложностью difficulty, действующую ttl
+func NewChallenge(secret string, difficulty int, ttl time.Duration, now time.Time) (Challenge, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return Challenge{}, err
+	}
+	c := Challenge{
+		ID:         hex.EncodeToString(raw),
+		Difficulty: difficulty,
+		ExpiresAt:  now.Add(ttl).Truncate(time.Second),
+	}
+	payload := fmt.Sprintf("%s.%d.%d", c.ID, c.Difficulty, c.ExpiresAt.Unix())
+	c.Token = payload + "." + signChallenge(secret, payload)
+	return c, nil
+}
+
+// ParseChallenge проверяет подпись и срок токена
+func ParseChallenge(secret, token string, now time.Time) (*Challenge, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return nil, ErrChallengeInvalid
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(signChallenge(secret, payload))) {
+		return nil, ErrChallengeInvalid
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, ErrChallengeInvalid
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrChallengeInvalid
+	}
+	c := &Challenge{ID: parts[0], Token: token, Difficulty: difficulty, ExpiresAt: time.Unix(expires, 0)}
+	if !now.Before(c.ExpiresAt) {
+		return nil, ErrChallengeInvalid
+	}
+	return c, nil
+}
+
+// Solved сообщает, решает ли solution задачу
+func (c Challenge) Solved(solution string) bool {
+	if solution == "" || len(solution) > maxSolutionLength {
+		return false
+	}
+	sum := sha256.Sum256([]byte(c.Token + ":" + solution))
+	return LeadingZeroBits(sum[:]) >= c.Difficulty
+}
+
+// LeadingZeroBits — число нулевых бит в начале хеша
+func LeadingZeroBits(sum []byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+func signChallenge(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
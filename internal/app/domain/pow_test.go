@@ -0,0 +1,59 @@
+package app
+
+import (
+	"crypto/sha256"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// solve перебирает решения так же, как клиентский решатель
+func solve(c Challenge) string {
+	for i := 0; ; i++ {
+		if s := strconv.Itoa(i); c.Solved(s) {
+			return s
+		}
+	}
+}
+
+func TestChallenge_RoundTrip(t *testing.T) {
+	now := time.Now()
+	c, err := NewChallenge("secret", 8, time.Minute, now)
+	assert.NoError(t, err)
+
+	parsed, err := ParseChallenge("secret", c.Token, now)
+	assert.NoError(t, err)
+	assert.Equal(t, c.ID, parsed.ID)
+	assert.Equal(t, 8, parsed.Difficulty)
+
+	solution := solve(*parsed)
+	sum := sha256.Sum256([]byte(c.Token + ":" + solution))
+	assert.GreaterOrEqual(t, LeadingZeroBits(sum[:]), 8)
+	assert.False(t, parsed.Solved(""))
+}
+
+func TestParseChallenge_Rejects(t *testing.T) {
+	now := time.Now()
+	c, _ := NewChallenge("secret", 8, time.Minute, now)
+
+	_, err := ParseChallenge("other", c.Token, now)
+	assert.ErrorIs(t, err, ErrChallengeInvalid)
+
+	// Понизить сложность в токене нельзя: подпись перестанет сходиться
+	tampered := c.ID + ".1." + strconv.FormatInt(c.ExpiresAt.Unix(), 10) + c.Token[len(c.Token)-65:]
+	_, err = ParseChallenge("secret", tampered, now)
+	assert.ErrorIs(t, err, ErrChallengeInvalid)
+
+	_, err = ParseChallenge("secret", c.Token, now.Add(2*time.Minute))
+	assert.ErrorIs(t, err, ErrChallengeInvalid)
+
+	_, err = ParseChallenge("secret", "garbage", now)
+	assert.ErrorIs(t, err, ErrChallengeInvalid)
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	assert.Equal(t, 0, LeadingZeroBits([]byte{0x80}))
+	assert.Equal(t, 12, LeadingZeroBits([]byte{0x00, 0x0f}))
+	assert.Equal(t, 16, LeadingZeroBits([]byte{0x00, 0x00}))
+}
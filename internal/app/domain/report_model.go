@@ -0,0 +1,79 @@
+package app
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ReportReason — категория жалобы на комментарий
+type ReportReason string
+
+const (
+	ReportSpam     ReportReason = "spam"
+	ReportAbuse    ReportReason = "abuse"
+	ReportOffTopic ReportReason = "off_topic"
+)
+
+// ReportReasons — все категории жалоб
+var ReportReasons = []ReportReason{ReportSpam, ReportAbuse, ReportOffTopic}
+
+func (r ReportReason) Valid() bool {
+	for _, reason := range ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// Report — жалоба пользователя на комментарий; от одного пользователя на комментарий хранится одна
+type Report struct {
+	ID         uuid.UUID    `json:"id"`
+	CommentID  uuid.UUID    `json:"comment_id"`
+	ReporterID uuid.UUID    `json:"reporter_id"`
+	Reason     ReportReason `json:"reason"`
+	CreatedAt  time.Time    `json:"created_at"`
+	// ReporterLevel — уровень доверия автора жалобы, от него зависит, учитывается ли жалоба при автоскрытии
+	ReporterLevel TrustLevel `json:"-"`
+}
+
+func NewReport(commentID, reporterID uuid.UUID, reason ReportReason) *Report {
+	return &Report{
+		ID:         uuid.New(),
+		CommentID:  commentID,
+		ReporterID: reporterID,
+		Reason:     reason,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// ReportWeights — вес жалобы каждой категории при подсчете серьезности
+type ReportWeights map[ReportReason]int
+
+// ReportSummary — жалобы на комментарий для модератора, без указания, кто жаловался
+type ReportSummary struct {
+	Comment        Comment              `json:"comment"`
+	Score          int                  `json:"score"`
+	Reports        int                  `json:"reports"`
+	Reasons        map[ReportReason]int `json:"reasons"`
+	LastReportedAt time.Time            `json:"last_reported_at"`
+}
+
+// Vote — голос пользователя за комментарий: +1 или -1; 0 отзывает голос
+type Vote struct {
+	CommentID uuid.UUID `json:"comment_id"`
+	VoterID   uuid.UUID `json:"voter_id"`
+	Value     int       `json:"value"`
+}
+
+// ValidVote проверяет значение голоса: -1, 0 или +1
+func ValidVote(value int) bool {
+	return value >= -1 && value <= 1
+}
+
+// CommentScore — сумма голосов за комментарий и голос текущего пользователя
+type CommentScore struct {
+	CommentID uuid.UUID `json:"comment_id"`
+	Score     int       `json:"score"`
+	Vote      int       `json:"vote"`
+}
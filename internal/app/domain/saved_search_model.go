@@ -0,0 +1,94 @@
+package app
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// SavedSearch — сохраненный запрос, по которому отслеживаются новые комментарии
+type SavedSearch struct {
+	ID         uuid.UUID  `json:"id"`
+	Query      string     `json:"query"`
+	ParentID   *uuid.UUID `json:"parent_id"`
+	WebhookURL string     `json:"webhook_url,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Alert — срабатывание сохраненного поиска на новом комментарии
+type Alert struct {
+	ID        uuid.UUID `json:"id"`
+	SearchID  uuid.UUID `json:"search_id"`
+	CommentID uuid.UUID `json:"comment_id"`
+	Query     string    `json:"query"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewSavedSearch(query, parentid, webhookURL string) (*SavedSearch, error) {
+	var s SavedSearch
+	query = strings.TrimSpace(query)
+	if query == "" {
+		err := errors.New("query is empty")
+		wbzlog.Logger.Error().Err(err).Msg("bad saved search")
+		return nil, err
+	}
+	if parentid != "" {
+		parentuuid, err := uuid.Parse(parentid)
+		if err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("bad parent id")
+			return nil, err
+		}
+		s.ParentID = &parentuuid
+	}
+	if webhookURL != "" {
+		u, err := url.Parse(webhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			err = errors.New("invalid webhook url")
+			wbzlog.Logger.Error().Err(err).Msg("bad saved search")
+			return nil, err
+		}
+		if !PublicHost(u.Hostname()) {
+			wbzlog.Logger.Error().Err(ErrWebhookTarget).Msg("bad saved search")
+			return nil, ErrWebhookTarget
+		}
+	}
+	s.Query = query
+	s.WebhookURL = webhookURL
+	s.ID = uuid.New()
+	s.CreatedAt = time.Now()
+	return &s, nil
+}
+
+// Matches проверяет, попадает ли комментарий под запрос.
+// path — идентификаторы предков комментария, нужны для поиска внутри ветки.
+func (s *SavedSearch) Matches(c *Comment, path []uuid.UUID) bool {
+	if !strings.Contains(strings.ToLower(c.Text), strings.ToLower(s.Query)) {
+		return false
+	}
+	if s.ParentID == nil || c.ID == *s.ParentID {
+		return true
+	}
+	for _, id := range path {
+		if id == *s.ParentID {
+			return true
+		}
+	}
+	return false
+}
+
+func NewAlert(search *SavedSearch, c *Comment) *Alert {
+	return &Alert{
+		ID:        uuid.New(),
+		SearchID:  search.ID,
+		CommentID: c.ID,
+		Query:     search.Query,
+		Text:      c.Text,
+		CreatedAt: time.Now(),
+	}
+}
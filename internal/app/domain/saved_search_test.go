@@ -0,0 +1,59 @@
+package app
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewSavedSearch(t *testing.T) {
+	t.Run("Create global saved search", func(t *testing.T) {
+		search, err := NewSavedSearch("  spam  ", "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, "spam", search.Query)
+		assert.Nil(t, search.ParentID)
+		assert.NotEqual(t, uuid.Nil, search.ID)
+	})
+
+	t.Run("Fail on empty query", func(t *testing.T) {
+		search, err := NewSavedSearch("   ", "", "")
+		assert.Error(t, err)
+		assert.Nil(t, search)
+	})
+
+	t.Run("Fail on invalid parent UUID", func(t *testing.T) {
+		search, err := NewSavedSearch("spam", "invalid-uuid", "")
+		assert.Error(t, err)
+		assert.Nil(t, search)
+	})
+
+	t.Run("Fail on invalid webhook url", func(t *testing.T) {
+		search, err := NewSavedSearch("spam", "", "ftp://example.com")
+		assert.Error(t, err)
+		assert.Nil(t, search)
+	})
+
+	t.Run("Fail on internal webhook url", func(t *testing.T) {
+		search, err := NewSavedSearch("spam", "", "http://169.254.169.254/latest/meta-data")
+		assert.ErrorIs(t, err, ErrWebhookTarget)
+		assert.Nil(t, search)
+	})
+}
+
+func TestSavedSearch_Matches(t *testing.T) {
+	rootID := uuid.New()
+	otherID := uuid.New()
+	comment := &Comment{ID: uuid.New(), Text: "Buy CHEAP watches", ParentID: &rootID}
+
+	global, _ := NewSavedSearch("cheap", "", "")
+	assert.True(t, global.Matches(comment, []uuid.UUID{rootID}))
+
+	inThread, _ := NewSavedSearch("cheap", rootID.String(), "")
+	assert.True(t, inThread.Matches(comment, []uuid.UUID{rootID}))
+
+	otherThread, _ := NewSavedSearch("cheap", otherID.String(), "")
+	assert.False(t, otherThread.Matches(comment, []uuid.UUID{rootID}))
+
+	noMatch, _ := NewSavedSearch("expensive", "", "")
+	assert.False(t, noMatch.Matches(comment, []uuid.UUID{rootID}))
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxSlowMode — самый длинный интервал медленного режима ветки
+const MaxSlowMode = 24 * time.Hour
+
+// SlowMode — медленный режим ветки и время прошлого комментария автора в ней.
+// Нулевой Interval — режим выключен.
+type SlowMode struct {
+	Interval     time.Duration
+	LastPostedAt *time.Time
+}
+
+// Wait — сколько автору осталось ждать до следующего комментария в ветке
+func (m SlowMode) Wait(now time.Time) time.Duration {
+	if m.Interval <= 0 || m.LastPostedAt == nil {
+		return 0
+	}
+	if wait := m.LastPostedAt.Add(m.Interval).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// SlowModeError — в ветке включен медленный режим, и автор писал в нее недавно
+type SlowModeError struct {
+	Interval   time.Duration
+	RetryAfter time.Duration
+}
+
+func (e *SlowModeError) Error() string {
+	return fmt.Sprintf("slow mode: one comment per %s in this thread", e.Interval)
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAlreadyThread = errors.New("comment is already a thread root")
+	ErrSameThread    = errors.New("comments are already in the same thread")
+)
+
+const (
+	AuditThreadSplit  = "thread.split"
+	AuditThreadMerged = "thread.merged"
+)
+
+// ThreadSplit — ветка, выделенная из поддерева, и узел-ссылка, оставленный на его месте
+type ThreadSplit struct {
+	ThreadID   uuid.UUID   `json:"thread_id"`
+	FromRootID uuid.UUID   `json:"from_root_id"`
+	Moved      int         `json:"moved"`
+	Link       *Comment    `json:"link"`
+	Move       CommentMove `json:"-"`
+}
+
+// ThreadMerge — слияние ветки FromRootID в ToRootID: ответы корня переносятся, ключ FromRootID
+// перенаправляется на ToRootID, сам корень удаляется. RootDeleted — корень был опубликован
+// и его удаление нужно разослать.
+type ThreadMerge struct {
+	FromRootID  uuid.UUID     `json:"from_root_id"`
+	ToRootID    uuid.UUID     `json:"to_root_id"`
+	Roots       []uuid.UUID   `json:"roots"`
+	Moved       int           `json:"moved"`
+	Moves       []CommentMove `json:"-"`
+	RootDeleted bool          `json:"-"`
+}
+
+// NewThreadLink создает служебный комментарий без автора под parentID со ссылкой на выделенную ветку threadID
+func NewThreadLink(parentID, threadID uuid.UUID) *Comment {
+	c, _ := NewComment(parentID.String(), fmt.Sprintf("Обсуждение перенесено в отдельную ветку: [перейти](/api/comments/%s/context)", threadID))
+	return c
+}
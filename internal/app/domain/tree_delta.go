@@ -0,0 +1,181 @@
+package app
+
+import (
+	"github.com/google/uuid"
+	"sort"
+)
+
+// NodeRef — ссылка на удаленный узел вместе с его родителем
+type NodeRef struct {
+	ID       uuid.UUID  `json:"id"`
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+// TreeDelta — изменения ветки, которые клиент может наложить на уже загруженное дерево
+type TreeDelta struct {
+	Revision int64     `json:"revision"`
+	Created  []Comment `json:"created"`
+	Updated  []Comment `json:"updated"`
+	Deleted  []NodeRef `json:"deleted"`
+	// Moved — перенесенные комментарии с новым ParentID; ответы переезжают вместе с ними
+	Moved []Comment `json:"moved"`
+}
+
+// NewTreeDelta сворачивает журнал изменений до итогового состояния каждого узла.
+// Узлы, созданные и удаленные внутри окна, в дельту не попадают.
+func NewTreeDelta(set *ChangeSet) TreeDelta {
+	delta := TreeDelta{
+		Revision: set.Revision,
+		Created:  []Comment{},
+		Updated:  []Comment{},
+		Deleted:  []NodeRef{},
+		Moved:    []Comment{},
+	}
+
+	type state struct {
+		created bool
+		deleted bool
+		moved   bool
+		change  CommentChange
+	}
+	var order []uuid.UUID
+	states := make(map[uuid.UUID]*state)
+	for _, ch := range set.Changes {
+		st, ok := states[ch.CommentID]
+		if !ok {
+			st = &state{}
+			states[ch.CommentID] = st
+			order = append(order, ch.CommentID)
+		}
+		switch ch.Type {
+		case EventCommentCreated:
+			st.created = true
+		case EventCommentDeleted:
+			st.deleted = true
+		case EventCommentMoved:
+			st.moved = true
+		}
+		if ch.Comment != nil || st.change.Comment == nil {
+			st.change = ch
+		}
+	}
+
+	for _, id := range order {
+		st := states[id]
+		switch {
+		case st.created && st.deleted:
+			continue
+		case st.deleted:
+			delta.Deleted = append(delta.Deleted, NodeRef{ID: id, ParentID: st.change.ParentID})
+		case st.change.Comment == nil:
+			continue
+		case st.created:
+			delta.Created = append(delta.Created, *st.change.Comment)
+		case st.moved:
+			delta.Moved = append(delta.Moved, *st.change.Comment)
+		default:
+			delta.Updated = append(delta.Updated, *st.change.Comment)
+		}
+	}
+	return delta
+}
+
+// ApplyDelta накладывает дельту на дерево и возвращает новое дерево, исходное не изменяется.
+// Созданные узлы, родителя которых нет в дереве, пропускаются — они вне загруженной ветки.
+// Перенесенный узел вместе с ответами переезжает к новому родителю или пропадает, если того нет в дереве.
+func ApplyDelta(nodes []CommentNode, delta TreeDelta) []CommentNode {
+	deleted := make(map[uuid.UUID]bool, len(delta.Deleted))
+	for _, ref := range delta.Deleted {
+		deleted[ref.ID] = true
+	}
+	updated := make(map[uuid.UUID]Comment, len(delta.Updated))
+	for _, c := range delta.Updated {
+		updated[c.ID] = c
+	}
+
+	result := patchNodes(nodes, deleted, updated)
+
+	// Родители перенесены раньше ответов, поэтому ответ из другой ветки найдет свой узел
+	moved := append([]Comment(nil), delta.Moved...)
+	sort.SliceStable(moved, func(i, j int) bool { return moved[i].CreatedAt.Before(moved[j].CreatedAt) })
+	for _, c := range moved {
+		if deleted[c.ID] {
+			continue
+		}
+		node := CommentNode{Comment: c}
+		var found *CommentNode
+		if result, found = detachNode(result, c.ID); found != nil {
+			node.Children = found.Children
+		}
+		if c.ParentID == nil {
+			continue
+		}
+		if parent := findNode(result, *c.ParentID); parent != nil {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	for _, c := range delta.Created {
+		if deleted[c.ID] {
+			continue
+		}
+		if node := findNode(result, c.ID); node != nil {
+			node.Comment = c
+			continue
+		}
+		if c.ParentID == nil {
+			result = append(result, CommentNode{Comment: c})
+			continue
+		}
+		if parent := findNode(result, *c.ParentID); parent != nil {
+			parent.Children = append(parent.Children, CommentNode{Comment: c})
+		}
+	}
+	return result
+}
+
+func patchNodes(nodes []CommentNode, deleted map[uuid.UUID]bool, updated map[uuid.UUID]Comment) []CommentNode {
+	var result []CommentNode
+	for _, n := range nodes {
+		if deleted[n.ID] {
+			continue
+		}
+		comment := n.Comment
+		if c, ok := updated[n.ID]; ok {
+			comment = c
+		}
+		result = append(result, CommentNode{
+			Comment:  comment,
+			Children: patchNodes(n.Children, deleted, updated),
+		})
+	}
+	return result
+}
+
+// detachNode вырезает узел id из дерева и возвращает его вместе с ответами
+func detachNode(nodes []CommentNode, id uuid.UUID) ([]CommentNode, *CommentNode) {
+	for i := range nodes {
+		if nodes[i].ID == id {
+			node := nodes[i]
+			return append(nodes[:i:i], nodes[i+1:]...), &node
+		}
+		children, found := detachNode(nodes[i].Children, id)
+		if found != nil {
+			nodes[i].Children = children
+			return nodes, found
+		}
+	}
+	return nodes, nil
+}
+
+func findNode(nodes []CommentNode, id uuid.UUID) *CommentNode {
+	for i := range nodes {
+		if nodes[i].ID == id {
+			return &nodes[i]
+		}
+		if found := findNode(nodes[i].Children, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
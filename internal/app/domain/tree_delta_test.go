@@ -0,0 +1,143 @@
+package app
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewTreeDelta(t *testing.T) {
+	rootID := uuid.New()
+	createdID := uuid.New()
+	editedID := uuid.New()
+	deletedID := uuid.New()
+	transientID := uuid.New()
+	movedID := uuid.New()
+
+	set := &ChangeSet{
+		Revision: 5,
+		Changes: []CommentChange{
+			{Revision: 1, Type: EventCommentCreated, CommentID: createdID, ParentID: &rootID,
+				Comment: &Comment{ID: createdID, Text: "new", ParentID: &rootID}},
+			{Revision: 2, Type: EventCommentEdited, CommentID: editedID, ParentID: &rootID,
+				Comment: &Comment{ID: editedID, Text: "edited", ParentID: &rootID}},
+			{Revision: 3, Type: EventCommentDeleted, CommentID: deletedID, ParentID: &rootID},
+			{Revision: 4, Type: EventCommentCreated, CommentID: transientID, ParentID: &rootID,
+				Comment: &Comment{ID: transientID, Text: "gone", ParentID: &rootID}},
+			{Revision: 5, Type: EventCommentDeleted, CommentID: transientID, ParentID: &rootID},
+			{Revision: 5, Type: EventCommentMoved, CommentID: movedID, ParentID: &createdID,
+				Comment: &Comment{ID: movedID, Text: "moved", ParentID: &createdID}},
+		},
+	}
+
+	delta := NewTreeDelta(set)
+	assert.Equal(t, int64(5), delta.Revision)
+	assert.Len(t, delta.Created, 1)
+	assert.Equal(t, createdID, delta.Created[0].ID)
+	assert.Len(t, delta.Updated, 1)
+	assert.Equal(t, "edited", delta.Updated[0].Text)
+	assert.Equal(t, []NodeRef{{ID: deletedID, ParentID: &rootID}}, delta.Deleted)
+	assert.Len(t, delta.Moved, 1)
+	assert.Equal(t, movedID, delta.Moved[0].ID)
+}
+
+func TestApplyDelta(t *testing.T) {
+	rootID := uuid.New()
+	childID := uuid.New()
+	grandChildID := uuid.New()
+	removedID := uuid.New()
+
+	comments := []Comment{
+		{ID: rootID, Text: "Root"},
+		{ID: childID, Text: "Child", ParentID: &rootID},
+		{ID: grandChildID, Text: "Grandchild", ParentID: &childID},
+		{ID: removedID, Text: "Removed", ParentID: &rootID},
+	}
+	tree := BuildTree(comments, nil)
+
+	newID := uuid.New()
+	newRootID := uuid.New()
+	delta := TreeDelta{
+		Created: []Comment{
+			{ID: newID, Text: "New reply", ParentID: &grandChildID},
+			{ID: newRootID, Text: "New root"},
+			{ID: uuid.New(), Text: "Outside of tree", ParentID: ptr(uuid.New())},
+		},
+		Updated: []Comment{{ID: childID, Text: "Child edited", ParentID: &rootID}},
+		Deleted: []NodeRef{{ID: removedID, ParentID: &rootID}},
+	}
+
+	patched := ApplyDelta(tree, delta)
+
+	assert.Len(t, patched, 2)
+	assert.Equal(t, "New root", patched[1].Text)
+	assert.Len(t, patched[0].Children, 1)
+	assert.Equal(t, "Child edited", patched[0].Children[0].Text)
+	grandChild := patched[0].Children[0].Children[0]
+	assert.Equal(t, "Grandchild", grandChild.Text)
+	assert.Len(t, grandChild.Children, 1)
+	assert.Equal(t, "New reply", grandChild.Children[0].Text)
+
+	// исходное дерево не изменилось
+	assert.Len(t, tree[0].Children, 2)
+	assert.Equal(t, "Child", tree[0].Children[0].Text)
+	assert.Len(t, tree[0].Children[0].Children[0].Children, 0)
+}
+
+func TestApplyDelta_CreatedAlreadyPresent(t *testing.T) {
+	rootID := uuid.New()
+	tree := BuildTree([]Comment{{ID: rootID, Text: "Root"}}, nil)
+
+	patched := ApplyDelta(tree, TreeDelta{Created: []Comment{{ID: rootID, Text: "Root again"}}})
+
+	assert.Len(t, patched, 1)
+	assert.Equal(t, "Root again", patched[0].Text)
+}
+
+func TestApplyDelta_Moved(t *testing.T) {
+	rootID := uuid.New()
+	firstID := uuid.New()
+	secondID := uuid.New()
+	replyID := uuid.New()
+	leavingID := uuid.New()
+	now := time.Now()
+
+	tree := BuildTree([]Comment{
+		{ID: rootID, Text: "Root", CreatedAt: now},
+		{ID: firstID, Text: "First", ParentID: &rootID, CreatedAt: now.Add(time.Second)},
+		{ID: secondID, Text: "Second", ParentID: &rootID, CreatedAt: now.Add(2 * time.Second)},
+		{ID: replyID, Text: "Reply", ParentID: &firstID, CreatedAt: now.Add(3 * time.Second)},
+		{ID: leavingID, Text: "Leaving", ParentID: &secondID, CreatedAt: now.Add(4 * time.Second)},
+	}, nil)
+
+	arrivedID := uuid.New()
+	arrivedReplyID := uuid.New()
+	patched := ApplyDelta(tree, TreeDelta{Moved: []Comment{
+		{ID: arrivedReplyID, Text: "Arrived reply", ParentID: &arrivedID, CreatedAt: now.Add(6 * time.Second)},
+		{ID: firstID, Text: "First", ParentID: &secondID, CreatedAt: now.Add(time.Second)},
+		{ID: leavingID, Text: "Leaving", CreatedAt: now.Add(4 * time.Second)},
+		{ID: arrivedID, Text: "Arrived", ParentID: &rootID, CreatedAt: now.Add(5 * time.Second)},
+	}})
+
+	assert.Len(t, patched, 1)
+	root := patched[0]
+	assert.Len(t, root.Children, 2)
+	second := root.Children[0]
+	assert.Equal(t, "Second", second.Text)
+	assert.Len(t, second.Children, 1)
+	assert.Equal(t, "First", second.Children[0].Text)
+	assert.Equal(t, "Reply", second.Children[0].Children[0].Text)
+	arrived := root.Children[1]
+	assert.Equal(t, "Arrived", arrived.Text)
+	assert.Equal(t, "Arrived reply", arrived.Children[0].Text)
+
+	// исходное дерево не изменилось
+	assert.Len(t, tree[0].Children, 2)
+	assert.Equal(t, "First", tree[0].Children[0].Text)
+	assert.Equal(t, "Leaving", tree[0].Children[1].Children[0].Text)
+}
+
+func ptr(id uuid.UUID) *uuid.UUID {
+	return &id
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+var ErrRateLimited = errors.New("posting rate limit exceeded")
+
+// TrustLevel — уровень доверия к автору; 0 — новый аккаунт или аноним
+type TrustLevel int
+
+// TrustSignals — то, из чего складывается уровень доверия автора
+type TrustSignals struct {
+	UserID           uuid.UUID `json:"user_id"`
+	MemberSince      time.Time `json:"member_since"`
+	ApprovedComments int       `json:"approved_comments"`
+	// UpheldReports — жалобы на комментарии автора, которые остались скрытыми
+	UpheldReports int `json:"upheld_reports"`
+	// VotesReceived — сумма голосов других пользователей за опубликованные комментарии автора
+	VotesReceived int `json:"votes_received"`
+}
+
+// TrustPrivileges — что разрешено автору уровня. Нулевые MaxLinks и MaxPerHour означают «без ограничения».
+type TrustPrivileges struct {
+	Premoderate bool `json:"premoderate"`
+	MaxLinks    int  `json:"max_links"`
+	MaxPerHour  int  `json:"max_per_hour"`
+}
+
+// TrustRule — требования уровня и его привилегии. Требования уровня 0 не проверяются.
+type TrustRule struct {
+	MinAge           time.Duration
+	MinApproved      int
+	MaxUpheldReports int
+	MinVotes         int
+	Privileges       TrustPrivileges
+}
+
+func (r TrustRule) met(signals TrustSignals, now time.Time) bool {
+	return now.Sub(signals.MemberSince) >= r.MinAge &&
+		signals.ApprovedComments >= r.MinApproved &&
+		signals.UpheldReports <= r.MaxUpheldReports &&
+		signals.VotesReceived >= r.MinVotes
+}
+
+// TrustPolicy — правила уровней по возрастанию; индекс правила — номер уровня
+type TrustPolicy []TrustRule
+
+// Trust — текущий уровень автора, из чего он получен и что дает
+type Trust struct {
+	Level      TrustLevel      `json:"level"`
+	Signals    TrustSignals    `json:"signals"`
+	Privileges TrustPrivileges `json:"privileges"`
+}
+
+// Evaluate возвращает наивысший уровень, требования которого выполнены
+func (p TrustPolicy) Evaluate(signals TrustSignals, now time.Time) Trust {
+	trust := Trust{Signals: signals}
+	if len(p) == 0 {
+		return trust
+	}
+	trust.Privileges = p[0].Privileges
+	for level := len(p) - 1; level > 0; level-- {
+		if p[level].met(signals, now) {
+			trust.Level = TrustLevel(level)
+			trust.Privileges = p[level].Privileges
+			break
+		}
+	}
+	return trust
+}
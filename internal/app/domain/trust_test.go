@@ -0,0 +1,38 @@
+package app
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTrustPolicy_Evaluate(t *testing.T) {
+	now := time.Now()
+	policy := TrustPolicy{
+		{Privileges: TrustPrivileges{Premoderate: true, MaxLinks: 1, MaxPerHour: 5}},
+		{MinAge: 24 * time.Hour, MinApproved: 3, MaxUpheldReports: 1, Privileges: TrustPrivileges{MaxLinks: 5, MaxPerHour: 30}},
+		{MinAge: 30 * 24 * time.Hour, MinApproved: 50, MinVotes: 10, Privileges: TrustPrivileges{}},
+	}
+
+	fresh := policy.Evaluate(TrustSignals{MemberSince: now.Add(-time.Hour), ApprovedComments: 10}, now)
+	assert.Equal(t, TrustLevel(0), fresh.Level)
+	assert.True(t, fresh.Privileges.Premoderate)
+
+	basic := policy.Evaluate(TrustSignals{MemberSince: now.Add(-48 * time.Hour), ApprovedComments: 3, UpheldReports: 1}, now)
+	assert.Equal(t, TrustLevel(1), basic.Level)
+	assert.Equal(t, 5, basic.Privileges.MaxLinks)
+
+	// Удержанные жалобы не пускают на уровень выше
+	reported := policy.Evaluate(TrustSignals{MemberSince: now.Add(-60 * 24 * time.Hour), ApprovedComments: 100, UpheldReports: 1}, now)
+	assert.Equal(t, TrustLevel(1), reported.Level)
+
+	// Без голосов других пользователей старый аккаунт с комментариями не поднимается выше
+	unvoted := policy.Evaluate(TrustSignals{MemberSince: now.Add(-60 * 24 * time.Hour), ApprovedComments: 100, VotesReceived: 9}, now)
+	assert.Equal(t, TrustLevel(1), unvoted.Level)
+
+	trusted := policy.Evaluate(TrustSignals{MemberSince: now.Add(-60 * 24 * time.Hour), ApprovedComments: 100, VotesReceived: 10}, now)
+	assert.Equal(t, TrustLevel(2), trusted.Level)
+	assert.False(t, trusted.Privileges.Premoderate)
+
+	assert.Equal(t, TrustPrivileges{}, TrustPolicy(nil).Evaluate(TrustSignals{}, now).Privileges)
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	NotifyImmediate = "immediate"
+	NotifyDigest    = "digest"
+	NotifyOff       = "off"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+)
+
+var (
+	ErrUnauthorized       = errors.New("unknown user")
+	ErrForbidden          = errors.New("forbidden")
+	ErrInvalidUnsubscribe = errors.New("invalid unsubscribe token")
+)
+
+var userNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{2,32}$`)
+
+// User — автор комментариев и получатель уведомлений
+type User struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Email      string    `json:"email,omitempty"`
+	NotifyMode string    `json:"notify_mode"`
+	Role       string    `json:"role"`
+	CreatedAt  time.Time `json:"created_at"`
+	// TokenHash — sha256 токена доступа; сам токен не хранится
+	TokenHash string `json:"-"`
+}
+
+// IsModerator — доступны ли пользователю маршруты /admin
+func (u *User) IsModerator() bool {
+	return u.Role == RoleModerator
+}
+
+func NewUser(name, email string) (*User, error) {
+	var u User
+	if !userNamePattern.MatchString(name) {
+		err := errors.New("name must be 2-32 letters, digits or underscores")
+		wbzlog.Logger.Error().Err(err).Msg("bad user")
+		return nil, err
+	}
+	if email != "" {
+		addr, err := mail.ParseAddress(email)
+		if err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("bad user email")
+			return nil, err
+		}
+		email = addr.Address
+	}
+	u.ID = uuid.New()
+	u.Name = name
+	u.Email = email
+	u.NotifyMode = NotifyImmediate
+	u.Role = RoleUser
+	u.CreatedAt = time.Now()
+	return &u, nil
+}
+
+// NewUserToken выдает случайный токен доступа. Id пользователя публичен (author_id комментариев),
+// поэтому личность подтверждает только токен
+func NewUserToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashUserToken — хеш токена, по которому ищется пользователь
+func HashUserToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidNotifyMode проверяет режим уведомлений: immediate, digest или off
+func ValidNotifyMode(mode string) error {
+	switch mode {
+	case NotifyImmediate, NotifyDigest, NotifyOff:
+		return nil
+	}
+	return fmt.Errorf("unknown notify mode %q", mode)
+}
+
+// UnsubscribeToken возвращает токен отписки вида "<user id>.<hmac>", который не нужно хранить
+func UnsubscribeToken(secret string, userID uuid.UUID) string {
+	return userID.String() + "." + unsubscribeMAC(secret, userID)
+}
+
+// ParseUnsubscribeToken проверяет подпись токена и возвращает id пользователя
+func ParseUnsubscribeToken(secret, token string) (uuid.UUID, error) {
+	rawID, mac, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, ErrInvalidUnsubscribe
+	}
+	userID, err := uuid.Parse(rawID)
+	if err != nil {
+		return uuid.Nil, ErrInvalidUnsubscribe
+	}
+	if !hmac.Equal([]byte(mac), []byte(unsubscribeMAC(secret, userID))) {
+		return uuid.Nil, ErrInvalidUnsubscribe
+	}
+	return userID, nil
+}
+
+func unsubscribeMAC(secret string, userID uuid.UUID) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("unsubscribe:" + userID.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
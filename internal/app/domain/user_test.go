@@ -0,0 +1,53 @@
+package app
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestNewUser(t *testing.T) {
+	u, err := NewUser("alice_1", "Alice <alice@example.com>")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", u.Email)
+	assert.Equal(t, NotifyImmediate, u.NotifyMode)
+
+	_, err = NewUser("a", "")
+	assert.Error(t, err)
+	_, err = NewUser("alice", "not-an-email")
+	assert.Error(t, err)
+}
+
+func TestNewUserToken(t *testing.T) {
+	first, err := NewUserToken()
+	require.NoError(t, err)
+	second, err := NewUserToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, HashUserToken(first), HashUserToken(first))
+	assert.NotEqual(t, HashUserToken(first), HashUserToken(second))
+	assert.NotContains(t, HashUserToken(first), first)
+}
+
+func TestUnsubscribeToken_RoundTrip(t *testing.T) {
+	id := uuid.New()
+	token := UnsubscribeToken("secret", id)
+
+	got, err := ParseUnsubscribeToken("secret", token)
+	require.NoError(t, err)
+	assert.Equal(t, id, got)
+
+	_, err = ParseUnsubscribeToken("other", token)
+	assert.ErrorIs(t, err, ErrInvalidUnsubscribe)
+
+	forged := uuid.New().String() + token[strings.Index(token, "."):]
+	_, err = ParseUnsubscribeToken("secret", forged)
+	assert.ErrorIs(t, err, ErrInvalidUnsubscribe)
+}
+
+func TestExcerpt_KeepsRunes(t *testing.T) {
+	assert.Equal(t, "привет", Excerpt("привет", 10))
+	assert.Equal(t, "при…", Excerpt("привет", 3))
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrWebhookTarget — адрес вебхука ведет во внутреннюю сеть
+var ErrWebhookTarget = errors.New("webhook url must point to a public address")
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookSubscription — подписка внешней системы на события комментариев
+type WebhookSubscription struct {
+	ID        uuid.UUID   `json:"id"`
+	URL       string      `json:"url"`
+	Secret    string      `json:"-"`
+	Events    []EventType `json:"events"`
+	SubjectID *uuid.UUID  `json:"subject_id"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// WebhookDelivery — запись журнала доставок
+type WebhookDelivery struct {
+	ID             uuid.UUID  `json:"id"`
+	SubscriptionID uuid.UUID  `json:"subscription_id"`
+	EventType      EventType  `json:"event_type"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseCode   int        `json:"response_code"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+// WebhookPayload — тело запроса, которое получает подписчик
+type WebhookPayload struct {
+	DeliveryID uuid.UUID    `json:"delivery_id"`
+	Type       EventType    `json:"type"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Data       CommentEvent `json:"data"`
+}
+
+var webhookEventTypes = map[EventType]bool{
+	EventCommentCreated: true,
+	EventCommentEdited:  true,
+	EventCommentDeleted: true,
+	EventCommentMoved:   true,
+
+	EventCommentApproved: true,
+	EventCommentHidden:   true,
+	EventCommentRejected: true,
+}
+
+func NewWebhookSubscription(rawURL, secret string, eventTypes []string, subjectid string) (*WebhookSubscription, error) {
+	var w WebhookSubscription
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		err = errors.New("invalid webhook url")
+		wbzlog.Logger.Error().Err(err).Msg("bad webhook subscription")
+		return nil, err
+	}
+	if !PublicHost(u.Hostname()) {
+		wbzlog.Logger.Error().Err(ErrWebhookTarget).Msg("bad webhook subscription")
+		return nil, ErrWebhookTarget
+	}
+	if secret == "" {
+		err := errors.New("secret is empty")
+		wbzlog.Logger.Error().Err(err).Msg("bad webhook subscription")
+		return nil, err
+	}
+	for _, t := range eventTypes {
+		if !webhookEventTypes[EventType(t)] {
+			err := fmt.Errorf("unknown event type %q", t)
+			wbzlog.Logger.Error().Err(err).Msg("bad webhook subscription")
+			return nil, err
+		}
+		w.Events = append(w.Events, EventType(t))
+	}
+	if subjectid != "" {
+		subjectuuid, err := uuid.Parse(subjectid)
+		if err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("bad subject id")
+			return nil, err
+		}
+		w.SubjectID = &subjectuuid
+	}
+	w.URL = rawURL
+	w.Secret = secret
+	w.ID = uuid.New()
+	w.CreatedAt = time.Now()
+	return &w, nil
+}
+
+// PublicAddress — можно ли слать вебхук на адрес: loopback, частные, link-local и служебные адреса запрещены
+func PublicAddress(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// PublicHost отсекает localhost и внутренние IP-адреса в URL. Имена, которые резолвятся во внутреннюю сеть,
+// проверяются при подписке и повторно при каждом соединении.
+func PublicHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return PublicAddress(ip)
+	}
+	return true
+}
+
+// Matches проверяет фильтры подписки: пустой список событий означает все события,
+// SubjectID ограничивает подписку веткой комментариев.
+func (w *WebhookSubscription) Matches(event CommentEvent) bool {
+	if len(w.Events) > 0 {
+		found := false
+		for _, t := range w.Events {
+			if t == event.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return w.SubjectID == nil || event.InSubtree(*w.SubjectID)
+}
+
+// SignPayload возвращает подпись HMAC-SHA256 тела запроса в формате "sha256=<hex>"
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewWebhookSubscription(t *testing.T) {
+	t.Run("Create subscription for all events", func(t *testing.T) {
+		sub, err := NewWebhookSubscription("https://example.com/hook", "secret", nil, "")
+		assert.NoError(t, err)
+		assert.Empty(t, sub.Events)
+		assert.Nil(t, sub.SubjectID)
+		assert.NotEqual(t, uuid.Nil, sub.ID)
+	})
+
+	t.Run("Fail on invalid url", func(t *testing.T) {
+		sub, err := NewWebhookSubscription("ftp://example.com", "secret", nil, "")
+		assert.Error(t, err)
+		assert.Nil(t, sub)
+	})
+
+	t.Run("Fail on internal address", func(t *testing.T) {
+		for _, target := range []string{
+			"http://127.0.0.1:8080/hook",
+			"http://localhost/hook",
+			"http://10.0.0.5/hook",
+			"http://192.168.1.1/hook",
+			"http://169.254.169.254/latest/meta-data",
+			"http://[::1]/hook",
+			"http://0.0.0.0/hook",
+		} {
+			sub, err := NewWebhookSubscription(target, "secret", nil, "")
+			assert.ErrorIs(t, err, ErrWebhookTarget, target)
+			assert.Nil(t, sub)
+		}
+	})
+
+	t.Run("Fail on empty secret", func(t *testing.T) {
+		sub, err := NewWebhookSubscription("https://example.com/hook", "", nil, "")
+		assert.Error(t, err)
+		assert.Nil(t, sub)
+	})
+
+	t.Run("Create subscription for moderation events", func(t *testing.T) {
+		sub, err := NewWebhookSubscription("https://example.com/hook", "secret", []string{"comment.hidden", "comment.rejected"}, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []EventType{EventCommentHidden, EventCommentRejected}, sub.Events)
+	})
+
+	t.Run("Fail on unknown event type", func(t *testing.T) {
+		sub, err := NewWebhookSubscription("https://example.com/hook", "secret", []string{"comment.liked"}, "")
+		assert.Error(t, err)
+		assert.Nil(t, sub)
+	})
+}
+
+func TestWebhookSubscription_Matches(t *testing.T) {
+	rootID := uuid.New()
+	event := NewCommentEvent(EventCommentCreated, uuid.New(), nil)
+	event.Path = []uuid.UUID{rootID, event.CommentID}
+
+	all, _ := NewWebhookSubscription("https://example.com/hook", "secret", nil, "")
+	assert.True(t, all.Matches(event))
+
+	deletesOnly, _ := NewWebhookSubscription("https://example.com/hook", "secret", []string{string(EventCommentDeleted)}, "")
+	assert.False(t, deletesOnly.Matches(event))
+
+	inThread, _ := NewWebhookSubscription("https://example.com/hook", "secret", nil, rootID.String())
+	assert.True(t, inThread.Matches(event))
+
+	otherThread, _ := NewWebhookSubscription("https://example.com/hook", "secret", nil, uuid.NewString())
+	assert.False(t, otherThread.Matches(event))
+}
+
+func TestSignPayload(t *testing.T) {
+	body := []byte(`{"type":"comment.created"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), SignPayload("secret", body))
+	assert.NotEqual(t, SignPayload("secret", body), SignPayload("other", body))
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"time"
+)
+
+// maxIdempotencyKeyLength — ограничение на длину ключа от клиента
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyService хранит ответы на запросы с ключом идемпотентности, чтобы повтор
+// запроса клиентом вернул исходный ответ, а не выполнил его еще раз
+type IdempotencyService struct {
+	db          IdempotencyDbProvider
+	ttl         time.Duration
+	lockTimeout time.Duration
+}
+
+type IdempotencyDbProvider interface {
+	ReserveIdempotencyKey(scope, key, requestHash string, lockedUntil, expiresAt time.Time) (*app.IdempotencyRecord, error)
+	CompleteIdempotencyKey(scope, key string, response app.IdempotentResponse) error
+	ReleaseIdempotencyKey(scope, key string) error
+}
+
+func NewIdempotencyService(db IdempotencyDbProvider, cfg *config.AppConfig) *IdempotencyService {
+	s := &IdempotencyService{
+		db:          db,
+		ttl:         cfg.IdempotencyConfig.TTL,
+		lockTimeout: cfg.IdempotencyConfig.LockTimeout,
+	}
+	if s.ttl <= 0 {
+		s.ttl = 24 * time.Hour
+	}
+	if s.lockTimeout <= 0 {
+		s.lockTimeout = time.Minute
+	}
+	return s
+}
+
+// Begin занимает ключ клиента scope. nil — запрос нужно выполнить и затем вызвать Complete или Release;
+// иначе возвращается сохраненный ответ на такой же запрос.
+func (s *IdempotencyService) Begin(scope, key, requestHash string) (*app.IdempotentResponse, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "Idempotency-Key", Code: "too_long", Message: "must be at most 255 characters"}}}
+	}
+	now := time.Now()
+	record, err := s.db.ReserveIdempotencyKey(scope, key, requestHash, now.Add(s.lockTimeout), now.Add(s.ttl))
+	if err != nil || record == nil {
+		return nil, err
+	}
+	if record.RequestHash != requestHash {
+		return nil, app.ErrIdempotencyMismatch
+	}
+	if record.Response == nil {
+		return nil, app.ErrIdempotencyInFlight
+	}
+	return record.Response, nil
+}
+
+// Complete сохраняет ответ на выполненный запрос
+func (s *IdempotencyService) Complete(scope, key string, response app.IdempotentResponse) error {
+	return s.db.CompleteIdempotencyKey(scope, key, response)
+}
+
+// Release освобождает ключ запроса, который не удался
+func (s *IdempotencyService) Release(scope, key string) error {
+	return s.db.ReleaseIdempotencyKey(scope, key)
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	domain "commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockIdempotencyDb struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyDb) ReserveIdempotencyKey(scope, key, requestHash string, lockedUntil, expiresAt time.Time) (*domain.IdempotencyRecord, error) {
+	args := m.Called(scope, key, requestHash, lockedUntil, expiresAt)
+	return args.Get(0).(*domain.IdempotencyRecord), args.Error(1)
+}
+
+func (m *MockIdempotencyDb) CompleteIdempotencyKey(scope, key string, response domain.IdempotentResponse) error {
+	args := m.Called(scope, key, response)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyDb) ReleaseIdempotencyKey(scope, key string) error {
+	args := m.Called(scope, key)
+	return args.Error(0)
+}
+
+func TestIdempotencyService_Begin(t *testing.T) {
+	mockDb := new(MockIdempotencyDb)
+	service := NewIdempotencyService(mockDb, &config.AppConfig{})
+
+	// Свободный ключ
+	mockDb.On("ReserveIdempotencyKey", "user", "fresh", "h1", mock.Anything, mock.Anything).Return((*domain.IdempotencyRecord)(nil), nil)
+	saved, err := service.Begin("user", "fresh", "h1")
+	assert.NoError(t, err)
+	assert.Nil(t, saved)
+
+	// Повтор выполненного запроса
+	response := &domain.IdempotentResponse{Status: 201, Body: []byte(`{"id":"1"}`)}
+	mockDb.On("ReserveIdempotencyKey", "user", "done", "h1", mock.Anything, mock.Anything).
+		Return(&domain.IdempotencyRecord{RequestHash: "h1", Response: response}, nil)
+	saved, err = service.Begin("user", "done", "h1")
+	assert.NoError(t, err)
+	assert.Equal(t, response, saved)
+
+	// Тот же ключ с другим телом
+	mockDb.On("ReserveIdempotencyKey", "user", "done", "h2", mock.Anything, mock.Anything).
+		Return(&domain.IdempotencyRecord{RequestHash: "h1", Response: response}, nil)
+	_, err = service.Begin("user", "done", "h2")
+	assert.ErrorIs(t, err, domain.ErrIdempotencyMismatch)
+
+	// Первый запрос еще выполняется
+	mockDb.On("ReserveIdempotencyKey", "user", "busy", "h1", mock.Anything, mock.Anything).
+		Return(&domain.IdempotencyRecord{RequestHash: "h1"}, nil)
+	_, err = service.Begin("user", "busy", "h1")
+	assert.ErrorIs(t, err, domain.ErrIdempotencyInFlight)
+
+	var verr *domain.ValidationError
+	_, err = service.Begin("user", strings.Repeat("k", 256), "h1")
+	assert.ErrorAs(t, err, &verr)
+}
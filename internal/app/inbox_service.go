@@ -0,0 +1,72 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// InboxService — подписки на ветки и лента новых комментариев в них.
+// Комментарии попадают во входящие при сохранении (db.SaveComment), автор подписывается на свою ветку там же.
+type InboxService struct {
+	db InboxDbProvider
+}
+
+type InboxDbProvider interface {
+	SubscribeThread(userID, commentID string) error
+	UnsubscribeThread(userID, commentID string) error
+	GetInbox(userID string, unreadOnly bool, page, pageSize int) ([]app.InboxItem, error)
+	CountUnread(userID string) (int64, error)
+	MarkInboxRead(userID string, commentIDs []uuid.UUID, readAt time.Time) (int64, error)
+}
+
+func NewInboxService(db InboxDbProvider) *InboxService {
+	return &InboxService{
+		db: db,
+	}
+}
+
+func (s *InboxService) Subscribe(userID, commentID string) error {
+	if _, err := uuid.Parse(commentID); err != nil {
+		return app.ErrNotFound
+	}
+	return s.db.SubscribeThread(userID, commentID)
+}
+
+func (s *InboxService) Unsubscribe(userID, commentID string) error {
+	if _, err := uuid.Parse(commentID); err != nil {
+		return app.ErrNotFound
+	}
+	return s.db.UnsubscribeThread(userID, commentID)
+}
+
+func (s *InboxService) GetInbox(userID string, unreadOnly bool, page, pageSize int) (*app.Inbox, error) {
+	items, err := s.db.GetInbox(userID, unreadOnly, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.db.CountUnread(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &app.Inbox{Items: items, UnreadCount: unread}, nil
+}
+
+// MarkRead отмечает прочитанными переданные комментарии, а без них — всю ленту.
+// Возвращает оставшееся число непрочитанных.
+func (s *InboxService) MarkRead(userID string, commentIDs []string) (int64, error) {
+	ids := make([]uuid.UUID, 0, len(commentIDs))
+	for _, raw := range commentIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("invalid comment id")
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if _, err := s.db.MarkInboxRead(userID, ids, time.Now()); err != nil {
+		return 0, err
+	}
+	return s.db.CountUnread(userID)
+}
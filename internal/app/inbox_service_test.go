@@ -0,0 +1,75 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type MockInboxDb struct {
+	mock.Mock
+}
+
+func (m *MockInboxDb) SubscribeThread(userID, commentID string) error {
+	args := m.Called(userID, commentID)
+	return args.Error(0)
+}
+
+func (m *MockInboxDb) UnsubscribeThread(userID, commentID string) error {
+	args := m.Called(userID, commentID)
+	return args.Error(0)
+}
+
+func (m *MockInboxDb) GetInbox(userID string, unreadOnly bool, page, pageSize int) ([]app.InboxItem, error) {
+	args := m.Called(userID, unreadOnly, page, pageSize)
+	return args.Get(0).([]app.InboxItem), args.Error(1)
+}
+
+func (m *MockInboxDb) CountUnread(userID string) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockInboxDb) MarkInboxRead(userID string, commentIDs []uuid.UUID, readAt time.Time) (int64, error) {
+	args := m.Called(userID, commentIDs, readAt)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestInboxService_GetInbox(t *testing.T) {
+	mockDb := new(MockInboxDb)
+	service := NewInboxService(mockDb)
+
+	items := []app.InboxItem{{Comment: app.Comment{ID: uuid.New(), Text: "New reply"}}}
+	mockDb.On("GetInbox", "user", true, 1, 20).Return(items, nil)
+	mockDb.On("CountUnread", "user").Return(int64(3), nil)
+
+	inbox, err := service.GetInbox("user", true, 1, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, items, inbox.Items)
+	assert.Equal(t, int64(3), inbox.UnreadCount)
+}
+
+func TestInboxService_MarkRead(t *testing.T) {
+	t.Run("Selected comments", func(t *testing.T) {
+		mockDb := new(MockInboxDb)
+		service := NewInboxService(mockDb)
+
+		id := uuid.New()
+		mockDb.On("MarkInboxRead", "user", []uuid.UUID{id}, mock.Anything).Return(int64(1), nil)
+		mockDb.On("CountUnread", "user").Return(int64(2), nil)
+
+		unread, err := service.MarkRead("user", []string{id.String()})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), unread)
+	})
+
+	t.Run("Invalid id", func(t *testing.T) {
+		service := NewInboxService(new(MockInboxDb))
+
+		_, err := service.MarkRead("user", []string{"bad"})
+		assert.Error(t, err)
+	})
+}
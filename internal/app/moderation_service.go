@@ -0,0 +1,211 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+// ModerationService — инструменты модераторов: журнал срабатываний фильтров,
+// очередь модерации, решения по комментариям и премодерация веток
+type ModerationService struct {
+	db       ModerationDbProvider
+	comments *CommentService
+}
+
+type ModerationDbProvider interface {
+	GetFilterHits(action, commentID string, page, pageSize int) ([]app.FilterHit, error)
+	GetModerationQueue(status app.CommentStatus, page, pageSize int) ([]app.Comment, error)
+	ModerateComments(ids []uuid.UUID, action app.ModerationAction, reason string) ([]app.Comment, error)
+	SetPremoderation(commentID string, enabled bool) error
+	SetSlowMode(commentID string, interval time.Duration) error
+	MoveComment(id uuid.UUID, parentID *uuid.UUID, actorID string) (*app.CommentMove, error)
+	SplitThread(id uuid.UUID, actorID string) (*app.ThreadSplit, error)
+	MergeThreads(sourceID, targetID uuid.UUID, actorID string) (*app.ThreadMerge, error)
+}
+
+// NewModerationService создает сервис; через comments решения модератора доходят до хуков событий
+func NewModerationService(db ModerationDbProvider, comments *CommentService) *ModerationService {
+	return &ModerationService{
+		db:       db,
+		comments: comments,
+	}
+}
+
+// GetFilterHits возвращает срабатывания фильтров; action — flag, reject или пусто для всех
+func (s *ModerationService) GetFilterHits(action, commentID string, page, pageSize int) ([]app.FilterHit, error) {
+	if action != "" && action != string(app.FilterFlag) && action != string(app.FilterReject) {
+		return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "action", Code: "invalid", Message: "must be flag or reject"}}}
+	}
+	if commentID != "" {
+		if _, err := uuid.Parse(commentID); err != nil {
+			return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "comment_id", Code: "invalid", Message: "must be a UUID"}}}
+		}
+	}
+	return s.db.GetFilterHits(action, commentID, page, pageSize)
+}
+
+// GetQueue возвращает комментарии в состоянии status, по умолчанию ожидающие модерации
+func (s *ModerationService) GetQueue(status string, page, pageSize int) ([]app.Comment, error) {
+	queueStatus := app.StatusPending
+	if status != "" {
+		queueStatus = app.CommentStatus(status)
+	}
+	if !app.ValidQueueStatus(queueStatus) {
+		return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "status", Code: "invalid", Message: "must be pending, hidden or rejected"}}}
+	}
+	return s.db.GetModerationQueue(queueStatus, page, pageSize)
+}
+
+// Moderate применяет действие к комментариям ids. Неподходящие по состоянию комментарии
+// не считаются ошибкой и возвращаются в Skipped.
+func (s *ModerationService) Moderate(ids []string, action, reason string) (*app.ModerationResult, error) {
+	moderationAction := app.ModerationAction(action)
+	if _, _, err := moderationAction.Transition(); err != nil {
+		return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "action", Code: "invalid", Message: err.Error()}}}
+	}
+	if len(ids) == 0 {
+		return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "comment_ids", Code: "required", Message: "must not be empty"}}}
+	}
+	commentIDs := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		commentID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "comment_ids", Code: "invalid", Message: "must be UUIDs"}}}
+		}
+		commentIDs = append(commentIDs, commentID)
+	}
+
+	moderated, err := s.db.ModerateComments(commentIDs, moderationAction, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &app.ModerationResult{Moderated: []uuid.UUID{}, Skipped: []uuid.UUID{}}
+	done := make(map[uuid.UUID]bool, len(moderated))
+	for i := range moderated {
+		c := &moderated[i]
+		done[c.ID] = true
+		result.Moderated = append(result.Moderated, c.ID)
+		switch c.Status {
+		case app.StatusActive:
+			s.comments.publish(app.NewCommentEvent(app.EventCommentCreated, c.ID, c))
+		case app.StatusHidden:
+			s.comments.publish(app.NewCommentEvent(app.EventCommentDeleted, c.ID, nil))
+		}
+	}
+	for _, id := range commentIDs {
+		if !done[id] {
+			result.Skipped = append(result.Skipped, id)
+			done[id] = true
+		}
+	}
+	return result, nil
+}
+
+// SetPremoderation включает или выключает премодерацию ветки, в которой находится commentID
+func (s *ModerationService) SetPremoderation(commentID string, enabled bool) error {
+	if _, err := uuid.Parse(commentID); err != nil {
+		return app.ErrNotFound
+	}
+	return s.db.SetPremoderation(commentID, enabled)
+}
+
+// SetSlowMode включает медленный режим ветки, в которой находится commentID; 0 выключает его
+func (s *ModerationService) SetSlowMode(commentID string, interval time.Duration) error {
+	if interval < 0 || interval > app.MaxSlowMode {
+		return &app.ValidationError{Errors: []app.FieldError{{Field: "slow_mode_seconds", Code: "invalid", Message: fmt.Sprintf("must be between 0 and %d", int(app.MaxSlowMode.Seconds()))}}}
+	}
+	if _, err := uuid.Parse(commentID); err != nil {
+		return app.ErrNotFound
+	}
+	return s.db.SetSlowMode(commentID, interval)
+}
+
+// MoveComment переносит комментарий с ответами под parentID; пустой parentID делает его корнем
+// новой ветки. Перенос под себя или свой ответ — ErrMoveCycle, под неопубликованный комментарий — ErrNotFound,
+// перенос к текущему родителю ничего не меняет.
+func (s *ModerationService) MoveComment(id, parentID, actorID string) (*app.CommentMove, error) {
+	commentID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, app.ErrNotFound
+	}
+	var newParentID *uuid.UUID
+	if parentID != "" {
+		parsed, err := uuid.Parse(parentID)
+		if err != nil {
+			return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "parent_id", Code: "invalid", Message: "must be a UUID or null"}}}
+		}
+		newParentID = &parsed
+	}
+
+	move, err := s.db.MoveComment(commentID, newParentID, actorID)
+	if errors.Is(err, app.ErrMoveParentNotFound) {
+		return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "parent_id", Code: "invalid", Message: err.Error()}}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.publishMove(move)
+	return move, nil
+}
+
+// SplitThread выносит комментарий с ответами в отдельную ветку; на прежнем месте остается
+// служебный комментарий со ссылкой на новую ветку. Корень ветки разделить нельзя — ErrAlreadyThread.
+func (s *ModerationService) SplitThread(id, actorID string) (*app.ThreadSplit, error) {
+	commentID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, app.ErrNotFound
+	}
+	split, err := s.db.SplitThread(commentID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	s.publishMove(&split.Move)
+	s.comments.publish(app.NewCommentEvent(app.EventCommentCreated, split.Link.ID, split.Link))
+	return split, nil
+}
+
+// MergeThreads переносит ответы корня ветки id под корень ветки into, перенаправляет ключ
+// исходной ветки на целевую и удаляет ее корень. Обе ветки задаются любым своим комментарием.
+func (s *ModerationService) MergeThreads(id, into, actorID string) (*app.ThreadMerge, error) {
+	sourceID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, app.ErrNotFound
+	}
+	targetID, err := uuid.Parse(into)
+	if err != nil {
+		return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "into", Code: "invalid", Message: "must be a UUID"}}}
+	}
+	merge, err := s.db.MergeThreads(sourceID, targetID, actorID)
+	if errors.Is(err, app.ErrMoveParentNotFound) {
+		return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "into", Code: "invalid", Message: "target thread not found"}}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range merge.Moves {
+		s.publishMove(&merge.Moves[i])
+	}
+	if merge.RootDeleted {
+		s.comments.publish(app.NewCommentEvent(app.EventCommentDeleted, merge.FromRootID, nil))
+	}
+	return merge, nil
+}
+
+// publishMove передает хукам событие о переносе опубликованного комментария
+func (s *ModerationService) publishMove(move *app.CommentMove) {
+	if move.Noop() || move.Comment.Status != app.StatusActive {
+		return
+	}
+	event := app.NewCommentEvent(app.EventCommentMoved, move.CommentID, move.Comment)
+	event.PreviousPath = move.PreviousPath
+	s.comments.publish(event)
+}
+
+// GetUserTrust возвращает уровень доверия пользователя и показатели, из которых он получен
+func (s *ModerationService) GetUserTrust(userID string) (*app.Trust, error) {
+	return s.comments.Trust(userID)
+}
@@ -0,0 +1,305 @@
+package app
+
+import (
+	domain "commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type MockModerationDb struct {
+	mock.Mock
+}
+
+func (m *MockModerationDb) GetFilterHits(action, commentID string, page, pageSize int) ([]domain.FilterHit, error) {
+	args := m.Called(action, commentID, page, pageSize)
+	return args.Get(0).([]domain.FilterHit), args.Error(1)
+}
+
+func (m *MockModerationDb) GetModerationQueue(status domain.CommentStatus, page, pageSize int) ([]domain.Comment, error) {
+	args := m.Called(status, page, pageSize)
+	return args.Get(0).([]domain.Comment), args.Error(1)
+}
+
+func (m *MockModerationDb) ModerateComments(ids []uuid.UUID, action domain.ModerationAction, reason string) ([]domain.Comment, error) {
+	args := m.Called(ids, action, reason)
+	return args.Get(0).([]domain.Comment), args.Error(1)
+}
+
+func (m *MockModerationDb) SetPremoderation(commentID string, enabled bool) error {
+	args := m.Called(commentID, enabled)
+	return args.Error(0)
+}
+
+func (m *MockModerationDb) SetSlowMode(commentID string, interval time.Duration) error {
+	args := m.Called(commentID, interval)
+	return args.Error(0)
+}
+
+func (m *MockModerationDb) MoveComment(id uuid.UUID, parentID *uuid.UUID, actorID string) (*domain.CommentMove, error) {
+	args := m.Called(id, parentID, actorID)
+	move, _ := args.Get(0).(*domain.CommentMove)
+	return move, args.Error(1)
+}
+
+func (m *MockModerationDb) SplitThread(id uuid.UUID, actorID string) (*domain.ThreadSplit, error) {
+	args := m.Called(id, actorID)
+	split, _ := args.Get(0).(*domain.ThreadSplit)
+	return split, args.Error(1)
+}
+
+func (m *MockModerationDb) MergeThreads(sourceID, targetID uuid.UUID, actorID string) (*domain.ThreadMerge, error) {
+	args := m.Called(sourceID, targetID, actorID)
+	merge, _ := args.Get(0).(*domain.ThreadMerge)
+	return merge, args.Error(1)
+}
+
+func TestModerationService_SetSlowMode(t *testing.T) {
+	mockDb := new(MockModerationDb)
+	service := NewModerationService(mockDb, nil)
+
+	id := uuid.New().String()
+	mockDb.On("SetSlowMode", id, 30*time.Second).Return(nil)
+	assert.NoError(t, service.SetSlowMode(id, 30*time.Second))
+
+	var verr *domain.ValidationError
+	assert.ErrorAs(t, service.SetSlowMode(id, 48*time.Hour), &verr)
+	assert.ErrorIs(t, service.SetSlowMode("bad", time.Minute), domain.ErrNotFound)
+	mockDb.AssertNumberOfCalls(t, "SetSlowMode", 1)
+}
+
+func TestModerationService_Moderate(t *testing.T) {
+	mockDb := new(MockModerationDb)
+	commentsDb := new(MockDb)
+	comments := NewCommentService(commentsDb, &config.AppConfig{})
+	service := NewModerationService(mockDb, comments)
+
+	approved := domain.Comment{ID: uuid.New(), Text: "Ok", Status: domain.StatusActive}
+	skipped := uuid.New()
+	ids := []uuid.UUID{approved.ID, skipped}
+
+	mockDb.On("ModerateComments", ids, domain.ModerationApprove, "fine").Return([]domain.Comment{approved}, nil)
+	commentsDb.On("GetAncestorIDs", approved.ID.String()).Return([]uuid.UUID{}, nil)
+
+	received := make(chan domain.CommentEvent, 1)
+	comments.Subscribe(func(event domain.CommentEvent) {
+		received <- event
+	})
+
+	result, err := service.Moderate([]string{approved.ID.String(), skipped.String()}, "approve", "fine")
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{approved.ID}, result.Moderated)
+	assert.Equal(t, []uuid.UUID{skipped}, result.Skipped)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, domain.EventCommentCreated, event.Type)
+		assert.Equal(t, approved.ID, event.CommentID)
+	case <-time.After(time.Second):
+		t.Fatal("event was not published")
+	}
+	mockDb.AssertExpectations(t)
+}
+
+func TestModerationService_Moderate_Validation(t *testing.T) {
+	mockDb := new(MockModerationDb)
+	service := NewModerationService(mockDb, NewCommentService(new(MockDb), &config.AppConfig{}))
+
+	var verr *domain.ValidationError
+	_, err := service.Moderate([]string{uuid.New().String()}, "delete", "")
+	assert.ErrorAs(t, err, &verr)
+
+	_, err = service.Moderate([]string{"not-a-uuid"}, "hide", "")
+	assert.ErrorAs(t, err, &verr)
+
+	_, err = service.GetQueue("active", 1, 10)
+	assert.ErrorAs(t, err, &verr)
+	mockDb.AssertNotCalled(t, "ModerateComments", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestModerationService_MoveComment(t *testing.T) {
+	mockDb := new(MockModerationDb)
+	commentsDb := new(MockDb)
+	comments := NewCommentService(commentsDb, &config.AppConfig{})
+	service := NewModerationService(mockDb, comments)
+
+	oldRootID, newParentID := uuid.New(), uuid.New()
+	moved := domain.Comment{ID: uuid.New(), Text: "Moved", Status: domain.StatusActive, ParentID: &newParentID}
+	move := &domain.CommentMove{
+		CommentID:    moved.ID,
+		FromParentID: &oldRootID,
+		ToParentID:   &newParentID,
+		Moved:        1,
+		Comment:      &moved,
+		PreviousPath: []uuid.UUID{oldRootID, moved.ID},
+	}
+	mockDb.On("MoveComment", moved.ID, &newParentID, "moderator").Return(move, nil)
+	commentsDb.On("GetAncestorIDs", moved.ID.String()).Return([]uuid.UUID{newParentID}, nil)
+
+	received := make(chan domain.CommentEvent, 1)
+	comments.Subscribe(func(event domain.CommentEvent) {
+		received <- event
+	})
+
+	result, err := service.MoveComment(moved.ID.String(), newParentID.String(), "moderator")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Moved)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, domain.EventCommentMoved, event.Type)
+		assert.True(t, event.InSubtree(oldRootID))
+		assert.True(t, event.InSubtree(newParentID))
+	case <-time.After(time.Second):
+		t.Fatal("event was not published")
+	}
+}
+
+func TestModerationService_MoveComment_Errors(t *testing.T) {
+	mockDb := new(MockModerationDb)
+	service := NewModerationService(mockDb, NewCommentService(new(MockDb), &config.AppConfig{}))
+
+	id, parentID := uuid.New(), uuid.New()
+	mockDb.On("MoveComment", id, &parentID, "").Return(nil, domain.ErrMoveParentNotFound).Once()
+	var verr *domain.ValidationError
+	_, err := service.MoveComment(id.String(), parentID.String(), "")
+	assert.ErrorAs(t, err, &verr)
+
+	mockDb.On("MoveComment", id, &parentID, "").Return(nil, domain.ErrMoveCycle).Once()
+	_, err = service.MoveComment(id.String(), parentID.String(), "")
+	assert.ErrorIs(t, err, domain.ErrMoveCycle)
+
+	// Неопубликованный родитель — не ошибка валидации, а отсутствующий комментарий
+	mockDb.On("MoveComment", id, &parentID, "").Return(nil, domain.ErrNotFound).Once()
+	_, err = service.MoveComment(id.String(), parentID.String(), "")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	_, err = service.MoveComment(id.String(), "not-a-uuid", "")
+	assert.ErrorAs(t, err, &verr)
+	_, err = service.MoveComment("bad", "", "")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	mockDb.AssertNumberOfCalls(t, "MoveComment", 3)
+}
+
+func TestModerationService_SplitThread(t *testing.T) {
+	mockDb := new(MockModerationDb)
+	commentsDb := new(MockDb)
+	comments := NewCommentService(commentsDb, &config.AppConfig{})
+	service := NewModerationService(mockDb, comments)
+
+	oldRootID := uuid.New()
+	thread := domain.Comment{ID: uuid.New(), Text: "Off-topic", Status: domain.StatusActive}
+	link := domain.NewThreadLink(oldRootID, thread.ID)
+	mockDb.On("SplitThread", thread.ID, "moderator").Return(&domain.ThreadSplit{
+		ThreadID: thread.ID,
+		Link:     link,
+		Move: domain.CommentMove{
+			CommentID:    thread.ID,
+			FromParentID: &oldRootID,
+			Comment:      &thread,
+			PreviousPath: []uuid.UUID{oldRootID, thread.ID},
+		},
+	}, nil)
+	commentsDb.On("GetAncestorIDs", thread.ID.String()).Return([]uuid.UUID{}, nil)
+	commentsDb.On("GetAncestorIDs", link.ID.String()).Return([]uuid.UUID{oldRootID}, nil)
+
+	received := make(chan domain.CommentEvent, 2)
+	comments.Subscribe(func(event domain.CommentEvent) {
+		received <- event
+	})
+
+	split, err := service.SplitThread(thread.ID.String(), "moderator")
+	assert.NoError(t, err)
+	assert.Contains(t, split.Link.HTML, thread.ID.String())
+
+	types := map[domain.EventType]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-received:
+			types[event.Type] = true
+			assert.True(t, event.InSubtree(oldRootID))
+		case <-time.After(time.Second):
+			t.Fatal("event was not published")
+		}
+	}
+	assert.True(t, types[domain.EventCommentMoved])
+	assert.True(t, types[domain.EventCommentCreated])
+}
+
+func TestModerationService_MergeThreads(t *testing.T) {
+	mockDb := new(MockModerationDb)
+	commentsDb := new(MockDb)
+	comments := NewCommentService(commentsDb, &config.AppConfig{})
+	service := NewModerationService(mockDb, comments)
+
+	sourceID, targetID := uuid.New(), uuid.New()
+	reply := domain.Comment{ID: uuid.New(), Text: "Reply", Status: domain.StatusActive}
+	mockDb.On("MergeThreads", sourceID, targetID, "moderator").Return(&domain.ThreadMerge{
+		FromRootID: sourceID,
+		ToRootID:   targetID,
+		Roots:      []uuid.UUID{reply.ID},
+		Moved:      1,
+		Moves: []domain.CommentMove{{
+			CommentID:    reply.ID,
+			FromParentID: &sourceID,
+			ToParentID:   &targetID,
+			Comment:      &reply,
+			PreviousPath: []uuid.UUID{sourceID, reply.ID},
+		}},
+		RootDeleted: true,
+	}, nil)
+	commentsDb.On("GetAncestorIDs", reply.ID.String()).Return([]uuid.UUID{targetID}, nil)
+	commentsDb.On("GetAncestorIDs", sourceID.String()).Return([]uuid.UUID{}, nil)
+
+	received := make(chan domain.CommentEvent, 2)
+	comments.Subscribe(func(event domain.CommentEvent) {
+		received <- event
+	})
+
+	_, err := service.MergeThreads(sourceID.String(), targetID.String(), "moderator")
+	assert.NoError(t, err)
+
+	events := map[domain.EventType]uuid.UUID{}
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-received:
+			events[event.Type] = event.CommentID
+		case <-time.After(time.Second):
+			t.Fatal("event was not published")
+		}
+	}
+	assert.Equal(t, reply.ID, events[domain.EventCommentMoved])
+	assert.Equal(t, sourceID, events[domain.EventCommentDeleted])
+
+	// Исходная ветка после слияния: дерево перенаправляется, удаленный корень не находится
+	commentsDb.On("GetThreadRedirect", sourceID.String()).Return(&targetID, nil)
+	commentsDb.On("GetCommentPath", sourceID.String(), "").Return([]domain.Comment(nil), domain.ErrNotFound)
+	redirect, err := comments.ThreadRedirect(sourceID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, targetID.String(), redirect)
+	_, err = comments.GetComment(sourceID.String(), "")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestModerationService_MergeThreads_Errors(t *testing.T) {
+	mockDb := new(MockModerationDb)
+	service := NewModerationService(mockDb, NewCommentService(new(MockDb), &config.AppConfig{}))
+
+	sourceID, targetID := uuid.New(), uuid.New()
+	mockDb.On("MergeThreads", sourceID, targetID, "").Return(nil, domain.ErrMoveParentNotFound).Once()
+	var verr *domain.ValidationError
+	_, err := service.MergeThreads(sourceID.String(), targetID.String(), "")
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, "into", verr.Errors[0].Field)
+
+	mockDb.On("MergeThreads", sourceID, targetID, "").Return(nil, domain.ErrSameThread).Once()
+	_, err = service.MergeThreads(sourceID.String(), targetID.String(), "")
+	assert.ErrorIs(t, err, domain.ErrSameThread)
+
+	_, err = service.MergeThreads(sourceID.String(), "bad", "")
+	assert.ErrorAs(t, err, &verr)
+	mockDb.AssertNumberOfCalls(t, "MergeThreads", 2)
+}
@@ -0,0 +1,269 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"commentTree/internal/jobs"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	NotifyReplyKind   = "notifications.reply"
+	NotifyMentionKind = "notifications.mention"
+	NotifyDigestKind  = "notifications.digest"
+)
+
+// Notifier доставляет письмо пользователю (лог, SMTP)
+type Notifier interface {
+	Send(ctx context.Context, msg app.Message) error
+}
+
+// JobQueue ставит фоновые задачи
+type JobQueue interface {
+	Enqueue(ctx context.Context, kind string, payload any, opts ...jobs.Option) (*app.Job, error)
+}
+
+type NotificationDbProvider interface {
+	GetComment(id string) (*app.Comment, error)
+	GetUser(id string) (*app.User, error)
+	SaveNotification(n *app.Notification) error
+	MarkNotificationsSent(ids []uuid.UUID, sentAt time.Time) error
+	GetDigestRecipients() ([]app.User, error)
+	GetUnsentNotifications(userID uuid.UUID) ([]app.Notification, error)
+}
+
+// NotificationService уведомляет авторов об ответах на их комментарии и пользователей об упоминаниях.
+// Событие создания только ставит задачу, письмо отправляется воркером jobs с ретраями.
+type NotificationService struct {
+	db             NotificationDbProvider
+	queue          JobQueue
+	notifier       Notifier
+	baseURL        string
+	secret         string
+	digestSchedule string
+}
+
+type commentPayload struct {
+	CommentID string `json:"comment_id"`
+}
+
+func NewNotificationService(db NotificationDbProvider, queue JobQueue, notifier Notifier, cfg *config.AppConfig) *NotificationService {
+	notifyCfg := cfg.NotifyConfig
+	if notifyCfg.UnsubscribeSecret == "" {
+		wbzlog.Logger.Warn().Msg("UNSUBSCRIBE_SECRET is not set, notification emails will have no unsubscribe link")
+	}
+	digestSchedule := notifyCfg.DigestSchedule
+	if digestSchedule == "" {
+		digestSchedule = "0 9 * * *"
+	}
+	return &NotificationService{
+		db:             db,
+		queue:          queue,
+		notifier:       notifier,
+		baseURL:        strings.TrimRight(notifyCfg.BaseURL, "/"),
+		secret:         notifyCfg.UnsubscribeSecret,
+		digestSchedule: digestSchedule,
+	}
+}
+
+// HandleEvent — хук CommentService: ставит задачи уведомлений об ответе и упоминаниях
+func (s *NotificationService) HandleEvent(event app.CommentEvent) {
+	if event.Comment == nil {
+		return
+	}
+	id := event.Comment.ID.String()
+	if event.Type == app.EventCommentCreated && event.Comment.ParentID != nil {
+		s.enqueue(NotifyReplyKind, id, "reply:"+id)
+	}
+	// При редактировании добавленные упоминания отправятся, уже отправленные отбросит SaveNotification
+	if (event.Type == app.EventCommentCreated || event.Type == app.EventCommentEdited) && len(event.Comment.Mentions) > 0 {
+		s.enqueue(NotifyMentionKind, id, "mention:"+event.ID.String())
+	}
+}
+
+func (s *NotificationService) enqueue(kind, commentID, key string) {
+	_, err := s.queue.Enqueue(context.Background(), kind, commentPayload{CommentID: commentID}, jobs.UniqueKey(key))
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Str("kind", kind).Str("comment_id", commentID).Msg("failed to enqueue notification")
+	}
+}
+
+// JobHandlers возвращает обработчики задач уведомлений для пула jobs
+func (s *NotificationService) JobHandlers() []jobs.Handler {
+	return []jobs.Handler{
+		jobs.NewHandler(NotifyReplyKind, s.handleReply),
+		jobs.NewHandler(NotifyMentionKind, s.handleMention),
+		jobs.NewHandler(NotifyDigestKind, func(ctx context.Context, _ struct{}) error {
+			return s.sendDigests(ctx)
+		}),
+	}
+}
+
+// DigestSchedule — ежедневная рассылка накопленных уведомлений пользователям в режиме digest
+func (s *NotificationService) DigestSchedule() jobs.Schedule {
+	return jobs.Schedule{
+		Name: "notifications-digest",
+		Spec: s.digestSchedule,
+		Kind: NotifyDigestKind,
+	}
+}
+
+func (s *NotificationService) handleReply(ctx context.Context, p commentPayload) error {
+	reply, err := s.db.GetComment(p.CommentID)
+	if err != nil {
+		return skipNotFound(err)
+	}
+	if reply.ParentID == nil {
+		return nil
+	}
+	parent, err := s.db.GetComment(reply.ParentID.String())
+	if err != nil {
+		return skipNotFound(err)
+	}
+	if parent.AuthorID == nil {
+		return nil
+	}
+	return s.deliver(ctx, app.NotificationReply, *parent.AuthorID, reply, func(n *app.Notification) (string, string) {
+		return "New reply to your comment",
+			fmt.Sprintf("%s replied to your comment:\n\n%s\n\n%s", s.actorName(reply), n.Excerpt, s.commentURL(parent.ID))
+	})
+}
+
+func (s *NotificationService) handleMention(ctx context.Context, p commentPayload) error {
+	comment, err := s.db.GetComment(p.CommentID)
+	if err != nil {
+		return skipNotFound(err)
+	}
+	notified := make(map[uuid.UUID]bool, len(comment.Mentions))
+	for _, m := range comment.Mentions {
+		if notified[m.UserID] {
+			continue
+		}
+		notified[m.UserID] = true
+		err := s.deliver(ctx, app.NotificationMention, m.UserID, comment, func(n *app.Notification) (string, string) {
+			return "You were mentioned in a comment",
+				fmt.Sprintf("%s mentioned you:\n\n%s\n\n%s", s.actorName(comment), n.Excerpt, s.commentURL(comment.ID))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver сохраняет уведомление для userID и, в режиме immediate, сразу отправляет письмо,
+// собранное compose (тема и текст). Себя о своих комментариях не уведомляем.
+func (s *NotificationService) deliver(ctx context.Context, kind string, userID uuid.UUID, c *app.Comment, compose func(n *app.Notification) (string, string)) error {
+	if c.AuthorID != nil && *c.AuthorID == userID {
+		return nil
+	}
+	user, err := s.db.GetUser(userID.String())
+	if err != nil {
+		return skipNotFound(err)
+	}
+	if user.NotifyMode == app.NotifyOff || user.Email == "" {
+		return nil
+	}
+
+	// Повтор задачи вернет уже сохраненное уведомление; отправленное второй раз не шлем
+	n := app.NewNotification(kind, user.ID, c)
+	if err := s.db.SaveNotification(n); err != nil {
+		return err
+	}
+	if n.SentAt != nil || user.NotifyMode == app.NotifyDigest {
+		return nil
+	}
+
+	subject, body := compose(n)
+	msg := s.message(user, subject, body)
+	if err := s.notifier.Send(ctx, msg); err != nil {
+		return err
+	}
+	return s.db.MarkNotificationsSent([]uuid.UUID{n.ID}, time.Now())
+}
+
+// sendDigests отправляет каждому получателю одно письмо со всеми неотправленными уведомлениями.
+// Ошибка одного получателя не мешает остальным; задача повторится только для неотправленных.
+func (s *NotificationService) sendDigests(ctx context.Context) error {
+	users, err := s.db.GetDigestRecipients()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, user := range users {
+		if err := s.sendDigest(ctx, &user); err != nil {
+			wbzlog.Logger.Error().Err(err).Str("user_id", user.ID.String()).Msg("failed to send digest")
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *NotificationService) sendDigest(ctx context.Context, user *app.User) error {
+	notifications, err := s.db.GetUnsentNotifications(user.ID)
+	if err != nil || len(notifications) == 0 {
+		return err
+	}
+	if user.Email == "" {
+		return nil
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "You have %d new notifications:\n", len(notifications))
+	ids := make([]uuid.UUID, 0, len(notifications))
+	for _, n := range notifications {
+		fmt.Fprintf(&body, "\n- [%s] %s\n  %s\n", n.Kind, n.Excerpt, s.commentURL(n.CommentID))
+		ids = append(ids, n.ID)
+	}
+
+	msg := s.message(user, "Your daily digest", body.String())
+	if err := s.notifier.Send(ctx, msg); err != nil {
+		return err
+	}
+	return s.db.MarkNotificationsSent(ids, time.Now())
+}
+
+// message добавляет к письму ссылку отписки в тело и заголовки List-Unsubscribe (RFC 8058)
+func (s *NotificationService) message(user *app.User, subject, body string) app.Message {
+	msg := app.Message{To: user.Email, Subject: subject, Body: body}
+	if s.secret == "" {
+		return msg
+	}
+	link := s.baseURL + "/api/unsubscribe?token=" + url.QueryEscape(app.UnsubscribeToken(s.secret, user.ID))
+	msg.Body += "\n\nUnsubscribe: " + link
+	msg.Headers = map[string]string{
+		"List-Unsubscribe":      "<" + link + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	return msg
+}
+
+func (s *NotificationService) actorName(c *app.Comment) string {
+	if c.AuthorID == nil {
+		return "Anonymous"
+	}
+	actor, err := s.db.GetUser(c.AuthorID.String())
+	if err != nil {
+		return "Someone"
+	}
+	return actor.Name
+}
+
+func (s *NotificationService) commentURL(id uuid.UUID) string {
+	return s.baseURL + "/api/comments?parent=" + id.String()
+}
+
+// skipNotFound: удаленный комментарий или пользователь — не ошибка, уведомлять некого
+func skipNotFound(err error) error {
+	if errors.Is(err, app.ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"commentTree/internal/jobs"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockNotificationDb struct {
+	mock.Mock
+}
+
+func (m *MockNotificationDb) GetComment(id string) (*app.Comment, error) {
+	args := m.Called(id)
+	return args.Get(0).(*app.Comment), args.Error(1)
+}
+
+func (m *MockNotificationDb) GetUser(id string) (*app.User, error) {
+	args := m.Called(id)
+	return args.Get(0).(*app.User), args.Error(1)
+}
+
+func (m *MockNotificationDb) SaveNotification(n *app.Notification) error {
+	args := m.Called(n)
+	return args.Error(0)
+}
+
+func (m *MockNotificationDb) MarkNotificationsSent(ids []uuid.UUID, sentAt time.Time) error {
+	args := m.Called(ids, sentAt)
+	return args.Error(0)
+}
+
+func (m *MockNotificationDb) GetDigestRecipients() ([]app.User, error) {
+	args := m.Called()
+	return args.Get(0).([]app.User), args.Error(1)
+}
+
+func (m *MockNotificationDb) GetUnsentNotifications(userID uuid.UUID) ([]app.Notification, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]app.Notification), args.Error(1)
+}
+
+type recordingNotifier struct {
+	sent []app.Message
+}
+
+func (n *recordingNotifier) Send(_ context.Context, msg app.Message) error {
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+type recordingQueue struct {
+	kinds []string
+}
+
+func (q *recordingQueue) Enqueue(_ context.Context, kind string, _ any, _ ...jobs.Option) (*app.Job, error) {
+	q.kinds = append(q.kinds, kind)
+	return &app.Job{Kind: kind}, nil
+}
+
+func newTestNotificationService(db NotificationDbProvider, queue JobQueue, notifier Notifier) *NotificationService {
+	cfg := &config.AppConfig{}
+	cfg.NotifyConfig.BaseURL = "http://localhost:8080/"
+	cfg.NotifyConfig.UnsubscribeSecret = "secret"
+	return NewNotificationService(db, queue, notifier, cfg)
+}
+
+// replyFixture — комментарий alice и ответ bob на него
+func replyFixture(mode string) (alice *app.User, parent, reply *app.Comment) {
+	alice = &app.User{ID: uuid.New(), Name: "alice", Email: "alice@example.com", NotifyMode: mode}
+	bobID := uuid.New()
+	parent = &app.Comment{ID: uuid.New(), Text: "Question", AuthorID: &alice.ID}
+	reply = &app.Comment{ID: uuid.New(), Text: "Answer", ParentID: &parent.ID, AuthorID: &bobID}
+	return alice, parent, reply
+}
+
+func TestNotificationService_HandleEvent_EnqueuesOnlyReplies(t *testing.T) {
+	queue := &recordingQueue{}
+	service := newTestNotificationService(new(MockNotificationDb), queue, &recordingNotifier{})
+
+	_, _, reply := replyFixture(app.NotifyImmediate)
+	root := &app.Comment{ID: uuid.New(), Text: "Root"}
+
+	service.HandleEvent(app.NewCommentEvent(app.EventCommentCreated, root.ID, root))
+	service.HandleEvent(app.NewCommentEvent(app.EventCommentDeleted, reply.ID, nil))
+	service.HandleEvent(app.NewCommentEvent(app.EventCommentCreated, reply.ID, reply))
+
+	assert.Equal(t, []string{NotifyReplyKind}, queue.kinds)
+}
+
+func TestNotificationService_HandleReply_SendsImmediately(t *testing.T) {
+	mockDb := new(MockNotificationDb)
+	notifier := &recordingNotifier{}
+	service := newTestNotificationService(mockDb, &recordingQueue{}, notifier)
+
+	alice, parent, reply := replyFixture(app.NotifyImmediate)
+	bob := &app.User{ID: *reply.AuthorID, Name: "bob"}
+
+	mockDb.On("GetComment", reply.ID.String()).Return(reply, nil)
+	mockDb.On("GetComment", parent.ID.String()).Return(parent, nil)
+	mockDb.On("GetUser", alice.ID.String()).Return(alice, nil)
+	mockDb.On("GetUser", bob.ID.String()).Return(bob, nil)
+	mockDb.On("SaveNotification", mock.Anything).Return(nil)
+	mockDb.On("MarkNotificationsSent", mock.Anything, mock.Anything).Return(nil)
+
+	require.NoError(t, service.handleReply(context.Background(), commentPayload{CommentID: reply.ID.String()}))
+
+	require.Len(t, notifier.sent, 1)
+	msg := notifier.sent[0]
+	assert.Equal(t, "alice@example.com", msg.To)
+	assert.Contains(t, msg.Body, "bob replied")
+	assert.True(t, strings.HasPrefix(msg.Headers["List-Unsubscribe"], "<http://localhost:8080/api/unsubscribe?token="))
+	assert.Equal(t, "List-Unsubscribe=One-Click", msg.Headers["List-Unsubscribe-Post"])
+	mockDb.AssertExpectations(t)
+}
+
+func TestNotificationService_HandleReply_Skips(t *testing.T) {
+	t.Run("Self reply", func(t *testing.T) {
+		mockDb := new(MockNotificationDb)
+		notifier := &recordingNotifier{}
+		service := newTestNotificationService(mockDb, &recordingQueue{}, notifier)
+
+		alice, parent, reply := replyFixture(app.NotifyImmediate)
+		reply.AuthorID = &alice.ID
+		mockDb.On("GetComment", reply.ID.String()).Return(reply, nil)
+		mockDb.On("GetComment", parent.ID.String()).Return(parent, nil)
+
+		require.NoError(t, service.handleReply(context.Background(), commentPayload{CommentID: reply.ID.String()}))
+		assert.Empty(t, notifier.sent)
+		mockDb.AssertNotCalled(t, "SaveNotification", mock.Anything)
+	})
+
+	t.Run("Digest mode keeps notification unsent", func(t *testing.T) {
+		mockDb := new(MockNotificationDb)
+		notifier := &recordingNotifier{}
+		service := newTestNotificationService(mockDb, &recordingQueue{}, notifier)
+
+		alice, parent, reply := replyFixture(app.NotifyDigest)
+		mockDb.On("GetComment", reply.ID.String()).Return(reply, nil)
+		mockDb.On("GetComment", parent.ID.String()).Return(parent, nil)
+		mockDb.On("GetUser", alice.ID.String()).Return(alice, nil)
+		mockDb.On("SaveNotification", mock.Anything).Return(nil)
+
+		require.NoError(t, service.handleReply(context.Background(), commentPayload{CommentID: reply.ID.String()}))
+		assert.Empty(t, notifier.sent)
+		mockDb.AssertNotCalled(t, "MarkNotificationsSent", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already sent on previous attempt", func(t *testing.T) {
+		mockDb := new(MockNotificationDb)
+		notifier := &recordingNotifier{}
+		service := newTestNotificationService(mockDb, &recordingQueue{}, notifier)
+
+		alice, parent, reply := replyFixture(app.NotifyImmediate)
+		sentAt := time.Now()
+		mockDb.On("GetComment", reply.ID.String()).Return(reply, nil)
+		mockDb.On("GetComment", parent.ID.String()).Return(parent, nil)
+		mockDb.On("GetUser", alice.ID.String()).Return(alice, nil)
+		mockDb.On("SaveNotification", mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(0).(*app.Notification).SentAt = &sentAt
+		}).Return(nil)
+
+		require.NoError(t, service.handleReply(context.Background(), commentPayload{CommentID: reply.ID.String()}))
+		assert.Empty(t, notifier.sent)
+	})
+}
+
+func TestNotificationService_HandleMention(t *testing.T) {
+	mockDb := new(MockNotificationDb)
+	notifier := &recordingNotifier{}
+	queue := &recordingQueue{}
+	service := newTestNotificationService(mockDb, queue, notifier)
+
+	alice := &app.User{ID: uuid.New(), Name: "alice", Email: "alice@example.com", NotifyMode: app.NotifyImmediate}
+	bob := &app.User{ID: uuid.New(), Name: "bob"}
+	comment := &app.Comment{ID: uuid.New(), Text: "@alice @alice @bob", AuthorID: &bob.ID, Mentions: []app.Mention{
+		{Offset: 0, Length: 6, UserID: alice.ID},
+		{Offset: 7, Length: 6, UserID: alice.ID},
+		{Offset: 14, Length: 4, UserID: bob.ID},
+	}}
+
+	service.HandleEvent(app.NewCommentEvent(app.EventCommentEdited, comment.ID, comment))
+	assert.Equal(t, []string{NotifyMentionKind}, queue.kinds)
+
+	mockDb.On("GetComment", comment.ID.String()).Return(comment, nil)
+	mockDb.On("GetUser", alice.ID.String()).Return(alice, nil)
+	mockDb.On("GetUser", bob.ID.String()).Return(bob, nil)
+	mockDb.On("SaveNotification", mock.Anything).Return(nil)
+	mockDb.On("MarkNotificationsSent", mock.Anything, mock.Anything).Return(nil)
+
+	require.NoError(t, service.handleMention(context.Background(), commentPayload{CommentID: comment.ID.String()}))
+
+	require.Len(t, notifier.sent, 1)
+	assert.Equal(t, "alice@example.com", notifier.sent[0].To)
+	assert.Contains(t, notifier.sent[0].Body, "bob mentioned you")
+	mockDb.AssertNumberOfCalls(t, "SaveNotification", 1)
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"fmt"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+// ReportService — жалобы и голоса пользователей за комментарии. Когда взвешенная сумма жалоб доверенных
+// пользователей достигает порога, комментарий скрывается так же, как решением модератора.
+type ReportService struct {
+	db               ReportDbProvider
+	comments         *CommentService
+	weights          app.ReportWeights
+	hideThreshold    int
+	minReporterLevel app.TrustLevel
+}
+
+type ReportDbProvider interface {
+	GetComment(id string) (*app.Comment, error)
+	SaveReport(report *app.Report, weights app.ReportWeights, minLevel app.TrustLevel) (int, error)
+	GetReportQueue(weights app.ReportWeights, page, pageSize int) ([]app.ReportSummary, error)
+	ModerateComments(ids []uuid.UUID, action app.ModerationAction, reason string) ([]app.Comment, error)
+	SaveVote(vote app.Vote) (int, error)
+}
+
+func NewReportService(db ReportDbProvider, comments *CommentService, cfg *config.AppConfig) *ReportService {
+	weights := app.ReportWeights{
+		app.ReportSpam:     cfg.ReportsConfig.SpamWeight,
+		app.ReportAbuse:    cfg.ReportsConfig.AbuseWeight,
+		app.ReportOffTopic: cfg.ReportsConfig.OffTopicWeight,
+	}
+	if cfg.ReportsConfig.SpamWeight == 0 && cfg.ReportsConfig.AbuseWeight == 0 && cfg.ReportsConfig.OffTopicWeight == 0 {
+		weights = app.ReportWeights{app.ReportSpam: 2, app.ReportAbuse: 3, app.ReportOffTopic: 1}
+	}
+	// уровня выше последнего настроенного не достичь: без уровней доверия учитываются все жалобы
+	minLevel := min(cfg.ReportsConfig.MinReporterLevel, max(len(cfg.TrustConfig.Levels)-1, 0))
+	return &ReportService{
+		db:               db,
+		comments:         comments,
+		weights:          weights,
+		hideThreshold:    cfg.ReportsConfig.HideThreshold,
+		minReporterLevel: app.TrustLevel(minLevel),
+	}
+}
+
+// Report сохраняет жалобу reporterID на опубликованный комментарий и при достижении порога скрывает его
+func (s *ReportService) Report(commentID, reporterID, reason string) (*app.Report, error) {
+	reportReason := app.ReportReason(reason)
+	if !reportReason.Valid() {
+		return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "reason", Code: "invalid", Message: "must be spam, abuse or off_topic"}}}
+	}
+	if _, err := uuid.Parse(commentID); err != nil {
+		return nil, app.ErrNotFound
+	}
+	reporter, err := uuid.Parse(reporterID)
+	if err != nil {
+		return nil, app.ErrForbidden
+	}
+	comment, err := s.db.GetComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Status != app.StatusActive {
+		return nil, app.ErrNotFound
+	}
+
+	trust, err := s.comments.authorTrust(reporterID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := app.NewReport(comment.ID, reporter, reportReason)
+	report.ReporterLevel = trust.Level
+	score, err := s.db.SaveReport(report, s.weights, s.minReporterLevel)
+	if err != nil {
+		return nil, err
+	}
+	if s.hideThreshold > 0 && score >= s.hideThreshold {
+		s.autoHide(comment.ID, score)
+	}
+	return report, nil
+}
+
+// autoHide скрывает комментарий по жалобам; жалоба уже сохранена, поэтому ошибка только логируется
+func (s *ReportService) autoHide(commentID uuid.UUID, score int) {
+	reason := fmt.Sprintf("auto-hidden: report score %d", score)
+	hidden, err := s.db.ModerateComments([]uuid.UUID{commentID}, app.ModerationHide, reason)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Str("comment_id", commentID.String()).Msg("failed to auto-hide reported comment")
+		return
+	}
+	for _, c := range hidden {
+		s.comments.publish(app.NewCommentEvent(app.EventCommentDeleted, c.ID, nil))
+	}
+}
+
+// GetReports возвращает жалобы, сгруппированные по комментариям, самые серьезные сверху
+func (s *ReportService) GetReports(page, pageSize int) ([]app.ReportSummary, error) {
+	return s.db.GetReportQueue(s.weights, page, pageSize)
+}
+
+// Vote сохраняет голос voterID за опубликованный чужой комментарий; value 0 отзывает голос
+func (s *ReportService) Vote(commentID, voterID string, value int) (*app.CommentScore, error) {
+	if !app.ValidVote(value) {
+		return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "value", Code: "invalid", Message: "must be -1, 0 or 1"}}}
+	}
+	if _, err := uuid.Parse(commentID); err != nil {
+		return nil, app.ErrNotFound
+	}
+	voter, err := uuid.Parse(voterID)
+	if err != nil {
+		return nil, app.ErrForbidden
+	}
+	comment, err := s.db.GetComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Status != app.StatusActive {
+		return nil, app.ErrNotFound
+	}
+	// голоса идут в уровень доверия автора, за себя голосовать нельзя
+	if comment.AuthorID != nil && *comment.AuthorID == voter {
+		return nil, app.ErrForbidden
+	}
+
+	score, err := s.db.SaveVote(app.Vote{CommentID: comment.ID, VoterID: voter, Value: value})
+	if err != nil {
+		return nil, err
+	}
+	return &app.CommentScore{CommentID: comment.ID, Score: score, Vote: value}, nil
+}
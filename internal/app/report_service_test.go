@@ -0,0 +1,157 @@
+package app
+
+import (
+	domain "commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type MockReportDb struct {
+	mock.Mock
+}
+
+func (m *MockReportDb) GetComment(id string) (*domain.Comment, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.Comment), args.Error(1)
+}
+
+func (m *MockReportDb) SaveReport(report *domain.Report, weights domain.ReportWeights, minLevel domain.TrustLevel) (int, error) {
+	args := m.Called(report, weights, minLevel)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockReportDb) GetReportQueue(weights domain.ReportWeights, page, pageSize int) ([]domain.ReportSummary, error) {
+	args := m.Called(weights, page, pageSize)
+	return args.Get(0).([]domain.ReportSummary), args.Error(1)
+}
+
+func (m *MockReportDb) ModerateComments(ids []uuid.UUID, action domain.ModerationAction, reason string) ([]domain.Comment, error) {
+	args := m.Called(ids, action, reason)
+	return args.Get(0).([]domain.Comment), args.Error(1)
+}
+
+func (m *MockReportDb) SaveVote(vote domain.Vote) (int, error) {
+	args := m.Called(vote)
+	return args.Int(0), args.Error(1)
+}
+
+func newReportService(mockDb *MockReportDb, threshold int) (*ReportService, *CommentService, *MockDb) {
+	commentsDb := new(MockDb)
+	comments := NewCommentService(commentsDb, &config.AppConfig{})
+	cfg := &config.AppConfig{}
+	cfg.ReportsConfig.HideThreshold = threshold
+	return NewReportService(mockDb, comments, cfg), comments, commentsDb
+}
+
+func TestReportService_Report(t *testing.T) {
+	mockDb := new(MockReportDb)
+	service, _, _ := newReportService(mockDb, 10)
+
+	comment := &domain.Comment{ID: uuid.New(), Status: domain.StatusActive}
+	reporter := uuid.New()
+	mockDb.On("GetComment", comment.ID.String()).Return(comment, nil)
+	mockDb.On("SaveReport", mock.MatchedBy(func(r *domain.Report) bool {
+		return r.CommentID == comment.ID && r.ReporterID == reporter && r.Reason == domain.ReportSpam
+	}), domain.ReportWeights{domain.ReportSpam: 2, domain.ReportAbuse: 3, domain.ReportOffTopic: 1}, domain.TrustLevel(0)).Return(2, nil)
+
+	report, err := service.Report(comment.ID.String(), reporter.String(), "spam")
+	assert.NoError(t, err)
+	assert.Equal(t, reporter, report.ReporterID)
+	mockDb.AssertNotCalled(t, "ModerateComments", mock.Anything, mock.Anything, mock.Anything)
+	mockDb.AssertExpectations(t)
+}
+
+func TestReportService_Report_AutoHide(t *testing.T) {
+	mockDb := new(MockReportDb)
+	service, comments, commentsDb := newReportService(mockDb, 5)
+
+	comment := &domain.Comment{ID: uuid.New(), Status: domain.StatusActive}
+	mockDb.On("GetComment", comment.ID.String()).Return(comment, nil)
+	mockDb.On("SaveReport", mock.Anything, mock.Anything, mock.Anything).Return(6, nil)
+	hidden := *comment
+	hidden.Status = domain.StatusHidden
+	mockDb.On("ModerateComments", []uuid.UUID{comment.ID}, domain.ModerationHide, "auto-hidden: report score 6").
+		Return([]domain.Comment{hidden}, nil)
+	commentsDb.On("GetAncestorIDs", comment.ID.String()).Return([]uuid.UUID{}, nil)
+
+	received := make(chan domain.CommentEvent, 1)
+	comments.Subscribe(func(event domain.CommentEvent) {
+		received <- event
+	})
+
+	_, err := service.Report(comment.ID.String(), uuid.New().String(), "abuse")
+	assert.NoError(t, err)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, domain.EventCommentDeleted, event.Type)
+	case <-time.After(time.Second):
+		t.Fatal("event was not published")
+	}
+	mockDb.AssertExpectations(t)
+}
+
+func TestReportService_Report_Invalid(t *testing.T) {
+	mockDb := new(MockReportDb)
+	service, _, _ := newReportService(mockDb, 5)
+
+	var verr *domain.ValidationError
+	_, err := service.Report(uuid.New().String(), uuid.New().String(), "boring")
+	assert.ErrorAs(t, err, &verr)
+
+	pending := &domain.Comment{ID: uuid.New(), Status: domain.StatusPending}
+	mockDb.On("GetComment", pending.ID.String()).Return(pending, nil)
+	_, err = service.Report(pending.ID.String(), uuid.New().String(), "spam")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	mockDb.AssertNotCalled(t, "SaveReport", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReportService_Report_CountsOnlyTrustedReporters(t *testing.T) {
+	mockDb := new(MockReportDb)
+	commentsDb := new(MockDb)
+	cfg := &config.AppConfig{}
+	cfg.TrustConfig.Levels = []config.TrustLevelConfig{{}, {MinAge: 24 * time.Hour}}
+	cfg.ReportsConfig.HideThreshold = 5
+	cfg.ReportsConfig.MinReporterLevel = 1
+	service := NewReportService(mockDb, NewCommentService(commentsDb, cfg), cfg)
+
+	comment := &domain.Comment{ID: uuid.New(), Status: domain.StatusActive}
+	newbie := uuid.New()
+	mockDb.On("GetComment", comment.ID.String()).Return(comment, nil)
+	commentsDb.On("GetTrustSignals", newbie.String()).Return(&domain.TrustSignals{UserID: newbie, MemberSince: time.Now()}, nil)
+	// сумма считается в базе только по жалобам уровня 1 и выше, жалоба новичка ее не увеличивает
+	mockDb.On("SaveReport", mock.MatchedBy(func(r *domain.Report) bool {
+		return r.ReporterLevel == 0
+	}), mock.Anything, domain.TrustLevel(1)).Return(3, nil)
+
+	_, err := service.Report(comment.ID.String(), newbie.String(), "abuse")
+	assert.NoError(t, err)
+	mockDb.AssertNotCalled(t, "ModerateComments", mock.Anything, mock.Anything, mock.Anything)
+	mockDb.AssertExpectations(t)
+}
+
+func TestReportService_Vote(t *testing.T) {
+	mockDb := new(MockReportDb)
+	service, _, _ := newReportService(mockDb, 0)
+
+	author, voter := uuid.New(), uuid.New()
+	comment := &domain.Comment{ID: uuid.New(), Status: domain.StatusActive, AuthorID: &author}
+	mockDb.On("GetComment", comment.ID.String()).Return(comment, nil)
+	mockDb.On("SaveVote", domain.Vote{CommentID: comment.ID, VoterID: voter, Value: -1}).Return(-1, nil)
+
+	score, err := service.Vote(comment.ID.String(), voter.String(), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.CommentScore{CommentID: comment.ID, Score: -1, Vote: -1}, *score)
+
+	_, err = service.Vote(comment.ID.String(), author.String(), 1)
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+
+	var verr *domain.ValidationError
+	_, err = service.Vote(comment.ID.String(), voter.String(), 5)
+	assert.ErrorAs(t, err, &verr)
+	mockDb.AssertNumberOfCalls(t, "SaveVote", 1)
+}
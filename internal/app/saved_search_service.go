@@ -0,0 +1,176 @@
+package app
+
+import (
+	"bytes"
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"net/http"
+	"time"
+)
+
+type SavedSearchService struct {
+	db     SavedSearchDbProvider
+	client *http.Client
+}
+
+type SavedSearchDbProvider interface {
+	SaveSavedSearch(search *app.SavedSearch) error
+	UpdateSavedSearch(search *app.SavedSearch) error
+	DeleteSavedSearch(id string) error
+	GetSavedSearch(id string) (*app.SavedSearch, error)
+	GetSavedSearches() ([]app.SavedSearch, error)
+	SaveAlert(alert *app.Alert) error
+	GetAlerts(searchID string, page, pageSize int) ([]app.Alert, error)
+}
+
+// AlertWebhookPayload — тело запроса, отправляемого на webhook сохраненного поиска
+type AlertWebhookPayload struct {
+	Alert   app.Alert   `json:"alert"`
+	Comment app.Comment `json:"comment"`
+}
+
+func NewSavedSearchService(db SavedSearchDbProvider, cfg *config.AppConfig) *SavedSearchService {
+	timeout := cfg.AlertsConfig.WebhookTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &SavedSearchService{
+		db:     db,
+		client: newWebhookClient(timeout),
+	}
+}
+
+func (s *SavedSearchService) CreateSavedSearch(query, parentID, webhookURL string) (*app.SavedSearch, error) {
+	search, err := app.NewSavedSearch(query, parentID, webhookURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWebhook(search.WebhookURL); err != nil {
+		return nil, err
+	}
+	if err := s.db.SaveSavedSearch(search); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+func (s *SavedSearchService) UpdateSavedSearch(id, query, parentID, webhookURL string) (*app.SavedSearch, error) {
+	existing, err := s.GetSavedSearch(id)
+	if err != nil {
+		return nil, err
+	}
+	search, err := app.NewSavedSearch(query, parentID, webhookURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWebhook(search.WebhookURL); err != nil {
+		return nil, err
+	}
+	search.ID = existing.ID
+	search.CreatedAt = existing.CreatedAt
+	if err := s.db.UpdateSavedSearch(search); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+// checkWebhook отклоняет webhook алертов, чье имя резолвится во внутреннюю сеть
+func (s *SavedSearchService) checkWebhook(webhookURL string) error {
+	if webhookURL == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.client.Timeout)
+	defer cancel()
+	if err := checkWebhookTarget(ctx, webhookURL); err != nil {
+		wbzlog.Logger.Error().Err(err).Str("url", webhookURL).Msg("rejected alert webhook target")
+		return err
+	}
+	return nil
+}
+
+func (s *SavedSearchService) DeleteSavedSearch(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("invalid saved search id")
+		return err
+	}
+	return s.db.DeleteSavedSearch(id)
+}
+
+func (s *SavedSearchService) GetSavedSearch(id string) (*app.SavedSearch, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("invalid saved search id")
+		return nil, err
+	}
+	return s.db.GetSavedSearch(id)
+}
+
+func (s *SavedSearchService) GetSavedSearches() ([]app.SavedSearch, error) {
+	return s.db.GetSavedSearches()
+}
+
+func (s *SavedSearchService) GetAlerts(searchID string, page, pageSize int) ([]app.Alert, error) {
+	if searchID != "" {
+		if _, err := uuid.Parse(searchID); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("invalid saved search id")
+			return nil, err
+		}
+	}
+	return s.db.GetAlerts(searchID, page, pageSize)
+}
+
+// HandleEvent прогоняет новый комментарий через все сохраненные поиски,
+// записывает алерты и отправляет webhook, если он задан.
+func (s *SavedSearchService) HandleEvent(event app.CommentEvent) {
+	if event.Type != app.EventCommentCreated || event.Comment == nil {
+		return
+	}
+	comment := event.Comment
+
+	searches, err := s.db.GetSavedSearches()
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("failed to load saved searches")
+		return
+	}
+
+	for i := range searches {
+		search := &searches[i]
+		if !search.Matches(comment, event.Path) {
+			continue
+		}
+		alert := app.NewAlert(search, comment)
+		if err := s.db.SaveAlert(alert); err != nil {
+			wbzlog.Logger.Error().Err(err).Str("search_id", search.ID.String()).Str("comment_id", comment.ID.String()).Msg("failed to save alert")
+			continue
+		}
+		if search.WebhookURL != "" {
+			if err := s.sendWebhook(search.WebhookURL, alert, comment); err != nil {
+				wbzlog.Logger.Error().Err(err).Str("url", search.WebhookURL).Msg("failed to send alert webhook")
+			}
+		}
+	}
+}
+
+func (s *SavedSearchService) sendWebhook(url string, alert *app.Alert, comment *app.Comment) error {
+	body, err := json.Marshal(AlertWebhookPayload{Alert: *alert, Comment: *comment})
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("failed to close webhook response body")
+		}
+	}()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
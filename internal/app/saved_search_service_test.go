@@ -0,0 +1,101 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MockSavedSearchDb struct {
+	mock.Mock
+}
+
+func (m *MockSavedSearchDb) SaveSavedSearch(search *app.SavedSearch) error {
+	args := m.Called(search)
+	return args.Error(0)
+}
+
+func (m *MockSavedSearchDb) UpdateSavedSearch(search *app.SavedSearch) error {
+	args := m.Called(search)
+	return args.Error(0)
+}
+
+func (m *MockSavedSearchDb) DeleteSavedSearch(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockSavedSearchDb) GetSavedSearch(id string) (*app.SavedSearch, error) {
+	args := m.Called(id)
+	return args.Get(0).(*app.SavedSearch), args.Error(1)
+}
+
+func (m *MockSavedSearchDb) GetSavedSearches() ([]app.SavedSearch, error) {
+	args := m.Called()
+	return args.Get(0).([]app.SavedSearch), args.Error(1)
+}
+
+func (m *MockSavedSearchDb) SaveAlert(alert *app.Alert) error {
+	args := m.Called(alert)
+	return args.Error(0)
+}
+
+func (m *MockSavedSearchDb) GetAlerts(searchID string, page, pageSize int) ([]app.Alert, error) {
+	args := m.Called(searchID, page, pageSize)
+	return args.Get(0).([]app.Alert), args.Error(1)
+}
+
+func TestSavedSearchService_CreateSavedSearch(t *testing.T) {
+	mockDb := new(MockSavedSearchDb)
+	service := NewSavedSearchService(mockDb, &config.AppConfig{})
+
+	mockDb.On("SaveSavedSearch", mock.AnythingOfType("*app.SavedSearch")).Return(nil)
+
+	search, err := service.CreateSavedSearch("spam", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "spam", search.Query)
+	mockDb.AssertExpectations(t)
+}
+
+func TestSavedSearchService_EvaluateComment(t *testing.T) {
+	var received AlertWebhookPayload
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	mockDb := new(MockSavedSearchDb)
+	service := NewSavedSearchService(mockDb, &config.AppConfig{})
+	// тестовый сервер слушает loopback, закрытый для настоящих алертов
+	service.client = &http.Client{Timeout: time.Second}
+
+	rootID := uuid.New()
+	comment := &app.Comment{ID: uuid.New(), Text: "cheap watches here", ParentID: &rootID}
+	withHook, _ := app.NewSavedSearch("cheap", rootID.String(), "")
+	withHook.WebhookURL = server.URL
+	noMatch, _ := app.NewSavedSearch("nothing", "", "")
+
+	mockDb.On("GetSavedSearches").Return([]app.SavedSearch{*withHook, *noMatch}, nil)
+	mockDb.On("SaveAlert", mock.MatchedBy(func(a *app.Alert) bool {
+		return a.SearchID == withHook.ID && a.CommentID == comment.ID
+	})).Return(nil).Once()
+
+	event := app.NewCommentEvent(app.EventCommentCreated, comment.ID, comment)
+	event.Path = []uuid.UUID{rootID, comment.ID}
+	service.HandleEvent(event)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, comment.ID, received.Comment.ID)
+	assert.Equal(t, withHook.ID, received.Alert.SearchID)
+	mockDb.AssertExpectations(t)
+}
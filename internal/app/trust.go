@@ -0,0 +1,78 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"github.com/google/uuid"
+	"time"
+)
+
+func newTrustPolicy(cfg *config.AppConfig) app.TrustPolicy {
+	policy := make(app.TrustPolicy, 0, len(cfg.TrustConfig.Levels))
+	for _, l := range cfg.TrustConfig.Levels {
+		policy = append(policy, app.TrustRule{
+			MinAge:           l.MinAge,
+			MinApproved:      l.MinApproved,
+			MaxUpheldReports: l.MaxUpheldReports,
+			MinVotes:         l.MinVotes,
+			Privileges: app.TrustPrivileges{
+				Premoderate: l.Premoderate,
+				MaxLinks:    l.MaxLinks,
+				MaxPerHour:  l.MaxPerHour,
+			},
+		})
+	}
+	return policy
+}
+
+// Trust возвращает текущий уровень доверия пользователя
+func (s *CommentService) Trust(userID string) (*app.Trust, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, app.ErrNotFound
+	}
+	signals, err := s.db.GetTrustSignals(userID)
+	if err != nil {
+		return nil, err
+	}
+	trust := s.trust.Evaluate(*signals, time.Now())
+	return &trust, nil
+}
+
+// authorTrust — уровень автора нового комментария; аноним всегда на уровне 0
+func (s *CommentService) authorTrust(authorID string) (app.Trust, error) {
+	if len(s.trust) == 0 {
+		return app.Trust{}, nil
+	}
+	if authorID == "" {
+		return app.Trust{Privileges: s.trust[0].Privileges}, nil
+	}
+	trust, err := s.Trust(authorID)
+	if err != nil {
+		return app.Trust{}, err
+	}
+	return *trust, nil
+}
+
+// contentPolicy ужесточает ограничение на ссылки под уровень автора
+func (s *CommentService) contentPolicy(trust app.Trust) app.ContentPolicy {
+	policy := s.policy
+	if max := trust.Privileges.MaxLinks; max > 0 && (policy.MaxLinks == 0 || max < policy.MaxLinks) {
+		policy.MaxLinks = max
+	}
+	return policy
+}
+
+// checkRate не дает автору публиковать чаще, чем разрешено его уровнем
+func (s *CommentService) checkRate(authorID string, trust app.Trust) error {
+	if authorID == "" || trust.Privileges.MaxPerHour <= 0 {
+		return nil
+	}
+	n, err := s.db.CountRecentComments(authorID, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if n >= trust.Privileges.MaxPerHour {
+		return app.ErrRateLimited
+	}
+	return nil
+}
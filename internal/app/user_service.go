@@ -0,0 +1,128 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"errors"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+type UserService struct {
+	db     UserDbProvider
+	secret string
+}
+
+type UserDbProvider interface {
+	SaveUser(u *app.User) error
+	GetUser(id string) (*app.User, error)
+	GetUserByTokenHash(hash string) (*app.User, error)
+	UpdateNotifyMode(id, mode string) error
+	GetUserMentions(userID string, page, pageSize int) ([]app.Comment, error)
+	SaveMute(mute *app.Mute) error
+	DeleteMute(userID, mutedID string) error
+	GetMutes(userID string) ([]app.Mute, error)
+}
+
+func NewUserService(db UserDbProvider, cfg *config.AppConfig) *UserService {
+	return &UserService{
+		db:     db,
+		secret: cfg.NotifyConfig.UnsubscribeSecret,
+	}
+}
+
+// CreateUser регистрирует пользователя и возвращает его токен доступа — он выдается только здесь
+func (s *UserService) CreateUser(name, email string) (*app.User, string, error) {
+	user, err := app.NewUser(name, email)
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := app.NewUserToken()
+	if err != nil {
+		return nil, "", err
+	}
+	user.TokenHash = app.HashUserToken(token)
+	if err := s.db.SaveUser(user); err != nil {
+		return nil, "", err
+	}
+	return user, token, nil
+}
+
+// Authenticate находит пользователя по токену доступа; неизвестный токен — ErrUnauthorized
+func (s *UserService) Authenticate(token string) (*app.User, error) {
+	if token == "" {
+		return nil, app.ErrUnauthorized
+	}
+	user, err := s.db.GetUserByTokenHash(app.HashUserToken(token))
+	if errors.Is(err, app.ErrNotFound) {
+		return nil, app.ErrUnauthorized
+	}
+	return user, err
+}
+
+func (s *UserService) GetUser(id string) (*app.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, app.ErrNotFound
+	}
+	return s.db.GetUser(id)
+}
+
+func (s *UserService) UpdateNotifyMode(id, mode string) error {
+	if err := app.ValidNotifyMode(mode); err != nil {
+		return err
+	}
+	return s.db.UpdateNotifyMode(id, mode)
+}
+
+// GetMentions возвращает комментарии, в которых упомянут пользователь
+func (s *UserService) GetMentions(id string, page, pageSize int) ([]app.Comment, error) {
+	if _, err := s.GetUser(id); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserMentions(id, page, pageSize)
+}
+
+// Unsubscribe отключает уведомления по токену из письма
+func (s *UserService) Unsubscribe(token string) error {
+	if s.secret == "" {
+		return app.ErrInvalidUnsubscribe
+	}
+	userID, err := app.ParseUnsubscribeToken(s.secret, token)
+	if err != nil {
+		wbzlog.Logger.Warn().Err(err).Msg("rejected unsubscribe token")
+		return err
+	}
+	return s.db.UpdateNotifyMode(userID.String(), app.NotifyOff)
+}
+
+// Mute сворачивает для userID комментарии автора mutedID
+func (s *UserService) Mute(userID, mutedID string) (*app.Mute, error) {
+	user, err := s.GetUser(mutedID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID.String() == userID {
+		return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "user_id", Code: "invalid", Message: "cannot mute yourself"}}}
+	}
+	mute := &app.Mute{UserID: uuid.MustParse(userID), MutedID: user.ID, CreatedAt: time.Now()}
+	if err := s.db.SaveMute(mute); err != nil {
+		return nil, err
+	}
+	return mute, nil
+}
+
+func (s *UserService) Unmute(userID, mutedID string) error {
+	if _, err := uuid.Parse(mutedID); err != nil {
+		return app.ErrNotFound
+	}
+	return s.db.DeleteMute(userID, mutedID)
+}
+
+// GetMutes возвращает авторов, скрытых пользователем
+func (s *UserService) GetMutes(userID string) ([]app.Mute, error) {
+	if _, err := s.GetUser(userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetMutes(userID)
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// newWebhookClient — HTTP-клиент исходящих вебхуков. Адрес проверяется на каждом соединении уже после резолва,
+// поэтому внутреннюю сеть не достать ни DNS-записью, ни редиректом. Прокси из окружения не используется.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !app.PublicAddress(ip) {
+				return app.ErrWebhookTarget
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// checkWebhookTarget резолвит хост URL и отклоняет его, если хотя бы один адрес внутренний
+func checkWebhookTarget(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if !app.PublicHost(u.Hostname()) {
+		return app.ErrWebhookTarget
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("resolve webhook host: %w", err)
+	}
+	for _, addr := range addrs {
+		if !app.PublicAddress(addr.IP) {
+			return app.ErrWebhookTarget
+		}
+	}
+	return nil
+}
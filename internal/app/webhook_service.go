@@ -0,0 +1,289 @@
+package app
+
+import (
+	"bytes"
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"commentTree/internal/jobs"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"net/http"
+	"time"
+)
+
+// WebhookDeliverKind — задача отправки одной доставки вебхука
+const WebhookDeliverKind = "webhooks.deliver"
+
+// WebhookService ставит события в доставку подписчикам. Отправка идет через очередь фоновых задач,
+// поэтому доставка переживает перезапуск и не теряется при остановке.
+type WebhookService struct {
+	db       WebhookDbProvider
+	queue    JobQueue
+	client   *http.Client
+	attempts int
+}
+
+type webhookDeliveryPayload struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+type WebhookDbProvider interface {
+	SaveWebhookSubscription(sub *app.WebhookSubscription) error
+	DeleteWebhookSubscription(id string) error
+	GetWebhookSubscription(id string) (*app.WebhookSubscription, error)
+	GetWebhookSubscriptions() ([]app.WebhookSubscription, error)
+	SaveWebhookDelivery(d *app.WebhookDelivery) error
+	GetWebhookDelivery(id string) (*app.WebhookDelivery, error)
+	GetWebhookDeliveries(subscriptionID string, page, pageSize int) ([]app.WebhookDelivery, error)
+}
+
+func NewWebhookService(db WebhookDbProvider, queue JobQueue, cfg *config.AppConfig) *WebhookService {
+	whCfg := cfg.WebhooksConfig
+	timeout := whCfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	attempts := whCfg.Attempts
+	if attempts <= 0 {
+		attempts = 5
+	}
+	return &WebhookService{
+		db:       db,
+		queue:    queue,
+		client:   newWebhookClient(timeout),
+		attempts: attempts,
+	}
+}
+
+func (s *WebhookService) CreateSubscription(url, secret string, eventTypes []string, subjectID string) (*app.WebhookSubscription, error) {
+	sub, err := app.NewWebhookSubscription(url, secret, eventTypes, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.client.Timeout)
+	defer cancel()
+	if err := checkWebhookTarget(ctx, sub.URL); err != nil {
+		wbzlog.Logger.Error().Err(err).Str("url", sub.URL).Msg("rejected webhook target")
+		return nil, err
+	}
+	if err := s.db.SaveWebhookSubscription(sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *WebhookService) DeleteSubscription(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("invalid webhook subscription id")
+		return err
+	}
+	return s.db.DeleteWebhookSubscription(id)
+}
+
+func (s *WebhookService) GetSubscription(id string) (*app.WebhookSubscription, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("invalid webhook subscription id")
+		return nil, err
+	}
+	return s.db.GetWebhookSubscription(id)
+}
+
+func (s *WebhookService) GetSubscriptions() ([]app.WebhookSubscription, error) {
+	return s.db.GetWebhookSubscriptions()
+}
+
+func (s *WebhookService) GetDeliveries(subscriptionID string, page, pageSize int) ([]app.WebhookDelivery, error) {
+	if _, err := uuid.Parse(subscriptionID); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("invalid webhook subscription id")
+		return nil, err
+	}
+	return s.db.GetWebhookDeliveries(subscriptionID, page, pageSize)
+}
+
+// HandleEvent ставит в журнал доставку для каждой подходящей подписки и задачу на ее отправку.
+// ID доставки выводится из ID события и подписки, поэтому повторно полученное событие не отправляется дважды.
+// Ошибка возвращается, только если доставку или задачу не удалось сохранить: outbox повторит событие.
+func (s *WebhookService) HandleEvent(event app.CommentEvent) error {
+	subs, err := s.db.GetWebhookSubscriptions()
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("failed to load webhook subscriptions")
+		return err
+	}
+	for i := range subs {
+		sub := &subs[i]
+		if !sub.Matches(event) {
+			continue
+		}
+		deliveryID := uuid.NewSHA1(event.ID, sub.ID[:])
+		delivery, err := s.db.GetWebhookDelivery(deliveryID.String())
+		if errors.Is(err, app.ErrNotFound) {
+			delivery, err = s.newDelivery(deliveryID, sub, event)
+			if err != nil {
+				wbzlog.Logger.Error().Err(err).Msg("failed to save webhook delivery")
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+		if delivery.Status != app.DeliveryPending {
+			continue // событие уже доставлено этой подписке
+		}
+		// задача могла не сохраниться в прошлый раз; UniqueKey не даст поставить ее дважды
+		_, err = s.queue.Enqueue(context.Background(), WebhookDeliverKind, webhookDeliveryPayload{DeliveryID: deliveryID.String()},
+			jobs.UniqueKey("webhook:"+deliveryID.String()), jobs.MaxAttempts(s.attempts))
+		if err != nil {
+			wbzlog.Logger.Error().Err(err).Str("delivery", deliveryID.String()).Msg("failed to enqueue webhook delivery")
+			return err
+		}
+	}
+	return nil
+}
+
+// JobHandlers — обработчик задач отправки для fx-группы job_handlers
+func (s *WebhookService) JobHandlers() []jobs.Handler {
+	return []jobs.Handler{jobs.NewHandler(WebhookDeliverKind, s.handleDelivery)}
+}
+
+// handleDelivery делает одну попытку отправки; повторы с задержкой выполняет очередь задач.
+// После webhooks.attempts неудач доставка помечается failed, ее можно повторить через Replay.
+func (s *WebhookService) handleDelivery(ctx context.Context, payload webhookDeliveryPayload) error {
+	delivery, err := s.db.GetWebhookDelivery(payload.DeliveryID)
+	if errors.Is(err, app.ErrNotFound) {
+		return nil // подписку удалили вместе с журналом
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status != app.DeliveryPending {
+		return nil
+	}
+	sub, err := s.db.GetWebhookSubscription(delivery.SubscriptionID.String())
+	if errors.Is(err, app.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	delivery.Attempts++
+	code, sendErr := s.send(sub, delivery)
+	delivery.ResponseCode = code
+	if sendErr == nil {
+		now := time.Now()
+		delivery.Status = app.DeliveryDelivered
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+	} else {
+		delivery.Error = sendErr.Error()
+		if delivery.Attempts >= s.attempts {
+			delivery.Status = app.DeliveryFailed
+			wbzlog.Logger.Error().Err(sendErr).Str("url", sub.URL).Str("delivery", delivery.ID.String()).Msg("webhook delivery failed")
+		}
+	}
+	if err := s.db.SaveWebhookDelivery(delivery); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("failed to update webhook delivery log")
+		return err
+	}
+	if delivery.Status == app.DeliveryPending {
+		return sendErr
+	}
+	return nil
+}
+
+// Replay ставит доставку из журнала в очередь заново с тем же телом и подписью и сбрасывает счетчик попыток.
+// Доставка, которая еще ждет отправки, второй раз в очередь не ставится.
+func (s *WebhookService) Replay(subscriptionID, deliveryID string) (*app.WebhookDelivery, error) {
+	sub, err := s.GetSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(deliveryID); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("invalid webhook delivery id")
+		return nil, err
+	}
+	delivery, err := s.db.GetWebhookDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.SubscriptionID != sub.ID {
+		return nil, app.ErrNotFound
+	}
+	if delivery.Status == app.DeliveryPending {
+		return delivery, nil
+	}
+
+	previous := *delivery
+	delivery.Status = app.DeliveryPending
+	delivery.Attempts = 0
+	delivery.Error = ""
+	delivery.DeliveredAt = nil
+	if err := s.db.SaveWebhookDelivery(delivery); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("failed to update webhook delivery log")
+		return nil, err
+	}
+	_, err = s.queue.Enqueue(context.Background(), WebhookDeliverKind, webhookDeliveryPayload{DeliveryID: deliveryID},
+		jobs.MaxAttempts(s.attempts))
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Str("delivery", deliveryID).Msg("failed to enqueue webhook replay")
+		// без задачи доставка осталась бы pending навсегда, а повторный Replay ее пропустил бы
+		if err := s.db.SaveWebhookDelivery(&previous); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("failed to restore webhook delivery log")
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (s *WebhookService) newDelivery(id uuid.UUID, sub *app.WebhookSubscription, event app.CommentEvent) (*app.WebhookDelivery, error) {
+	delivery := &app.WebhookDelivery{
+		ID:             id,
+		SubscriptionID: sub.ID,
+		EventType:      event.Type,
+		Status:         app.DeliveryPending,
+		CreatedAt:      time.Now(),
+	}
+	body, err := json.Marshal(app.WebhookPayload{
+		DeliveryID: delivery.ID,
+		Type:       event.Type,
+		OccurredAt: event.OccurredAt,
+		Data:       event,
+	})
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = string(body)
+	if err := s.db.SaveWebhookDelivery(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (s *WebhookService) send(sub *app.WebhookSubscription, delivery *app.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", string(delivery.EventType))
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.String())
+	req.Header.Set("X-Webhook-Signature", app.SignPayload(sub.Secret, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("failed to close webhook response body")
+		}
+	}()
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
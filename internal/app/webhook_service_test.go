@@ -0,0 +1,308 @@
+package app
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"commentTree/internal/jobs"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type MockWebhookDb struct {
+	mock.Mock
+}
+
+func (m *MockWebhookDb) SaveWebhookSubscription(sub *app.WebhookSubscription) error {
+	args := m.Called(sub)
+	return args.Error(0)
+}
+
+func (m *MockWebhookDb) DeleteWebhookSubscription(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookDb) GetWebhookSubscription(id string) (*app.WebhookSubscription, error) {
+	args := m.Called(id)
+	return args.Get(0).(*app.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookDb) GetWebhookSubscriptions() ([]app.WebhookSubscription, error) {
+	args := m.Called()
+	return args.Get(0).([]app.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookDb) SaveWebhookDelivery(d *app.WebhookDelivery) error {
+	args := m.Called(d)
+	return args.Error(0)
+}
+
+func (m *MockWebhookDb) GetWebhookDelivery(id string) (*app.WebhookDelivery, error) {
+	args := m.Called(id)
+	return args.Get(0).(*app.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookDb) GetWebhookDeliveries(subscriptionID string, page, pageSize int) ([]app.WebhookDelivery, error) {
+	args := m.Called(subscriptionID, page, pageSize)
+	return args.Get(0).([]app.WebhookDelivery), args.Error(1)
+}
+
+// jobRecorder — очередь задач в памяти: run выполняет поставленные задачи, как пул, пока они не перестанут падать
+type jobRecorder struct {
+	payloads []any
+}
+
+func (q *jobRecorder) Enqueue(_ context.Context, kind string, payload any, _ ...jobs.Option) (*app.Job, error) {
+	q.payloads = append(q.payloads, payload)
+	return &app.Job{Kind: kind}, nil
+}
+
+func (q *jobRecorder) run(t *testing.T, service *WebhookService, maxRuns int) {
+	handler := service.JobHandlers()[0]
+	for _, payload := range q.payloads {
+		raw, _ := json.Marshal(payload)
+		for i := 0; i < maxRuns; i++ {
+			if err := handler.Handle(context.Background(), raw); err == nil {
+				break
+			}
+		}
+	}
+	q.payloads = nil
+}
+
+func newTestWebhookService(db WebhookDbProvider, queue JobQueue) *WebhookService {
+	cfg := &config.AppConfig{}
+	cfg.WebhooksConfig.Attempts = 3
+	service := NewWebhookService(db, queue, cfg)
+	// тестовые серверы слушают loopback, который закрыт для настоящих доставок
+	service.client = &http.Client{Timeout: time.Second}
+	return service
+}
+
+// testSubscription — подписка на адрес тестового сервера в обход проверки NewWebhookSubscription
+func testSubscription(url string, events ...app.EventType) *app.WebhookSubscription {
+	return &app.WebhookSubscription{ID: uuid.New(), URL: url, Secret: "s3cret", Events: events, CreatedAt: time.Now()}
+}
+
+// memoryWebhookDb — подписки и журнал доставок в памяти
+type memoryWebhookDb struct {
+	WebhookDbProvider
+	subs       []app.WebhookSubscription
+	deliveries map[uuid.UUID]app.WebhookDelivery
+	saves      int
+}
+
+func newMemoryWebhookDb(subs ...app.WebhookSubscription) *memoryWebhookDb {
+	return &memoryWebhookDb{subs: subs, deliveries: map[uuid.UUID]app.WebhookDelivery{}}
+}
+
+func (m *memoryWebhookDb) GetWebhookSubscriptions() ([]app.WebhookSubscription, error) {
+	return m.subs, nil
+}
+
+func (m *memoryWebhookDb) GetWebhookSubscription(id string) (*app.WebhookSubscription, error) {
+	for i := range m.subs {
+		if m.subs[i].ID.String() == id {
+			sub := m.subs[i]
+			return &sub, nil
+		}
+	}
+	return nil, app.ErrNotFound
+}
+
+func (m *memoryWebhookDb) SaveWebhookDelivery(d *app.WebhookDelivery) error {
+	m.saves++
+	m.deliveries[d.ID] = *d
+	return nil
+}
+
+func (m *memoryWebhookDb) GetWebhookDelivery(id string) (*app.WebhookDelivery, error) {
+	d, ok := m.deliveries[uuid.MustParse(id)]
+	if !ok {
+		return nil, app.ErrNotFound
+	}
+	return &d, nil
+}
+
+// only возвращает единственную доставку журнала
+func (m *memoryWebhookDb) only(t *testing.T) app.WebhookDelivery {
+	t.Helper()
+	if !assert.Len(t, m.deliveries, 1) {
+		t.FailNow()
+	}
+	for _, d := range m.deliveries {
+		return d
+	}
+	return app.WebhookDelivery{}
+}
+
+func TestWebhookService_HandleEvent_SignsAndDelivers(t *testing.T) {
+	var signature, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		body = string(raw)
+		signature = r.Header.Get("X-Webhook-Signature")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sub := testSubscription(server.URL, app.EventCommentCreated)
+	db := newMemoryWebhookDb(*sub)
+	queue := &jobRecorder{}
+	service := newTestWebhookService(db, queue)
+
+	comment := &app.Comment{ID: uuid.New(), Text: "hello"}
+	assert.NoError(t, service.HandleEvent(app.NewCommentEvent(app.EventCommentCreated, comment.ID, comment)))
+	// удаление под фильтр подписки не попадает
+	assert.NoError(t, service.HandleEvent(app.NewCommentEvent(app.EventCommentDeleted, comment.ID, nil)))
+	assert.Len(t, queue.payloads, 1)
+	queue.run(t, service, 1)
+
+	last := db.only(t)
+	assert.Equal(t, app.SignPayload("s3cret", []byte(body)), signature)
+	assert.Equal(t, app.DeliveryDelivered, last.Status)
+	assert.Equal(t, 1, last.Attempts)
+	assert.Equal(t, http.StatusOK, last.ResponseCode)
+	assert.Equal(t, 2, db.saves)
+}
+
+func TestWebhookService_HandleEvent_RetriesFailedDelivery(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sub := testSubscription(server.URL)
+	db := newMemoryWebhookDb(*sub)
+	queue := &jobRecorder{}
+	service := newTestWebhookService(db, queue)
+
+	assert.NoError(t, service.HandleEvent(app.NewCommentEvent(app.EventCommentDeleted, uuid.New(), nil)))
+	queue.run(t, service, 5)
+
+	last := db.only(t)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, app.DeliveryDelivered, last.Status)
+	assert.Equal(t, 2, last.Attempts)
+	assert.Equal(t, http.StatusNoContent, last.ResponseCode)
+}
+
+func TestWebhookService_HandleEvent_FailsAfterAttempts(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	sub := testSubscription(server.URL)
+	db := newMemoryWebhookDb(*sub)
+	queue := &jobRecorder{}
+	service := newTestWebhookService(db, queue)
+
+	assert.NoError(t, service.HandleEvent(app.NewCommentEvent(app.EventCommentCreated, uuid.New(), nil)))
+	queue.run(t, service, 10)
+
+	last := db.only(t)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, app.DeliveryFailed, last.Status)
+	assert.Equal(t, http.StatusBadGateway, last.ResponseCode)
+}
+
+func TestWebhookService_HandleEvent_SkipsRedeliveredEvent(t *testing.T) {
+	sub := testSubscription("http://127.0.0.1:1/hook")
+	db := newMemoryWebhookDb(*sub)
+	queue := &jobRecorder{}
+	service := newTestWebhookService(db, queue)
+
+	event := app.NewCommentEvent(app.EventCommentCreated, uuid.New(), nil)
+	deliveryID := uuid.NewSHA1(event.ID, sub.ID[:])
+	db.deliveries[deliveryID] = app.WebhookDelivery{ID: deliveryID, Status: app.DeliveryDelivered}
+
+	assert.NoError(t, service.HandleEvent(event))
+	assert.Empty(t, queue.payloads)
+	assert.Equal(t, 0, db.saves)
+}
+
+func TestWebhookService_Replay(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sub := testSubscription(server.URL)
+	db := newMemoryWebhookDb(*sub)
+	delivery := app.WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: sub.ID,
+		EventType:      app.EventCommentCreated,
+		Payload:        `{"type":"comment.created"}`,
+		Status:         app.DeliveryFailed,
+		Attempts:       3,
+		Error:          "webhook responded with status 500",
+	}
+	db.deliveries[delivery.ID] = delivery
+	queue := &jobRecorder{}
+	service := newTestWebhookService(db, queue)
+
+	// Replay только ставит задачу: отправка идет в очереди, а не в запросе
+	replayed, err := service.Replay(sub.ID.String(), delivery.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, app.DeliveryPending, replayed.Status)
+	assert.Equal(t, 0, replayed.Attempts)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	assert.Len(t, queue.payloads, 1)
+
+	// доставка уже ждет отправки — вторая задача не нужна
+	_, err = service.Replay(sub.ID.String(), delivery.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, queue.payloads, 1)
+
+	queue.run(t, service, 1)
+	last := db.only(t)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, app.DeliveryDelivered, last.Status)
+	assert.Equal(t, 1, last.Attempts)
+	assert.Empty(t, last.Error)
+
+	other := app.WebhookDelivery{ID: uuid.New(), SubscriptionID: uuid.New(), Status: app.DeliveryFailed}
+	db.deliveries[other.ID] = other
+	_, err = service.Replay(sub.ID.String(), other.ID.String())
+	assert.ErrorIs(t, err, app.ErrNotFound)
+}
+
+func TestWebhookService_RefusesInternalTargets(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.AppConfig{}
+	service := NewWebhookService(new(MockWebhookDb), &jobRecorder{}, cfg)
+
+	_, err := service.CreateSubscription(server.URL, "s3cret", nil, "")
+	assert.ErrorIs(t, err, app.ErrWebhookTarget)
+
+	// подписка, сохраненная раньше или указывающая на имя с внутренним адресом, не доставляется
+	_, err = service.send(testSubscription(server.URL), &app.WebhookDelivery{ID: uuid.New(), Payload: "{}"})
+	assert.ErrorIs(t, err, app.ErrWebhookTarget)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
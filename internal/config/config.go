@@ -8,12 +8,29 @@ import (
 )
 
 type AppConfig struct {
-	ServerConfig ServerConfig `mapstructure:"server"`
-	LoggerConfig loggerConfig `mapstructure:"logger"`
-	RedisConfig  redisConfig  `mapstructure:"redis"`
-	DBConfig     dbConfig     `mapstructure:"db_config"`
-	RetrysConfig RetrysConfig `mapstructure:"retry_strategy"`
-	GinConfig    ginConfig    `mapstructure:"gin"`
+	ServerConfig      ServerConfig      `mapstructure:"server"`
+	LoggerConfig      loggerConfig      `mapstructure:"logger"`
+	RedisConfig       redisConfig       `mapstructure:"redis"`
+	DBConfig          dbConfig          `mapstructure:"db_config"`
+	RetrysConfig      RetrysConfig      `mapstructure:"retry_strategy"`
+	GinConfig         ginConfig         `mapstructure:"gin"`
+	AlertsConfig      alertsConfig      `mapstructure:"alerts"`
+	StreamConfig      streamConfig      `mapstructure:"stream"`
+	EventBusConfig    eventBusConfig    `mapstructure:"event_bus"`
+	LongPollConfig    longPollConfig    `mapstructure:"long_poll"`
+	WebhooksConfig    webhooksConfig    `mapstructure:"webhooks"`
+	OutboxConfig      outboxConfig      `mapstructure:"outbox"`
+	JobsConfig        jobsConfig        `mapstructure:"jobs"`
+	NotifyConfig      notifyConfig      `mapstructure:"notifications"`
+	ContentConfig     contentConfig     `mapstructure:"content"`
+	FiltersConfig     filtersConfig     `mapstructure:"filters"`
+	ModerationConfig  moderationConfig  `mapstructure:"moderation"`
+	ReportsConfig     reportsConfig     `mapstructure:"reports"`
+	TrustConfig       trustConfig       `mapstructure:"trust"`
+	BansConfig        bansConfig        `mapstructure:"bans"`
+	RateLimitConfig   rateLimitConfig   `mapstructure:"rate_limit"`
+	PowConfig         powConfig         `mapstructure:"proof_of_work"`
+	IdempotencyConfig idempotencyConfig `mapstructure:"idempotency"`
 }
 
 type RetrysConfig struct {
@@ -26,9 +43,156 @@ type ginConfig struct {
 	Mode string `mapstructure:"mode" default:"debug"`
 }
 
+type alertsConfig struct {
+	WebhookTimeout time.Duration `mapstructure:"webhook_timeout" default:"5s"`
+}
+
+type streamConfig struct {
+	BufferSize   int           `mapstructure:"buffer_size" default:"256"`
+	ClientBuffer int           `mapstructure:"client_buffer" default:"32"`
+	Heartbeat    time.Duration `mapstructure:"heartbeat" default:"15s"`
+}
+
+type eventBusConfig struct {
+	Driver       string        `mapstructure:"driver" default:"postgres"`
+	Channel      string        `mapstructure:"channel" default:"comment_events"`
+	MinReconnect time.Duration `mapstructure:"min_reconnect" default:"1s"`
+	MaxReconnect time.Duration `mapstructure:"max_reconnect" default:"1m"`
+	PingInterval time.Duration `mapstructure:"ping_interval" default:"90s"`
+}
+
+type longPollConfig struct {
+	Timeout time.Duration `mapstructure:"timeout" default:"30s"`
+}
+
+type webhooksConfig struct {
+	Timeout  time.Duration `mapstructure:"timeout" default:"5s"`
+	Attempts int           `mapstructure:"attempts" default:"5"`
+}
+
+type outboxConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval" default:"1s"`
+	BatchSize    int           `mapstructure:"batch_size" default:"100"`
+	MaxAttempts  int           `mapstructure:"max_attempts" default:"10"`
+	Sinks        []string      `mapstructure:"sinks"`
+}
+
+type jobsConfig struct {
+	Concurrency   int           `mapstructure:"concurrency" default:"4"`
+	PollInterval  time.Duration `mapstructure:"poll_interval" default:"1s"`
+	Lease         time.Duration `mapstructure:"lease" default:"5m"`
+	MaxAttempts   int           `mapstructure:"max_attempts" default:"5"`
+	BackoffBase   time.Duration `mapstructure:"backoff_base" default:"5s"`
+	BackoffMax    time.Duration `mapstructure:"backoff_max" default:"1h"`
+	Retention     time.Duration `mapstructure:"retention" default:"168h"`
+	PurgeSchedule string        `mapstructure:"purge_schedule" default:"0 3 * * *"`
+}
+
+type notifyConfig struct {
+	Driver            string     `mapstructure:"driver" default:"log"`
+	BaseURL           string     `mapstructure:"base_url"`
+	UnsubscribeSecret string     `mapstructure:"unsubscribe_secret"`
+	DigestSchedule    string     `mapstructure:"digest_schedule" default:"0 9 * * *"`
+	SMTP              smtpConfig `mapstructure:"smtp"`
+}
+
+type smtpConfig struct {
+	Host     string `mapstructure:"host" default:"localhost"`
+	Port     int    `mapstructure:"port" default:"1025"`
+	From     string `mapstructure:"from"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+type contentConfig struct {
+	MinLength int `mapstructure:"min_length" default:"1"`
+	MaxLength int `mapstructure:"max_length" default:"10000"`
+	MaxLines  int `mapstructure:"max_lines" default:"200"`
+	MaxLinks  int `mapstructure:"max_links" default:"10"`
+}
+
+type filtersConfig struct {
+	RejectWords     []string      `mapstructure:"reject_words"`
+	FlagWords       []string      `mapstructure:"flag_words"`
+	BlockedDomains  []string      `mapstructure:"blocked_domains"`
+	MaxRepeat       int           `mapstructure:"max_repeat" default:"10"`
+	DuplicateWindow time.Duration `mapstructure:"duplicate_window" default:"10m"`
+}
+
+type moderationConfig struct {
+	PremoderateAll bool `mapstructure:"premoderate_all" default:"false"`
+	HoldFlagged    bool `mapstructure:"hold_flagged" default:"false"`
+}
+
+type reportsConfig struct {
+	SpamWeight     int `mapstructure:"spam_weight" default:"2"`
+	AbuseWeight    int `mapstructure:"abuse_weight" default:"3"`
+	OffTopicWeight int `mapstructure:"off_topic_weight" default:"1"`
+	HideThreshold  int `mapstructure:"hide_threshold" default:"10"`
+	// MinReporterLevel — минимальный уровень доверия, с которого жалоба учитывается при автоскрытии
+	MinReporterLevel int `mapstructure:"min_reporter_level" default:"1"`
+}
+
+type trustConfig struct {
+	Levels []TrustLevelConfig `mapstructure:"levels"`
+}
+
+type TrustLevelConfig struct {
+	MinAge           time.Duration `mapstructure:"min_age"`
+	MinApproved      int           `mapstructure:"min_approved"`
+	MaxUpheldReports int           `mapstructure:"max_upheld_reports"`
+	MinVotes         int           `mapstructure:"min_votes"`
+	Premoderate      bool          `mapstructure:"premoderate"`
+	MaxLinks         int           `mapstructure:"max_links"`
+	MaxPerHour       int           `mapstructure:"max_per_hour"`
+}
+
+type bansConfig struct {
+	IPSalt string `mapstructure:"ip_salt"`
+}
+
+type rateLimitConfig struct {
+	Driver string             `mapstructure:"driver" default:"memory"`
+	Prefix string             `mapstructure:"prefix" default:"ratelimit:"`
+	Routes []RouteLimitConfig `mapstructure:"routes"`
+}
+
+// RouteLimitConfig — ограничения одного маршрута; ключи author, ip и thread считаются независимо
+type RouteLimitConfig struct {
+	Method string         `mapstructure:"method"`
+	Path   string         `mapstructure:"path"`
+	Author RateRuleConfig `mapstructure:"author"`
+	IP     RateRuleConfig `mapstructure:"ip"`
+	Thread RateRuleConfig `mapstructure:"thread"`
+}
+
+type RateRuleConfig struct {
+	Rate  int           `mapstructure:"rate"`
+	Per   time.Duration `mapstructure:"per"`
+	Burst int           `mapstructure:"burst"`
+}
+
+type powConfig struct {
+	Enabled        bool          `mapstructure:"enabled" default:"false"`
+	Secret         string        `mapstructure:"secret"`
+	BaseDifficulty int           `mapstructure:"base_difficulty" default:"16"`
+	MaxDifficulty  int           `mapstructure:"max_difficulty" default:"22"`
+	TTL            time.Duration `mapstructure:"ttl" default:"5m"`
+	Window         time.Duration `mapstructure:"window" default:"10m"`
+	StepComments   int           `mapstructure:"step_comments" default:"20"`
+	MinTrustLevel  int           `mapstructure:"min_trust_level" default:"1"`
+}
+
+type idempotencyConfig struct {
+	TTL         time.Duration `mapstructure:"ttl" default:"24h"`
+	LockTimeout time.Duration `mapstructure:"lock_timeout" default:"1m"`
+}
+
 type ServerConfig struct {
 	Host string `mapstructure:"host" default:"localhost"`
 	Port int    `mapstructure:"port" default:"8080"`
+	// TrustedProxies — адреса и сети прокси, которым доверяется X-Forwarded-For; по умолчанию никому
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type loggerConfig struct {
@@ -90,5 +254,11 @@ func NewAppConfig() (*AppConfig, error) {
 
 	appCfg.RedisConfig.Password = os.Getenv("REDIS_PASSWORD")
 
+	appCfg.NotifyConfig.UnsubscribeSecret = os.Getenv("UNSUBSCRIBE_SECRET")
+	appCfg.NotifyConfig.SMTP.Password = os.Getenv("SMTP_PASSWORD")
+
+	appCfg.BansConfig.IPSalt = os.Getenv("IP_HASH_SALT")
+	appCfg.PowConfig.Secret = os.Getenv("POW_SECRET")
+
 	return &appCfg, nil
 }
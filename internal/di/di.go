@@ -1,7 +1,11 @@
 package di
 
 import (
+	"commentTree/internal/app"
 	"commentTree/internal/config"
+	"commentTree/internal/events"
+	"commentTree/internal/jobs"
+	"commentTree/internal/outbox"
 	"commentTree/internal/storage/db"
 	"commentTree/internal/web"
 	"context"
@@ -12,22 +16,23 @@ import (
 	"net/http"
 )
 
-func StartHTTPServer(lc fx.Lifecycle, CommentHandler *web.CommentHandler, config *config.AppConfig) {
-	router := wbgin.New(config.GinConfig.Mode)
-
-	router.Use(wbgin.Logger(), wbgin.Recovery())
+func StartHTTPServer(lc fx.Lifecycle, CommentHandler *web.CommentHandler, SavedSearchHandler *web.SavedSearchHandler, StreamHandler *web.StreamHandler, ChangesHandler *web.ChangesHandler, WebhookHandler *web.WebhookHandler, JobHandler *web.JobHandler, UserHandler *web.UserHandler, InboxHandler *web.InboxHandler, ModerationHandler *web.ModerationHandler, ReportHandler *web.ReportHandler, BanHandler *web.BanHandler, ChallengeHandler *web.ChallengeHandler, Idempotency *web.Idempotency, RateLimiter *web.RateLimiter, config *config.AppConfig) error {
+	router, err := newRouter(config)
+	if err != nil {
+		return err
+	}
 	router.Use(func(c *wbgin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, DELETE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-PoW-Challenge, X-PoW-Solution, Idempotency-Key")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-First-Unread, Retry-After, Idempotent-Replayed")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
 		}
 		c.Next()
 	})
-
-	web.RegisterRoutes(router, CommentHandler)
+	web.RegisterRoutes(router, CommentHandler, SavedSearchHandler, StreamHandler, ChangesHandler, WebhookHandler, JobHandler, UserHandler, InboxHandler, ModerationHandler, ReportHandler, BanHandler, ChallengeHandler, Idempotency, RateLimiter)
 
 	addres := fmt.Sprintf("%s:%d", config.ServerConfig.Host, config.ServerConfig.Port)
 	server := &http.Server{
@@ -50,9 +55,80 @@ func StartHTTPServer(lc fx.Lifecycle, CommentHandler *web.CommentHandler, config
 			return server.Close()
 		},
 	})
+	return nil
+}
+
+// newRouter создает движок gin. X-Forwarded-For учитывается только от server.trusted_proxies: иначе любой клиент
+// подменил бы адрес, по которому проверяются баны и лимиты.
+func newRouter(config *config.AppConfig) (*wbgin.Engine, error) {
+	router := wbgin.New(config.GinConfig.Mode)
+	if err := router.SetTrustedProxies(config.ServerConfig.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("server.trusted_proxies: %w", err)
+	}
+	router.Use(wbgin.Logger(), wbgin.Recovery())
+	return router, nil
+}
+
+func RegisterSavedSearchHook(commentService *app.CommentService, searchService *app.SavedSearchService) {
+	commentService.Subscribe(searchService.HandleEvent)
+}
+
+func RegisterNotificationHook(commentService *app.CommentService, notificationService *app.NotificationService) {
+	commentService.Subscribe(notificationService.HandleEvent)
+}
+
+func StartEventBus(lc fx.Lifecycle, bus events.Bus, hub *events.Hub) {
+	bus.Subscribe(hub.Publish)
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			log.Println("Starting event bus...")
+			return bus.Start(ctx)
+		},
+		OnStop: func(ctx context.Context) error {
+			log.Println("Stopping event bus...")
+			return bus.Stop(ctx)
+		},
+	})
+}
+
+func StartOutboxRelay(lc fx.Lifecycle, relay *outbox.Relay, postgres *db.Postgres) {
+	postgres.SetOutboxWaker(relay.Wake)
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			log.Println("Starting outbox relay...")
+			return relay.Start(ctx)
+		},
+		OnStop: func(ctx context.Context) error {
+			log.Println("Stopping outbox relay...")
+			return relay.Stop(ctx)
+		},
+	})
+}
+
+func StartJobPool(lc fx.Lifecycle, pool *jobs.Pool) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			log.Println("Starting job workers...")
+			return pool.Start(ctx)
+		},
+		OnStop: func(ctx context.Context) error {
+			log.Println("Draining job workers...")
+			return pool.Stop(ctx)
+		},
+	})
+}
+
+// Database — соединения с базой, которые закрываются при остановке приложения
+type Database interface {
+	Close() error
 }
 
-func ClosePostgresOnStop(lc fx.Lifecycle, postgres *db.Postgres) {
+// ClosePostgresOnStop закрывает соединения с базой. Вызывается первым из fx.Invoke: fx выполняет OnStop
+// в обратном порядке, поэтому база закрывается последней, после того как HTTP-сервер, пул задач,
+// outbox relay и шина событий остановились и записали результаты.
+func ClosePostgresOnStop(lc fx.Lifecycle, postgres Database) {
 	lc.Append(fx.Hook{
 		OnStop: func(ctx context.Context) error {
 			log.Println("Closing Postgres connections...")
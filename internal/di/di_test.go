@@ -0,0 +1,131 @@
+package di
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"commentTree/internal/jobs"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// closableStore — хранилище задач, которое, как *sql.DB, перестает писать после Close
+type closableStore struct {
+	jobs.Store
+	mu     sync.Mutex
+	closed bool
+	job    *app.Job
+}
+
+func (s *closableStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *closableStore) EnqueueJob(ctx context.Context, job *app.Job, uniqueKey string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *job
+	s.job = &stored
+	return true, nil
+}
+
+func (s *closableStore) ClaimJobs(ctx context.Context, kinds []string, limit int, lockedUntil time.Time) ([]app.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errors.New("sql: database is closed")
+	}
+	if s.job == nil || s.job.Status != app.JobPending {
+		return nil, nil
+	}
+	s.job.Status = app.JobRunning
+	s.job.Attempts++
+	return []app.Job{*s.job}, nil
+}
+
+func (s *closableStore) CompleteJob(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("sql: database is closed")
+	}
+	s.job.Status = app.JobDone
+	return nil
+}
+
+func (s *closableStore) status() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.job.Status
+}
+
+func TestShutdown_CompletesJobInFlightBeforeClosingDatabase(t *testing.T) {
+	store := &closableStore{}
+	started := make(chan struct{})
+	slow := jobs.NewHandler("slow", func(ctx context.Context, payload struct{}) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+
+	var queue *jobs.Queue
+	fxApp := fxtest.New(t,
+		fx.Supply(&config.AppConfig{}),
+		fx.Provide(
+			func() jobs.Store { return store },
+			func() Database { return store },
+			jobs.NewQueue,
+			fx.Annotate(func() jobs.Handler { return slow }, fx.ResultTags(`group:"job_handlers"`)),
+			jobs.NewPool,
+		),
+		fx.Populate(&queue),
+		// тот же порядок, что в main: база регистрируется первой и закрывается последней
+		fx.Invoke(ClosePostgresOnStop, StartJobPool),
+	)
+	fxApp.RequireStart()
+
+	_, err := queue.Enqueue(context.Background(), "slow", struct{}{})
+	assert.NoError(t, err)
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job was not started")
+	}
+
+	fxApp.RequireStop()
+	assert.Equal(t, app.JobDone, store.status())
+}
+
+func TestNewRouter_IgnoresForwardedForFromUntrustedClients(t *testing.T) {
+	clientIP := func(cfg *config.AppConfig) string {
+		router, err := newRouter(cfg)
+		assert.NoError(t, err)
+		var ip string
+		// CreateComment передает ctx.ClientIP() в проверку банов, лимитер берет из него ключ
+		router.POST("/api/comments", func(ctx *wbgin.Context) { ip = ctx.ClientIP() })
+
+		req := httptest.NewRequest(http.MethodPost, "/api/comments", nil)
+		req.RemoteAddr = "10.0.0.7:51234"
+		req.Header.Set("X-Forwarded-For", "203.0.113.9")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return ip
+	}
+
+	assert.Equal(t, "10.0.0.7", clientIP(&config.AppConfig{}))
+
+	behindProxy := &config.AppConfig{}
+	behindProxy.ServerConfig.TrustedProxies = []string{"10.0.0.0/8"}
+	assert.Equal(t, "203.0.113.9", clientIP(behindProxy))
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"sync"
+)
+
+// Handler получает события шины
+type Handler func(event app.CommentEvent)
+
+// Bus — шина событий об изменении комментариев.
+// Реализации могут доставлять события между инстансами сервиса.
+type Bus interface {
+	Publish(event app.CommentEvent) error
+	Subscribe(handler Handler)
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// LocalBus — шина в пределах одного процесса
+type LocalBus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewLocalBus() *LocalBus {
+	return &LocalBus{}
+}
+
+func (b *LocalBus) Publish(event app.CommentEvent) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(event)
+	}
+	return nil
+}
+
+func (b *LocalBus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *LocalBus) Start(ctx context.Context) error {
+	return nil
+}
+
+func (b *LocalBus) Stop(ctx context.Context) error {
+	return nil
+}
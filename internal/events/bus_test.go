@@ -0,0 +1,27 @@
+package events
+
+import (
+	"commentTree/internal/app/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLocalBus_DeliversToAllSubscribers(t *testing.T) {
+	bus := NewLocalBus()
+	hub := newTestHub(10, 10)
+	sub, _, _ := hub.Subscribe(nil, 0)
+
+	var received []app.CommentEvent
+	bus.Subscribe(func(event app.CommentEvent) {
+		received = append(received, event)
+	})
+	bus.Subscribe(hub.Publish)
+
+	event := app.NewCommentEvent(app.EventCommentDeleted, uuid.New(), nil)
+	assert.NoError(t, bus.Publish(event))
+
+	assert.Len(t, received, 1)
+	assert.Equal(t, event.CommentID, received[0].CommentID)
+	assert.Len(t, sub.C, 1)
+}
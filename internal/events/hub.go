@@ -0,0 +1,149 @@
+package events
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"errors"
+	"github.com/google/uuid"
+	"sync"
+)
+
+var ErrEventExpired = errors.New("last event id is no longer in buffer")
+
+// Message — событие с порядковым номером, по которому клиент может возобновить поток
+type Message struct {
+	ID    uint64
+	Event app.CommentEvent
+}
+
+// Subscription — подписка одного клиента на ветку комментариев.
+// Если клиент не успевает вычитывать сообщения, подписка закрывается.
+type Subscription struct {
+	C      chan Message
+	Done   chan struct{}
+	rootID *uuid.UUID
+	once   sync.Once
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		close(s.Done)
+	})
+}
+
+func (s *Subscription) matches(event app.CommentEvent) bool {
+	return s.rootID == nil || event.InSubtree(*s.rootID)
+}
+
+// Hub — внутрипроцессная рассылка событий с коротким буфером для Last-Event-ID
+type Hub struct {
+	mu           sync.Mutex
+	seq          uint64
+	buffer       []Message
+	bufferSize   int
+	clientBuffer int
+	subs         map[*Subscription]struct{}
+}
+
+func NewHub(cfg *config.AppConfig) *Hub {
+	bufferSize := cfg.StreamConfig.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 256
+	}
+	clientBuffer := cfg.StreamConfig.ClientBuffer
+	if clientBuffer <= 0 {
+		clientBuffer = 32
+	}
+	return &Hub{
+		bufferSize:   bufferSize,
+		clientBuffer: clientBuffer,
+		subs:         make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish раздает событие всем подходящим подписчикам, не блокируясь на медленных клиентах.
+// Повторно доставленное событие, которое еще лежит в буфере, отбрасывается.
+func (h *Hub) Publish(event app.CommentEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.seen(event.ID) {
+		return
+	}
+	h.seq++
+	msg := Message{ID: h.seq, Event: event}
+	h.buffer = append(h.buffer, msg)
+	if len(h.buffer) > h.bufferSize {
+		h.buffer = h.buffer[len(h.buffer)-h.bufferSize:]
+	}
+
+	for sub := range h.subs {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.C <- msg:
+		default:
+			delete(h.subs, sub)
+			sub.close()
+		}
+	}
+}
+
+func (h *Hub) seen(id uuid.UUID) bool {
+	if id == uuid.Nil {
+		return false
+	}
+	for _, msg := range h.buffer {
+		if msg.Event.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribe подписывает клиента на ветку rootID (nil — все комментарии).
+// Если lastEventID > 0, возвращает пропущенные события из буфера.
+func (h *Hub) Subscribe(rootID *uuid.UUID, lastEventID uint64) (*Subscription, []Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{
+		C:      make(chan Message, h.clientBuffer),
+		Done:   make(chan struct{}),
+		rootID: rootID,
+	}
+
+	var missed []Message
+	if lastEventID > h.seq {
+		return nil, nil, ErrEventExpired
+	}
+	if lastEventID > 0 && lastEventID < h.seq {
+		if len(h.buffer) == 0 || h.buffer[0].ID > lastEventID+1 {
+			return nil, nil, ErrEventExpired
+		}
+		for _, msg := range h.buffer {
+			if msg.ID > lastEventID && sub.matches(msg.Event) {
+				missed = append(missed, msg)
+			}
+		}
+	}
+
+	h.subs[sub] = struct{}{}
+	return sub, missed, nil
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subs, sub)
+	sub.close()
+}
+
+// LastID возвращает номер последнего опубликованного события
+func (h *Hub) LastID() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seq
+}
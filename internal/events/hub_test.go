@@ -0,0 +1,114 @@
+package events
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestHub(bufferSize, clientBuffer int) *Hub {
+	cfg := &config.AppConfig{}
+	cfg.StreamConfig.BufferSize = bufferSize
+	cfg.StreamConfig.ClientBuffer = clientBuffer
+	return NewHub(cfg)
+}
+
+func eventIn(path ...uuid.UUID) app.CommentEvent {
+	event := app.NewCommentEvent(app.EventCommentCreated, path[len(path)-1], nil)
+	event.Path = path
+	return event
+}
+
+func TestHub_PublishFiltersBySubtree(t *testing.T) {
+	hub := newTestHub(10, 10)
+	rootID, otherID := uuid.New(), uuid.New()
+
+	sub, missed, err := hub.Subscribe(&rootID, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, missed)
+
+	hub.Publish(eventIn(rootID, uuid.New()))
+	hub.Publish(eventIn(otherID, uuid.New()))
+
+	assert.Len(t, sub.C, 1)
+	msg := <-sub.C
+	assert.Equal(t, uint64(1), msg.ID)
+	assert.True(t, msg.Event.InSubtree(rootID))
+}
+
+func TestHub_PublishMovedToBothSubtrees(t *testing.T) {
+	hub := newTestHub(10, 10)
+	fromID, toID := uuid.New(), uuid.New()
+	fromSub, _, _ := hub.Subscribe(&fromID, 0)
+	toSub, _, _ := hub.Subscribe(&toID, 0)
+
+	movedID := uuid.New()
+	event := app.NewCommentEvent(app.EventCommentMoved, movedID, nil)
+	event.Path = []uuid.UUID{toID, movedID}
+	event.PreviousPath = []uuid.UUID{fromID, movedID}
+	hub.Publish(event)
+
+	assert.Len(t, fromSub.C, 1)
+	assert.Len(t, toSub.C, 1)
+}
+
+func TestHub_SubscribeResumesFromBuffer(t *testing.T) {
+	hub := newTestHub(10, 10)
+	rootID := uuid.New()
+	for i := 0; i < 3; i++ {
+		hub.Publish(eventIn(rootID, uuid.New()))
+	}
+
+	_, missed, err := hub.Subscribe(&rootID, 1)
+	assert.NoError(t, err)
+	assert.Len(t, missed, 2)
+	assert.Equal(t, uint64(2), missed[0].ID)
+	assert.Equal(t, uint64(3), missed[1].ID)
+}
+
+func TestHub_SubscribeExpiredLastEventID(t *testing.T) {
+	hub := newTestHub(2, 10)
+	rootID := uuid.New()
+	for i := 0; i < 5; i++ {
+		hub.Publish(eventIn(rootID, uuid.New()))
+	}
+
+	_, _, err := hub.Subscribe(nil, 1)
+	assert.ErrorIs(t, err, ErrEventExpired)
+
+	_, _, err = hub.Subscribe(nil, 100)
+	assert.ErrorIs(t, err, ErrEventExpired)
+
+	_, missed, err := hub.Subscribe(nil, 3)
+	assert.NoError(t, err)
+	assert.Len(t, missed, 2)
+}
+
+func TestHub_SlowClientIsDropped(t *testing.T) {
+	hub := newTestHub(10, 1)
+	sub, _, _ := hub.Subscribe(nil, 0)
+
+	hub.Publish(eventIn(uuid.New()))
+	hub.Publish(eventIn(uuid.New()))
+
+	select {
+	case <-sub.Done:
+	default:
+		t.Fatal("expected slow subscription to be closed")
+	}
+	hub.Unsubscribe(sub)
+}
+
+func TestHub_DropsRedeliveredEvent(t *testing.T) {
+	hub := newTestHub(10, 10)
+	sub, _, _ := hub.Subscribe(nil, 0)
+
+	event := eventIn(uuid.New())
+	hub.Publish(event)
+	hub.Publish(event)
+
+	assert.Len(t, sub.C, 1)
+	assert.Equal(t, uint64(1), hub.LastID())
+}
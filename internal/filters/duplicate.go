@@ -0,0 +1,46 @@
+package filters
+
+import (
+	"commentTree/internal/app/domain"
+	"github.com/google/uuid"
+	"time"
+)
+
+// DuplicateStore ищет комментарии автора с тем же текстом
+type DuplicateStore interface {
+	CountDuplicates(authorID, text string, excludeID *uuid.UUID, since time.Time) (int, error)
+}
+
+// DuplicateFilter отклоняет повтор текста от одного автора в пределах окна.
+// Анонимные комментарии не проверяются: их нельзя связать с автором.
+type DuplicateFilter struct {
+	store  DuplicateStore
+	window time.Duration
+}
+
+// NewDuplicateFilter создает фильтр; window <= 0 отключает проверку
+func NewDuplicateFilter(store DuplicateStore, window time.Duration) *DuplicateFilter {
+	return &DuplicateFilter{store: store, window: window}
+}
+
+func (f *DuplicateFilter) Name() string {
+	return "duplicate"
+}
+
+func (f *DuplicateFilter) Check(input app.FilterInput) (app.FilterVerdict, error) {
+	if f.window <= 0 || input.AuthorID == "" {
+		return app.Allow(), nil
+	}
+	n, err := f.store.CountDuplicates(input.AuthorID, input.Text, input.CommentID, time.Now().Add(-f.window))
+	if err != nil {
+		return app.FilterVerdict{}, err
+	}
+	if n > 0 {
+		return app.FilterVerdict{
+			Action: app.FilterReject,
+			Filter: f.Name(),
+			Reason: "same text was posted within " + f.window.String(),
+		}, nil
+	}
+	return app.Allow(), nil
+}
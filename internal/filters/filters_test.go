@@ -0,0 +1,73 @@
+package filters
+
+import (
+	"commentTree/internal/app/domain"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func check(t *testing.T, f interface {
+	Check(app.FilterInput) (app.FilterVerdict, error)
+}, text string) app.FilterAction {
+	t.Helper()
+	v, err := f.Check(app.FilterInput{Text: text, AuthorID: uuid.New().String()})
+	assert.NoError(t, err)
+	return v.Action
+}
+
+func TestWords(t *testing.T) {
+	assert.Equal(t, []string{"spam"}, Words("SPAM"))
+	assert.Equal(t, []string{"spam"}, Words("$p4m"))
+	assert.Equal(t, []string{"spam"}, Words("s.p.a.m"))
+	assert.Equal(t, []string{"spam"}, Words("spaaaam"))
+	assert.Equal(t, Words("spammers"), Words("spammer"))
+	assert.Equal(t, Words("дурак"), Words("дураки"))
+}
+
+func TestWordFilter(t *testing.T) {
+	f := NewWordFilter([]string{"spam", "дурак"}, app.FilterReject)
+	assert.Equal(t, app.FilterReject, check(t, f, "buy sp4m now"))
+	assert.Equal(t, app.FilterReject, check(t, f, "сам ты ДУРАКИ"))
+	assert.Equal(t, app.FilterAllow, check(t, f, "spanish is nice"))
+	assert.Equal(t, app.FilterAllow, check(t, NewWordFilter(nil, app.FilterReject), "spam"))
+}
+
+func TestDomainFilter(t *testing.T) {
+	f := NewDomainFilter([]string{"Spam.example"})
+	assert.Equal(t, app.FilterReject, check(t, f, "see https://spam.example/x"))
+	assert.Equal(t, app.FilterReject, check(t, f, "[ok](http://user@cdn.spam.example:8080/a)"))
+	assert.Equal(t, app.FilterReject, check(t, f, "www.spam.example"))
+	assert.Equal(t, app.FilterAllow, check(t, f, "https://notspam.example and spam.example without scheme"))
+}
+
+func TestRepeatFilter(t *testing.T) {
+	f := NewRepeatFilter(5)
+	assert.Equal(t, app.FilterFlag, check(t, f, "wow!!!!!!"))
+	assert.Equal(t, app.FilterAllow, check(t, f, "wow!!!!!"))
+	assert.Equal(t, app.FilterAllow, check(t, f, "a"+strings.Repeat(" ", 20)+"b"))
+}
+
+type fakeDuplicates struct {
+	count int
+	err   error
+}
+
+func (f fakeDuplicates) CountDuplicates(authorID, text string, excludeID *uuid.UUID, since time.Time) (int, error) {
+	return f.count, f.err
+}
+
+func TestDuplicateFilter(t *testing.T) {
+	assert.Equal(t, app.FilterReject, check(t, NewDuplicateFilter(fakeDuplicates{count: 1}, time.Minute), "hi"))
+	assert.Equal(t, app.FilterAllow, check(t, NewDuplicateFilter(fakeDuplicates{}, time.Minute), "hi"))
+
+	v, err := NewDuplicateFilter(fakeDuplicates{count: 1}, time.Minute).Check(app.FilterInput{Text: "hi"})
+	assert.NoError(t, err)
+	assert.Equal(t, app.FilterAllow, v.Action, "anonymous comments are not checked")
+
+	_, err = NewDuplicateFilter(fakeDuplicates{err: errors.New("db")}, time.Minute).Check(app.FilterInput{Text: "hi", AuthorID: "a"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package filters
+
+import (
+	"commentTree/internal/app/domain"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// hostPattern выделяет хост из ссылок вида http(s)://host и www.host
+var hostPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)([^\s/?#)\]>"']+)`)
+
+// DomainFilter отклоняет комментарии со ссылками на домены из блок-листа и их поддомены
+type DomainFilter struct {
+	domains []string
+}
+
+func NewDomainFilter(domains []string) *DomainFilter {
+	f := &DomainFilter{}
+	for _, d := range domains {
+		d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d != "" {
+			f.domains = append(f.domains, d)
+		}
+	}
+	return f
+}
+
+func (f *DomainFilter) Name() string {
+	return "domains"
+}
+
+func (f *DomainFilter) Check(input app.FilterInput) (app.FilterVerdict, error) {
+	if len(f.domains) == 0 {
+		return app.Allow(), nil
+	}
+	for _, m := range hostPattern.FindAllStringSubmatch(input.Text, -1) {
+		host := linkHost(m[1])
+		for _, d := range f.domains {
+			if host == d || strings.HasSuffix(host, "."+d) {
+				return app.FilterVerdict{Action: app.FilterReject, Filter: f.Name(), Reason: "blocked link domain: " + d}, nil
+			}
+		}
+	}
+	return app.Allow(), nil
+}
+
+// linkHost отрезает от authority учетные данные и порт
+func linkHost(authority string) string {
+	if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+		authority = authority[i+1:]
+	}
+	if host, _, err := net.SplitHostPort(authority); err == nil {
+		authority = host
+	}
+	host := strings.Trim(strings.ToLower(authority), ".")
+	return strings.TrimPrefix(host, "www.")
+}
@@ -0,0 +1,44 @@
+package filters
+
+import (
+	"commentTree/internal/app/domain"
+	"fmt"
+	"unicode"
+)
+
+// RepeatFilter отправляет на проверку текст с длинными повторами одного символа («!!!!!!!!!!!», «ааааааааааа»)
+type RepeatFilter struct {
+	max int
+}
+
+// NewRepeatFilter создает фильтр; max <= 0 отключает проверку
+func NewRepeatFilter(max int) *RepeatFilter {
+	return &RepeatFilter{max: max}
+}
+
+func (f *RepeatFilter) Name() string {
+	return "repeat"
+}
+
+func (f *RepeatFilter) Check(input app.FilterInput) (app.FilterVerdict, error) {
+	if f.max <= 0 {
+		return app.Allow(), nil
+	}
+	var prev rune
+	run := 0
+	for _, r := range input.Text {
+		if r == prev {
+			run++
+		} else {
+			prev, run = r, 1
+		}
+		if run > f.max && !unicode.IsSpace(r) {
+			return app.FilterVerdict{
+				Action: app.FilterFlag,
+				Filter: f.Name(),
+				Reason: fmt.Sprintf("character %q repeated more than %d times", r, f.max),
+			}, nil
+		}
+	}
+	return app.Allow(), nil
+}
@@ -0,0 +1,114 @@
+// Package filters содержит встроенные фильтры содержимого комментариев:
+// списки слов, блок-лист доменов ссылок, повтор символов и дубли от одного автора.
+package filters
+
+import (
+	"commentTree/internal/app/domain"
+	"strings"
+	"unicode"
+)
+
+// leet — замены цифр и символов на похожие буквы
+var leet = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '@': 'a', '$': 's',
+}
+
+// suffixes — окончания, которые отрезает stem, от длинных к коротким
+var suffixes = []string{
+	"иями", "ями", "ами", "ого", "его", "ому", "ему", "ыми", "ими",
+	"ing", "ers", "ed", "es", "er", "ly",
+	"ов", "ев", "ей", "ой", "ий", "ый", "ая", "ое", "ые", "ие", "ам", "ям", "ах", "ях", "ом", "ем",
+	"s", "а", "я", "о", "е", "ы", "и", "у", "ю",
+}
+
+// Words разбивает текст на нормализованные основы слов: нижний регистр, leet-замены,
+// схлопнутые повторы букв, отрезанные окончания. Разрядка «s p a m» и «s.p.a.m» склеивается в одно слово.
+func Words(text string) []string {
+	var tokens []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			tokens = append(tokens, string(cur))
+			cur = cur[:0]
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		if l, ok := leet[r]; ok {
+			r = l
+		}
+		if unicode.IsLetter(r) {
+			cur = append(cur, r)
+			continue
+		}
+		flush()
+	}
+	flush()
+
+	var words []string
+	var spaced []rune
+	for _, t := range tokens {
+		if r := []rune(t); len(r) == 1 {
+			spaced = append(spaced, r[0])
+			continue
+		}
+		if len(spaced) > 1 {
+			words = append(words, stem(string(spaced)))
+		}
+		spaced = spaced[:0]
+		words = append(words, stem(t))
+	}
+	if len(spaced) > 1 {
+		words = append(words, stem(string(spaced)))
+	}
+	return words
+}
+
+func stem(word string) string {
+	var b []rune
+	for _, r := range word {
+		if len(b) > 0 && b[len(b)-1] == r {
+			continue
+		}
+		b = append(b, r)
+	}
+	word = string(b)
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(word, suffix) && len([]rune(word))-len([]rune(suffix)) >= 3 {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+// WordFilter срабатывает на слова из списка с учетом словоформ и leet-замен
+type WordFilter struct {
+	action app.FilterAction
+	stems  map[string]string
+}
+
+// NewWordFilter создает фильтр по списку отдельных слов с решением action
+func NewWordFilter(words []string, action app.FilterAction) *WordFilter {
+	stems := make(map[string]string, len(words))
+	for _, w := range words {
+		for _, s := range Words(w) {
+			stems[s] = w
+		}
+	}
+	return &WordFilter{action: action, stems: stems}
+}
+
+func (f *WordFilter) Name() string {
+	return "words"
+}
+
+func (f *WordFilter) Check(input app.FilterInput) (app.FilterVerdict, error) {
+	if len(f.stems) == 0 {
+		return app.Allow(), nil
+	}
+	for _, w := range Words(input.Text) {
+		if listed, ok := f.stems[w]; ok {
+			return app.FilterVerdict{Action: f.action, Filter: f.Name(), Reason: "word from list: " + listed}, nil
+		}
+	}
+	return app.Allow(), nil
+}
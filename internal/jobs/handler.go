@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Handler выполняет задачи одного вида. Handler регистрируется в fx-группе "job_handlers".
+type Handler interface {
+	Kind() string
+	Handle(ctx context.Context, payload json.RawMessage) error
+}
+
+type typedHandler[T any] struct {
+	kind string
+	fn   func(ctx context.Context, payload T) error
+}
+
+// NewHandler создает обработчик, который декодирует payload задачи в T
+func NewHandler[T any](kind string, fn func(ctx context.Context, payload T) error) Handler {
+	return &typedHandler[T]{kind: kind, fn: fn}
+}
+
+func (h *typedHandler[T]) Kind() string {
+	return h.kind
+}
+
+func (h *typedHandler[T]) Handle(ctx context.Context, payload json.RawMessage) error {
+	var p T
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return Permanent(fmt.Errorf("decode %s payload: %w", h.kind, err))
+		}
+	}
+	return h.fn(ctx, p)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent помечает ошибку как неисправимую: задача сразу переходит в failed без ретраев
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,222 @@
+package jobs
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"context"
+	"fmt"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"go.uber.org/fx"
+	"sync"
+	"time"
+)
+
+// PoolParams — зависимости пула: обработчики и расписания собираются из fx-групп
+type PoolParams struct {
+	fx.In
+
+	Queue     *Queue
+	Store     Store
+	Config    *config.AppConfig
+	Handlers  []Handler  `group:"job_handlers"`
+	Schedules []Schedule `group:"job_schedules"`
+}
+
+// Pool — пул воркеров, выполняющих задачи из jobs
+type Pool struct {
+	queue        *Queue
+	store        Store
+	handlers     map[string]Handler
+	kinds        []string
+	schedules    []*scheduler
+	concurrency  int
+	pollInterval time.Duration
+	lease        time.Duration
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+
+	// stop останавливает выборку новых задач, kill прерывает уже выполняющиеся
+	stop    context.CancelFunc
+	kill    context.CancelFunc
+	jobsCtx context.Context
+	wg      sync.WaitGroup
+}
+
+func NewPool(p PoolParams) (*Pool, error) {
+	jobsCfg := p.Config.JobsConfig
+	pool := &Pool{
+		queue:        p.Queue,
+		store:        p.Store,
+		handlers:     make(map[string]Handler, len(p.Handlers)),
+		concurrency:  jobsCfg.Concurrency,
+		pollInterval: jobsCfg.PollInterval,
+		lease:        jobsCfg.Lease,
+		backoffBase:  jobsCfg.BackoffBase,
+		backoffMax:   jobsCfg.BackoffMax,
+	}
+	if pool.concurrency <= 0 {
+		pool.concurrency = 4
+	}
+	if pool.pollInterval <= 0 {
+		pool.pollInterval = time.Second
+	}
+	if pool.lease <= 0 {
+		pool.lease = 5 * time.Minute
+	}
+	if pool.backoffBase <= 0 {
+		pool.backoffBase = 5 * time.Second
+	}
+	if pool.backoffMax <= 0 {
+		pool.backoffMax = time.Hour
+	}
+
+	for _, h := range p.Handlers {
+		if _, ok := pool.handlers[h.Kind()]; ok {
+			return nil, fmt.Errorf("duplicate job handler %q", h.Kind())
+		}
+		pool.handlers[h.Kind()] = h
+		pool.kinds = append(pool.kinds, h.Kind())
+	}
+	for _, s := range p.Schedules {
+		if _, ok := pool.handlers[s.Kind]; !ok {
+			return nil, fmt.Errorf("schedule %q: no handler for job kind %q", s.Name, s.Kind)
+		}
+		sched, err := newScheduler(s, p.Queue)
+		if err != nil {
+			return nil, err
+		}
+		pool.schedules = append(pool.schedules, sched)
+	}
+	return pool, nil
+}
+
+func (p *Pool) Start(ctx context.Context) error {
+	var runCtx context.Context
+	runCtx, p.stop = context.WithCancel(context.Background())
+	p.jobsCtx, p.kill = context.WithCancel(context.Background())
+
+	if len(p.kinds) > 0 {
+		for i := 0; i < p.concurrency; i++ {
+			p.wg.Add(1)
+			go p.work(runCtx)
+		}
+	}
+	for _, sched := range p.schedules {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			sched.run(runCtx)
+		}()
+	}
+	return nil
+}
+
+// Stop перестает забирать новые задачи и ждет выполняющиеся. Если ctx истекает раньше,
+// задачи прерываются и после окончания аренды будут взяты повторно.
+func (p *Pool) Stop(ctx context.Context) error {
+	if p.stop == nil {
+		return nil
+	}
+	p.stop()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.kill()
+		return nil
+	case <-ctx.Done():
+		p.kill()
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work(ctx context.Context) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if p.runNext(ctx) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.queue.ready:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext забирает и выполняет одну задачу; false, если готовых задач нет
+func (p *Pool) runNext(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	claimed, err := p.store.ClaimJobs(ctx, p.kinds, 1, time.Now().Add(p.lease))
+	if err != nil {
+		if ctx.Err() == nil {
+			wbzlog.Logger.Error().Err(err).Msg("failed to claim jobs")
+		}
+		return false
+	}
+	if len(claimed) == 0 {
+		return false
+	}
+	p.run(claimed[0])
+	return true
+}
+
+func (p *Pool) run(job app.Job) {
+	ctx, cancel := context.WithTimeout(p.jobsCtx, p.lease)
+	defer cancel()
+
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// аренда истекла на последней попытке — воркер упал посреди выполнения
+		err = Permanent(fmt.Errorf("job lease expired after %d attempts", job.MaxAttempts))
+	} else {
+		err = p.handle(ctx, job)
+	}
+
+	// результат записываем и при остановке пула, поэтому не используем ctx задачи
+	storeCtx := context.Background()
+	switch {
+	case err == nil:
+		err = p.store.CompleteJob(storeCtx, job.ID)
+	case isPermanent(err) || job.Attempts >= job.MaxAttempts:
+		wbzlog.Logger.Error().Err(err).Str("kind", job.Kind).Str("job", job.ID.String()).Msg("job failed")
+		err = p.store.FailJob(storeCtx, job.ID, err.Error())
+	default:
+		runAt := time.Now().Add(p.backoff(job.Attempts))
+		wbzlog.Logger.Warn().Err(err).Str("kind", job.Kind).Str("job", job.ID.String()).Time("retry_at", runAt).Msg("job will be retried")
+		err = p.store.RetryJob(storeCtx, job.ID, runAt, err.Error())
+	}
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Str("job", job.ID.String()).Msg("failed to update job status")
+	}
+}
+
+func (p *Pool) handle(ctx context.Context, job app.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panic: %v", r)
+		}
+	}()
+	return p.handlers[job.Kind].Handle(ctx, job.Payload)
+}
+
+// backoff возвращает задержку перед попыткой attempt+1: base * 2^(attempt-1), не больше backoffMax
+func (p *Pool) backoff(attempt int) time.Duration {
+	delay := p.backoffBase
+	for i := 1; i < attempt && delay < p.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > p.backoffMax {
+		delay = p.backoffMax
+	}
+	return delay
+}
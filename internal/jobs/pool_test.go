@@ -0,0 +1,248 @@
+package jobs
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore — хранилище задач в памяти с семантикой Postgres-реализации
+type memoryStore struct {
+	mu   sync.Mutex
+	jobs map[uuid.UUID]*app.Job
+	keys map[string]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{jobs: make(map[uuid.UUID]*app.Job), keys: make(map[string]bool)}
+}
+
+func (s *memoryStore) EnqueueJob(ctx context.Context, job *app.Job, uniqueKey string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if uniqueKey != "" {
+		if s.keys[uniqueKey] {
+			return false, nil
+		}
+		s.keys[uniqueKey] = true
+	}
+	stored := *job
+	s.jobs[job.ID] = &stored
+	return true, nil
+}
+
+func (s *memoryStore) ClaimJobs(ctx context.Context, kinds []string, limit int, lockedUntil time.Time) ([]app.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []app.Job
+	for _, job := range s.jobs {
+		if len(claimed) == limit {
+			break
+		}
+		if job.Status != app.JobPending || job.RunAt.After(time.Now()) {
+			continue
+		}
+		job.Status = app.JobRunning
+		job.Attempts++
+		claimed = append(claimed, *job)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) setStatus(id uuid.UUID, status string, runAt time.Time, lastError string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[id]
+	job.Status = status
+	job.LastError = lastError
+	if !runAt.IsZero() {
+		job.RunAt = runAt
+	}
+}
+
+func (s *memoryStore) CompleteJob(ctx context.Context, id uuid.UUID) error {
+	s.setStatus(id, app.JobDone, time.Time{}, "")
+	return nil
+}
+
+func (s *memoryStore) RetryJob(ctx context.Context, id uuid.UUID, runAt time.Time, lastError string) error {
+	s.setStatus(id, app.JobPending, runAt, lastError)
+	return nil
+}
+
+func (s *memoryStore) FailJob(ctx context.Context, id uuid.UUID, lastError string) error {
+	s.setStatus(id, app.JobFailed, time.Time{}, lastError)
+	return nil
+}
+
+func (s *memoryStore) PurgeJobs(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *memoryStore) GetJobs(status string, page, pageSize int) ([]app.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []app.Job
+	for _, job := range s.jobs {
+		if job.Status == status {
+			jobs = append(jobs, *job)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *memoryStore) RequeueJob(id string) (*app.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[uuid.MustParse(id)]
+	if !ok || job.Status != app.JobFailed {
+		return nil, app.ErrNotFound
+	}
+	job.Status = app.JobPending
+	job.Attempts = 0
+	job.RunAt = time.Now()
+	copied := *job
+	return &copied, nil
+}
+
+func (s *memoryStore) DeleteJob(id string) error {
+	return nil
+}
+
+func (s *memoryStore) get(id uuid.UUID) app.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.jobs[id]
+}
+
+type testPayload struct {
+	Value string `json:"value"`
+}
+
+func newTestPool(t *testing.T, store *memoryStore, handlers ...Handler) (*Queue, *Pool) {
+	cfg := &config.AppConfig{}
+	cfg.JobsConfig.MaxAttempts = 3
+	cfg.JobsConfig.BackoffBase = time.Millisecond
+	cfg.JobsConfig.BackoffMax = 4 * time.Millisecond
+	cfg.JobsConfig.PollInterval = time.Millisecond
+	queue := NewQueue(store, cfg)
+	pool, err := NewPool(PoolParams{Queue: queue, Store: store, Config: cfg, Handlers: handlers})
+	assert.NoError(t, err)
+	return queue, pool
+}
+
+func waitStatus(t *testing.T, store *memoryStore, id uuid.UUID, status string) app.Job {
+	var job app.Job
+	assert.Eventually(t, func() bool {
+		job = store.get(id)
+		return job.Status == status
+	}, time.Second, time.Millisecond)
+	return job
+}
+
+func TestPool_RunsTypedHandler(t *testing.T) {
+	store := newMemoryStore()
+	got := make(chan string, 1)
+	queue, pool := newTestPool(t, store, NewHandler("test.echo", func(ctx context.Context, p testPayload) error {
+		got <- p.Value
+		return nil
+	}))
+	assert.NoError(t, pool.Start(context.Background()))
+	defer func() { _ = pool.Stop(context.Background()) }()
+
+	job, err := queue.Enqueue(context.Background(), "test.echo", testPayload{Value: "hello"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "hello", <-got)
+	waitStatus(t, store, job.ID, app.JobDone)
+}
+
+func TestPool_RetriesWithBackoffThenFails(t *testing.T) {
+	store := newMemoryStore()
+	queue, pool := newTestPool(t, store, NewHandler("test.flaky", func(ctx context.Context, p testPayload) error {
+		return errors.New("temporary failure")
+	}))
+	assert.NoError(t, pool.Start(context.Background()))
+	defer func() { _ = pool.Stop(context.Background()) }()
+
+	job, _ := queue.Enqueue(context.Background(), "test.flaky", testPayload{})
+	failed := waitStatus(t, store, job.ID, app.JobFailed)
+	assert.Equal(t, 3, failed.Attempts)
+	assert.Equal(t, "temporary failure", failed.LastError)
+
+	// после ручного retry задача снова выполняется с нуля
+	requeued, err := queue.RetryJob(job.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, requeued.Attempts)
+	waitStatus(t, store, job.ID, app.JobFailed)
+}
+
+func TestPool_PermanentErrorSkipsRetries(t *testing.T) {
+	store := newMemoryStore()
+	queue, pool := newTestPool(t, store, NewHandler("test.bad", func(ctx context.Context, p testPayload) error {
+		return nil
+	}))
+	assert.NoError(t, pool.Start(context.Background()))
+	defer func() { _ = pool.Stop(context.Background()) }()
+
+	// payload не декодируется в testPayload
+	job, _ := queue.Enqueue(context.Background(), "test.bad", []int{1, 2})
+	failed := waitStatus(t, store, job.ID, app.JobFailed)
+	assert.Equal(t, 1, failed.Attempts)
+}
+
+func TestPool_StopDrainsRunningJobs(t *testing.T) {
+	store := newMemoryStore()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	queue, pool := newTestPool(t, store, NewHandler("test.slow", func(ctx context.Context, p testPayload) error {
+		close(started)
+		<-release
+		return nil
+	}))
+	assert.NoError(t, pool.Start(context.Background()))
+
+	job, _ := queue.Enqueue(context.Background(), "test.slow", testPayload{})
+	<-started
+
+	stopped := make(chan error)
+	go func() { stopped <- pool.Stop(context.Background()) }()
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned before the running job finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	assert.NoError(t, <-stopped)
+	assert.Equal(t, app.JobDone, store.get(job.ID).Status)
+}
+
+func TestPool_Backoff(t *testing.T) {
+	pool := &Pool{backoffBase: time.Second, backoffMax: 5 * time.Second}
+	assert.Equal(t, time.Second, pool.backoff(1))
+	assert.Equal(t, 2*time.Second, pool.backoff(2))
+	assert.Equal(t, 4*time.Second, pool.backoff(3))
+	assert.Equal(t, 5*time.Second, pool.backoff(10))
+}
+
+func TestScheduler_EnqueuesTickOnce(t *testing.T) {
+	store := newMemoryStore()
+	queue := NewQueue(store, &config.AppConfig{})
+	sched, err := newScheduler(Schedule{Name: "nightly", Spec: "0 3 * * *", Kind: "test.echo"}, queue)
+	assert.NoError(t, err)
+
+	tick := time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)
+	assert.NoError(t, sched.enqueue(context.Background(), tick))
+	// второй инстанс на том же срабатывании задачу не дублирует
+	assert.NoError(t, sched.enqueue(context.Background(), tick))
+	assert.Len(t, store.jobs, 1)
+
+	_, err = newScheduler(Schedule{Name: "broken", Spec: "not a cron"}, queue)
+	assert.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"commentTree/internal/config"
+	"context"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+const PurgeKind = "jobs.purge"
+
+// PurgePayload — параметры очистки: удаляются завершенные задачи старше Retention
+type PurgePayload struct {
+	Retention time.Duration `json:"retention"`
+}
+
+// NewPurgeHandler удаляет из jobs выполненные задачи. Упавшие остаются до ручного retry/discard.
+func NewPurgeHandler(store Store) Handler {
+	return NewHandler(PurgeKind, func(ctx context.Context, p PurgePayload) error {
+		n, err := store.PurgeJobs(ctx, time.Now().Add(-p.Retention))
+		if err != nil {
+			return err
+		}
+		wbzlog.Logger.Info().Int64("purged", n).Msg("purged finished jobs")
+		return nil
+	})
+}
+
+func NewPurgeSchedule(cfg *config.AppConfig) Schedule {
+	spec := cfg.JobsConfig.PurgeSchedule
+	if spec == "" {
+		spec = "0 3 * * *"
+	}
+	retention := cfg.JobsConfig.Retention
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+	return Schedule{
+		Name:    "purge-finished-jobs",
+		Spec:    spec,
+		Kind:    PurgeKind,
+		Payload: PurgePayload{Retention: retention},
+	}
+}
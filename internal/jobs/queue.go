@@ -0,0 +1,145 @@
+package jobs
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// Store — хранилище задач
+type Store interface {
+	// EnqueueJob сохраняет задачу; false, если задача с тем же uniqueKey уже есть
+	EnqueueJob(ctx context.Context, job *app.Job, uniqueKey string) (bool, error)
+	// ClaimJobs забирает до limit готовых задач указанных видов, продлевая аренду до lockedUntil.
+	// Задачи, аренда которых истекла (воркер упал), забираются повторно.
+	ClaimJobs(ctx context.Context, kinds []string, limit int, lockedUntil time.Time) ([]app.Job, error)
+	CompleteJob(ctx context.Context, id uuid.UUID) error
+	RetryJob(ctx context.Context, id uuid.UUID, runAt time.Time, lastError string) error
+	FailJob(ctx context.Context, id uuid.UUID, lastError string) error
+	PurgeJobs(ctx context.Context, before time.Time) (int64, error)
+
+	GetJobs(status string, page, pageSize int) ([]app.Job, error)
+	RequeueJob(id string) (*app.Job, error)
+	DeleteJob(id string) error
+}
+
+// Queue ставит задачи в очередь и управляет упавшими задачами
+type Queue struct {
+	store       Store
+	maxAttempts int
+	ready       chan struct{}
+}
+
+// Option настраивает задачу при постановке в очередь
+type Option func(job *app.Job, uniqueKey *string)
+
+// RunAt откладывает выполнение задачи до t
+func RunAt(t time.Time) Option {
+	return func(job *app.Job, _ *string) {
+		job.RunAt = t
+	}
+}
+
+// MaxAttempts переопределяет число попыток из конфига
+func MaxAttempts(n int) Option {
+	return func(job *app.Job, _ *string) {
+		if n > 0 {
+			job.MaxAttempts = n
+		}
+	}
+}
+
+// UniqueKey не дает поставить задачу повторно, пока запись с этим ключом хранится в jobs
+func UniqueKey(key string) Option {
+	return func(_ *app.Job, uniqueKey *string) {
+		*uniqueKey = key
+	}
+}
+
+func NewQueue(store Store, cfg *config.AppConfig) *Queue {
+	maxAttempts := cfg.JobsConfig.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	return &Queue{
+		store:       store,
+		maxAttempts: maxAttempts,
+		ready:       make(chan struct{}, 1),
+	}
+}
+
+// Enqueue ставит задачу вида kind с payload, сериализованным в JSON.
+// Если задача с тем же UniqueKey уже есть, возвращает nil без ошибки.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any, opts ...Option) (*app.Job, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	job := &app.Job{
+		ID:          uuid.New(),
+		Kind:        kind,
+		Payload:     body,
+		Status:      app.JobPending,
+		MaxAttempts: q.maxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	var uniqueKey string
+	for _, opt := range opts {
+		opt(job, &uniqueKey)
+	}
+
+	inserted, err := q.store.EnqueueJob(ctx, job, uniqueKey)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Str("kind", kind).Msg("failed to enqueue job")
+		return nil, err
+	}
+	if !inserted {
+		return nil, nil
+	}
+	if !job.RunAt.After(now) {
+		q.notify()
+	}
+	return job, nil
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// GetFailedJobs возвращает задачи, исчерпавшие попытки, новые сверху
+func (q *Queue) GetFailedJobs(page, pageSize int) ([]app.Job, error) {
+	return q.store.GetJobs(app.JobFailed, page, pageSize)
+}
+
+// RetryJob возвращает упавшую задачу в очередь с обнуленным счетчиком попыток
+func (q *Queue) RetryJob(id string) (*app.Job, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("invalid job id")
+		return nil, err
+	}
+	job, err := q.store.RequeueJob(id)
+	if err != nil {
+		return nil, err
+	}
+	q.notify()
+	return job, nil
+}
+
+// DiscardJob удаляет упавшую задачу
+func (q *Queue) DiscardJob(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("invalid job id")
+		return err
+	}
+	return q.store.DeleteJob(id)
+}
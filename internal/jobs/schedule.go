@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// Schedule — периодическая задача. Spec — cron-выражение из пяти полей или дескриптор вида "@every 1h".
+// Schedule регистрируется в fx-группе "job_schedules".
+type Schedule struct {
+	Name    string
+	Spec    string
+	Kind    string
+	Payload any
+}
+
+type scheduler struct {
+	schedule Schedule
+	spec     cron.Schedule
+	queue    *Queue
+}
+
+func newScheduler(s Schedule, queue *Queue) (*scheduler, error) {
+	spec, err := cron.ParseStandard(s.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %q: %w", s.Name, err)
+	}
+	return &scheduler{schedule: s, spec: spec, queue: queue}, nil
+}
+
+func (s *scheduler) run(ctx context.Context) {
+	for {
+		next := s.spec.Next(time.Now())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := s.enqueue(ctx, next); err != nil && ctx.Err() == nil {
+			wbzlog.Logger.Error().Err(err).Str("schedule", s.schedule.Name).Msg("failed to enqueue scheduled job")
+		}
+	}
+}
+
+// enqueue ставит задачу за срабатывание tick. Ключ уникален для срабатывания,
+// поэтому при нескольких инстансах задача ставится один раз.
+func (s *scheduler) enqueue(ctx context.Context, tick time.Time) error {
+	key := fmt.Sprintf("cron:%s:%d", s.schedule.Name, tick.Unix())
+	_, err := s.queue.Enqueue(ctx, s.schedule.Kind, s.schedule.Payload, UniqueKey(key))
+	return err
+}
@@ -0,0 +1,294 @@
+// Package markdown рендерит ограниченный диалект Markdown комментариев в HTML.
+// Поддерживаются выделение, ссылки, инлайн-код, блоки кода, цитаты и списки;
+// остальная разметка выводится как текст. Результат дополнительно проходит Sanitize.
+package markdown
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Render возвращает безопасный HTML для исходного текста комментария
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"))
+	return Sanitize(b.String())
+}
+
+func renderBlocks(b *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			i++
+		case strings.HasPrefix(trimmed, "```"):
+			i = renderFence(b, lines, i)
+		case strings.HasPrefix(trimmed, ">"):
+			var inner []string
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(t, ">") {
+					break
+				}
+				t = strings.TrimPrefix(t, ">")
+				inner = append(inner, strings.TrimPrefix(t, " "))
+			}
+			b.WriteString("<blockquote>")
+			renderBlocks(b, inner)
+			b.WriteString("</blockquote>")
+		case listMarker(trimmed) != "":
+			i = renderList(b, lines, i)
+		default:
+			var para []string
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if t == "" || strings.HasPrefix(t, "```") || strings.HasPrefix(t, ">") || (len(para) > 0 && listMarker(t) != "") {
+					break
+				}
+				para = append(para, t)
+			}
+			b.WriteString("<p>")
+			for j, p := range para {
+				if j > 0 {
+					b.WriteString("<br>")
+				}
+				renderInline(b, p)
+			}
+			b.WriteString("</p>")
+		}
+	}
+}
+
+// renderFence выводит блок кода между ``` и возвращает индекс строки после него.
+// Незакрытый блок продолжается до конца текста.
+func renderFence(b *strings.Builder, lines []string, start int) int {
+	i := start + 1
+	var code []string
+	for ; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+	b.WriteString("<pre><code>")
+	b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+	b.WriteString("</code></pre>")
+	return i
+}
+
+// listMarker возвращает "ul" или "ol" для строки, начинающейся с маркера списка
+func listMarker(line string) string {
+	if len(line) >= 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return "ul"
+	}
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits < 10 && len(line) > digits+1 && line[digits] == '.' && line[digits+1] == ' ' {
+		return "ol"
+	}
+	return ""
+}
+
+func stripMarker(line string) string {
+	if listMarker(line) == "ul" {
+		return strings.TrimSpace(line[2:])
+	}
+	return strings.TrimSpace(line[strings.IndexByte(line, '.')+1:])
+}
+
+// renderList выводит подряд идущие пункты одного типа; строки с отступом продолжают пункт
+func renderList(b *strings.Builder, lines []string, start int) int {
+	kind := listMarker(strings.TrimSpace(lines[start]))
+	var items []string
+	i := start
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		t := strings.TrimSpace(line)
+		if t == "" {
+			break
+		}
+		if listMarker(t) == kind {
+			items = append(items, stripMarker(t))
+			continue
+		}
+		if line != strings.TrimLeft(line, " \t") && listMarker(t) == "" {
+			items[len(items)-1] += "\n" + t
+			continue
+		}
+		break
+	}
+	b.WriteString("<" + kind + ">")
+	for _, item := range items {
+		b.WriteString("<li>")
+		for j, part := range strings.Split(item, "\n") {
+			if j > 0 {
+				b.WriteString("<br>")
+			}
+			renderInline(b, part)
+		}
+		b.WriteString("</li>")
+	}
+	b.WriteString("</" + kind + ">")
+	return i
+}
+
+func renderInline(b *strings.Builder, s string) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && isPunct(s[i+1]) {
+				b.WriteString(html.EscapeString(s[i+1 : i+2]))
+				i += 2
+				continue
+			}
+		case '`':
+			n := runLength(s[i:], '`')
+			fence := s[i : i+n]
+			if end := strings.Index(s[i+n:], fence); end >= 0 {
+				b.WriteString("<code>")
+				b.WriteString(html.EscapeString(strings.TrimSpace(s[i+n : i+n+end])))
+				b.WriteString("</code>")
+				i += n + end + n
+				continue
+			}
+			b.WriteString(fence)
+			i += n
+			continue
+		case '*', '_':
+			if next, ok := renderEmphasis(b, s, i); ok {
+				i = next
+				continue
+			}
+			n := runLength(s[i:], c)
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '[':
+			if next, ok := renderLink(b, s, i); ok {
+				i = next
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(html.EscapeString(string(r)))
+		i += size
+	}
+}
+
+// renderEmphasis выводит *em* / **strong** (и то же с _), если у разделителя есть пара.
+// Подчеркивание внутри слова (snake_case) выделением не считается.
+func renderEmphasis(b *strings.Builder, s string, i int) (int, bool) {
+	c := s[i]
+	n := runLength(s[i:], c)
+	if n > 2 {
+		return 0, false
+	}
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return 0, false
+	}
+	delim := s[i : i+n]
+	body := s[i+n:]
+	if body == "" || body[0] == ' ' {
+		return 0, false
+	}
+	for from := 0; from < len(body); {
+		end := strings.Index(body[from:], delim)
+		if end < 0 {
+			return 0, false
+		}
+		end += from
+		after := end + n
+		closes := end > 0 && body[end-1] != ' ' &&
+			(after >= len(body) || body[after] != c) &&
+			(c != '_' || after >= len(body) || !isWordByte(body[after]))
+		if closes {
+			tag := "em"
+			if n == 2 {
+				tag = "strong"
+			}
+			b.WriteString("<" + tag + ">")
+			renderInline(b, body[:end])
+			b.WriteString("</" + tag + ">")
+			return i + n + after, true
+		}
+		from = end + runLength(body[end:], c)
+	}
+	return 0, false
+}
+
+// renderLink выводит [text](url); ссылки с неразрешенной схемой остаются текстом
+func renderLink(b *strings.Builder, s string, i int) (int, bool) {
+	depth := 0
+	closing := -1
+	for j := i; j < len(s) && closing < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = j
+			}
+		}
+	}
+	if closing < 0 || closing+1 >= len(s) || s[closing+1] != '(' {
+		return 0, false
+	}
+	end := closingParen(s[closing+2:])
+	if end < 0 {
+		return 0, false
+	}
+	href := strings.TrimSpace(s[closing+2 : closing+2+end])
+	text := s[i+1 : closing]
+	if SafeURL(href) {
+		b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="` + linkRel + `">`)
+		renderInline(b, text)
+		b.WriteString("</a>")
+	} else {
+		renderInline(b, text)
+	}
+	return closing + 2 + end + 1, true
+}
+
+// closingParen ищет ")", закрывающую адрес ссылки, с учетом вложенных скобок
+func closingParen(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func runLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+func isPunct(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("`*_[]()<>#+-.!|~", c) >= 0
+}
+
+func isWordByte(c byte) bool {
+	return c >= utf8.RuneSelf || c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
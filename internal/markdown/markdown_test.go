@@ -0,0 +1,55 @@
+package markdown
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"plain text is escaped", `a < b & "c"`, `<p>a &lt; b &amp; &#34;c&#34;</p>`},
+		{"line breaks inside paragraph", "one\ntwo\n\nthree", "<p>one<br>two</p><p>three</p>"},
+		{"emphasis", "*em* and **strong** and _em_", "<p><em>em</em> and <strong>strong</strong> and <em>em</em></p>"},
+		{"snake_case is not emphasis", "snake_case_name", "<p>snake_case_name</p>"},
+		{"unpaired delimiter", "2 * 3", "<p>2 * 3</p>"},
+		{"inline code is not parsed", "`**x** <b>`", "<p><code>**x** &lt;b&gt;</code></p>"},
+		{"escaped delimiter", `\*not em\*`, "<p>*not em*</p>"},
+		{"link", "[site](https://example.com/?a=1&b=2)",
+			`<p><a href="https://example.com/?a=1&amp;b=2" rel="nofollow ugc">site</a></p>`},
+		{"javascript link is dropped", "[x](javascript:alert(1))", "<p>x</p>"},
+		{"code block", "```go\nif a < b {\n}\n```", "<pre><code>if a &lt; b {\n}</code></pre>"},
+		{"quote", "> quoted *text*\n> more", "<blockquote><p>quoted <em>text</em><br>more</p></blockquote>"},
+		{"unordered list", "- one\n- two", "<ul><li>one</li><li>two</li></ul>"},
+		{"ordered list", "1. one\n2. two", "<ol><li>one</li><li>two</li></ol>"},
+		{"raw html is text", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Render(tt.src))
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"attributes are stripped", `<p onclick="x()" class="a">t</p>`, "<p>t</p>"},
+		{"unknown tags keep text", "<div><span>t</span></div>", "t"},
+		{"script is dropped with content", "<p>a<script>alert(1)</script>b</p>", "<p>ab</p>"},
+		{"unsafe href is removed", `<a href="javascript:x()">t</a>`, `<a href="" rel="nofollow ugc">t</a>`},
+		{"rel is forced", `<a href="/x" rel="me" target="_blank">t</a>`, `<a href="/x" rel="nofollow ugc">t</a>`},
+		{"unclosed tags are closed", "<p><em>t", "<p><em>t</em></p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Sanitize(tt.src))
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package markdown
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"net/url"
+	"strings"
+)
+
+// linkRel добавляется ко всем ссылкам: пользовательский контент не должен передавать вес ссылок
+const linkRel = "nofollow ugc"
+
+// allowedTags — теги, которые может выдать Render
+var allowedTags = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Strong: true, atom.Em: true, atom.Code: true, atom.Pre: true,
+	atom.Blockquote: true, atom.Ul: true, atom.Ol: true, atom.Li: true, atom.A: true,
+}
+
+// droppedTags удаляются вместе с содержимым
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Object: true, atom.Template: true,
+}
+
+// SafeURL разрешает http(s), mailto и относительные ссылки
+func SafeURL(raw string) bool {
+	if raw == "" || strings.ContainsAny(raw, " \t\n\r") {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// Sanitize оставляет в HTML только теги allowedTags без атрибутов, кроме href у ссылок,
+// и проставляет ссылкам rel. Незакрытые теги закрываются, лишние закрывающие отбрасываются.
+func Sanitize(src string) string {
+	var b strings.Builder
+	var open []atom.Atom
+	skip := 0
+	z := html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// io.EOF или обрезанный ввод: дальше разбирать нечего
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[tok.DataAtom] {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if skip > 0 || !allowedTags[tok.DataAtom] {
+				continue
+			}
+			if tok.DataAtom == atom.Br {
+				b.WriteString("<br>")
+				continue
+			}
+			if tok.DataAtom == atom.A {
+				href := ""
+				for _, attr := range tok.Attr {
+					if attr.Key == "href" && SafeURL(attr.Val) {
+						href = attr.Val
+					}
+				}
+				b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="` + linkRel + `">`)
+			} else {
+				b.WriteString("<" + tok.DataAtom.String() + ">")
+			}
+			open = append(open, tok.DataAtom)
+		case html.EndTagToken:
+			if droppedTags[tok.DataAtom] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == tok.DataAtom {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j].String() + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(tok.Data))
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i].String() + ">")
+	}
+	return b.String()
+}
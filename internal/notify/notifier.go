@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"context"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+// Notifier доставляет письмо пользователю
+type Notifier interface {
+	Send(ctx context.Context, msg app.Message) error
+}
+
+// NewNotifier выбирает реализацию по notifications.driver: smtp или log (по умолчанию)
+func NewNotifier(cfg *config.AppConfig) Notifier {
+	if cfg.NotifyConfig.Driver == "smtp" {
+		return NewSMTPNotifier(cfg)
+	}
+	return NewLogNotifier()
+}
+
+// LogNotifier только пишет письма в лог; подходит для разработки
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Send(_ context.Context, msg app.Message) error {
+	wbzlog.Logger.Info().Str("to", msg.To).Str("subject", msg.Subject).Str("body", msg.Body).Msg("notification")
+	return nil
+}
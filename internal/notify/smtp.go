@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"bytes"
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// SMTPNotifier отправляет письма через SMTP-сервер. STARTTLS используется, если сервер его поддерживает,
+// авторизация — только если задан username.
+type SMTPNotifier struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password string
+}
+
+func NewSMTPNotifier(cfg *config.AppConfig) *SMTPNotifier {
+	smtpCfg := cfg.NotifyConfig.SMTP
+	host := smtpCfg.Host
+	if host == "" {
+		host = "localhost"
+	}
+	port := smtpCfg.Port
+	if port <= 0 {
+		port = 1025
+	}
+	from := smtpCfg.From
+	if from == "" {
+		from = "noreply@" + host
+	}
+	return &SMTPNotifier{
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+		from:     from,
+		username: smtpCfg.Username,
+		password: smtpCfg.Password,
+	}
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, msg app.Message) error {
+	from, err := parseAddress(n.from)
+	if err != nil {
+		return fmt.Errorf("smtp from: %w", err)
+	}
+	to, err := parseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("smtp to: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return err
+		}
+	}
+	if n.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(n.compose(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// compose собирает письмо в формате RFC 5322: заголовки, пустая строка, тело с CRLF
+func (n *SMTPNotifier) compose(msg app.Message) []byte {
+	var buf bytes.Buffer
+	writeHeader := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	writeHeader("From", n.from)
+	writeHeader("To", msg.To)
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", "text/plain; charset=utf-8")
+	writeHeader("Content-Transfer-Encoding", "8bit")
+
+	keys := make([]string, 0, len(msg.Headers))
+	for k := range msg.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeHeader(k, msg.Headers[k])
+	}
+	buf.WriteString("\r\n")
+	buf.Write(bytes.ReplaceAll(bytes.ReplaceAll([]byte(msg.Body), []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n")))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// parseAddress возвращает голый адрес из строки вида "Name <addr@host>"
+func parseAddress(s string) (string, error) {
+	addr, err := mail.ParseAddress(s)
+	if err != nil {
+		return "", err
+	}
+	return addr.Address, nil
+}
@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"bufio"
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTPServer принимает одно соединение и запоминает письмо
+func fakeSMTPServer(t *testing.T) (string, int, <-chan receivedMail) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	out := make(chan receivedMail, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var mail receivedMail
+		_ = tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				_ = tp.PrintfLine("250-localhost")
+				_ = tp.PrintfLine("250 8BITMIME")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				mail.from = angleAddr(line)
+				_ = tp.PrintfLine("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				mail.to = append(mail.to, angleAddr(line))
+				_ = tp.PrintfLine("250 OK")
+			case cmd == "DATA":
+				_ = tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+				data, err := io.ReadAll(tp.DotReader())
+				if err != nil {
+					return
+				}
+				mail.data = string(data)
+				_ = tp.PrintfLine("250 OK")
+			case cmd == "QUIT":
+				_ = tp.PrintfLine("221 Bye")
+				out <- mail
+				return
+			default:
+				_ = tp.PrintfLine("502 Command not implemented")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return host, p, out
+}
+
+// angleAddr достает адрес из "MAIL FROM:<addr> BODY=8BITMIME"
+func angleAddr(line string) string {
+	_, rest, _ := strings.Cut(line, "<")
+	addr, _, _ := strings.Cut(rest, ">")
+	return addr
+}
+
+func TestSMTPNotifier_Send(t *testing.T) {
+	host, port, received := fakeSMTPServer(t)
+
+	var cfg config.AppConfig
+	cfg.NotifyConfig.SMTP.Host = host
+	cfg.NotifyConfig.SMTP.Port = port
+	cfg.NotifyConfig.SMTP.From = "commentTree <noreply@example.com>"
+	notifier := NewSMTPNotifier(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := notifier.Send(ctx, app.Message{
+		To:      "alice@example.com",
+		Subject: "Новый ответ",
+		Body:    "bob ответил:\nпривет",
+		Headers: map[string]string{"List-Unsubscribe": "<http://localhost/unsubscribe?token=t>"},
+	})
+	require.NoError(t, err)
+
+	select {
+	case mail := <-received:
+		assert.Equal(t, "noreply@example.com", mail.from)
+		assert.Equal(t, []string{"alice@example.com"}, mail.to)
+
+		msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(mail.data))).ReadMIMEHeader()
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", msg.Get("To"))
+		assert.Equal(t, "<http://localhost/unsubscribe?token=t>", msg.Get("List-Unsubscribe"))
+		assert.Contains(t, mail.data, "bob ответил:\nпривет")
+	case <-time.After(5 * time.Second):
+		t.Fatal("mail was not received")
+	}
+}
@@ -0,0 +1,169 @@
+package outbox
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"context"
+	"fmt"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"sync"
+	"time"
+)
+
+// Store — хранилище outbox. ProcessOutbox передает handle события по порядку вместе с числом прошлых неудач
+// и удаляет из outbox только успешно обработанные; на app.ErrEventUndeliverable событие откладывается как неудачное.
+type Store interface {
+	ProcessOutbox(ctx context.Context, limit int, handle func(event app.CommentEvent, attempts int) error) (int, error)
+}
+
+// Relay вычитывает outbox и раздает события синкам.
+// Доставка — at-least-once: после сбоя событие придет повторно с тем же ID.
+type Relay struct {
+	store       Store
+	sinks       []Sink
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRelay создает релей. Если в конфиге задан список outbox.sinks, подключаются только перечисленные синки.
+func NewRelay(store Store, cfg *config.AppConfig, sinks ...Sink) *Relay {
+	outboxCfg := cfg.OutboxConfig
+	interval := outboxCfg.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	batchSize := outboxCfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	maxAttempts := outboxCfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	return &Relay{
+		store:       store,
+		sinks:       selectSinks(sinks, outboxCfg.Sinks),
+		interval:    interval,
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+func selectSinks(sinks []Sink, names []string) []Sink {
+	if len(names) == 0 {
+		return sinks
+	}
+	enabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		enabled[name] = true
+	}
+	var selected []Sink
+	for _, sink := range sinks {
+		if enabled[sink.Name()] {
+			selected = append(selected, sink)
+		}
+	}
+	return selected
+}
+
+// Wake будит релей, не дожидаясь следующего опроса
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Relay) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go r.run(runCtx)
+	return nil
+}
+
+// Stop дожидается окончания текущей выборки; необработанные события останутся в outbox
+func (r *Relay) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Relay) run(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Drain(ctx); err != nil && ctx.Err() == nil {
+			wbzlog.Logger.Error().Err(err).Msg("outbox relay failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain обрабатывает накопившиеся события, пока выборки заполняются целиком.
+// Останавливается на первом событии, которое не принял какой-либо синк; событие, исчерпавшее
+// outbox.max_attempts попыток, откладывается как неудачное, и обработка идет дальше.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	total := 0
+	for ctx.Err() == nil {
+		var failed error
+		n, err := r.store.ProcessOutbox(ctx, r.batchSize, func(event app.CommentEvent, attempts int) error {
+			err := r.dispatch(ctx, event)
+			if err == nil {
+				return nil
+			}
+			if attempts+1 >= r.maxAttempts {
+				wbzlog.Logger.Error().Err(err).Str("event_id", event.ID.String()).Int("attempts", attempts+1).
+					Msg("Giving up on outbox event")
+				return fmt.Errorf("%w: %v", app.ErrEventUndeliverable, err)
+			}
+			failed = err
+			return err
+		})
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if failed != nil {
+			return total, failed
+		}
+		if n < r.batchSize {
+			return total, nil
+		}
+	}
+	return total, ctx.Err()
+}
+
+func (r *Relay) dispatch(ctx context.Context, event app.CommentEvent) error {
+	for _, sink := range r.sinks {
+		if err := sink.Handle(ctx, event); err != nil {
+			return fmt.Errorf("outbox sink %s: %w", sink.Name(), err)
+		}
+	}
+	return nil
+}
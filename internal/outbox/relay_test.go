@@ -0,0 +1,155 @@
+package outbox
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"commentTree/internal/events"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// memoryStore — outbox в памяти с той же семантикой, что и Postgres.ProcessOutbox
+type memoryStore struct {
+	events   []app.CommentEvent
+	attempts map[uuid.UUID]int
+	failed   []app.CommentEvent
+}
+
+func (s *memoryStore) ProcessOutbox(ctx context.Context, limit int, handle func(event app.CommentEvent, attempts int) error) (int, error) {
+	if s.attempts == nil {
+		s.attempts = make(map[uuid.UUID]int)
+	}
+	processed := 0
+	for len(s.events) > 0 && processed < limit {
+		event := s.events[0]
+		if err := handle(event, s.attempts[event.ID]); err != nil {
+			s.attempts[event.ID]++
+			if !errors.Is(err, app.ErrEventUndeliverable) {
+				return processed, nil
+			}
+			s.failed = append(s.failed, event)
+		}
+		s.events = s.events[1:]
+		processed++
+	}
+	return processed, nil
+}
+
+type recordingSink struct {
+	name   string
+	failOn map[uuid.UUID]bool
+	got    []uuid.UUID
+}
+
+func (s *recordingSink) Name() string {
+	return s.name
+}
+
+func (s *recordingSink) Handle(ctx context.Context, event app.CommentEvent) error {
+	if s.failOn[event.ID] {
+		return errors.New("sink unavailable")
+	}
+	s.got = append(s.got, event.ID)
+	return nil
+}
+
+func newEvents(n int) []app.CommentEvent {
+	events := make([]app.CommentEvent, n)
+	for i := range events {
+		events[i] = app.NewCommentEvent(app.EventCommentCreated, uuid.New(), nil)
+	}
+	return events
+}
+
+func newTestRelay(store Store, sinkNames []string, sinks ...Sink) *Relay {
+	cfg := &config.AppConfig{}
+	cfg.OutboxConfig.BatchSize = 2
+	cfg.OutboxConfig.Sinks = sinkNames
+	return NewRelay(store, cfg, sinks...)
+}
+
+func TestRelay_DrainDeliversInOrder(t *testing.T) {
+	events := newEvents(5)
+	store := &memoryStore{events: events}
+	sink := &recordingSink{name: "bus"}
+	relay := newTestRelay(store, nil, sink)
+
+	n, err := relay.Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Empty(t, store.events)
+	for i, event := range events {
+		assert.Equal(t, event.ID, sink.got[i])
+	}
+}
+
+func TestRelay_DrainStopsOnSinkFailure(t *testing.T) {
+	events := newEvents(3)
+	store := &memoryStore{events: events}
+	sink := &recordingSink{name: "webhook", failOn: map[uuid.UUID]bool{events[1].ID: true}}
+	relay := newTestRelay(store, nil, sink)
+
+	n, err := relay.Drain(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, store.events, 2)
+
+	// после восстановления синка событие доставляется повторно, порядок сохраняется
+	sink.failOn = nil
+	n, err = relay.Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []uuid.UUID{events[0].ID, events[1].ID, events[2].ID}, sink.got)
+}
+
+func TestRelay_DrainSetsAsideEventAfterMaxAttempts(t *testing.T) {
+	events := newEvents(3)
+	store := &memoryStore{events: events}
+	sink := &recordingSink{name: "webhook", failOn: map[uuid.UUID]bool{events[0].ID: true}}
+	relay := newTestRelay(store, nil, sink)
+	relay.maxAttempts = 3
+
+	for i := 0; i < 2; i++ {
+		_, err := relay.Drain(context.Background())
+		assert.Error(t, err)
+		assert.Len(t, store.events, 3)
+		assert.Empty(t, sink.got)
+	}
+
+	// третья неудача исчерпывает попытки: событие откладывается, следующие доставляются
+	_, err := relay.Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, store.events)
+	assert.Equal(t, []app.CommentEvent{events[0]}, store.failed)
+	assert.Equal(t, 3, store.attempts[events[0].ID])
+	assert.Equal(t, []uuid.UUID{events[1].ID, events[2].ID}, sink.got)
+}
+
+func TestRelay_SelectsConfiguredSinks(t *testing.T) {
+	store := &memoryStore{events: newEvents(1)}
+	bus := &recordingSink{name: "bus"}
+	logSink := &recordingSink{name: "log"}
+	relay := newTestRelay(store, []string{"bus"}, bus, logSink)
+
+	_, err := relay.Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, bus.got, 1)
+	assert.Empty(t, logSink.got)
+}
+
+func TestBusSink_SkipsModerationEvents(t *testing.T) {
+	bus := events.NewLocalBus()
+	var got []app.EventType
+	bus.Subscribe(func(event app.CommentEvent) {
+		got = append(got, event.Type)
+	})
+	sink := NewBusSink(bus)
+
+	for _, eventType := range []app.EventType{app.EventCommentHidden, app.EventCommentDeleted} {
+		assert.NoError(t, sink.Handle(context.Background(), app.NewCommentEvent(eventType, uuid.New(), nil)))
+	}
+	assert.Equal(t, []app.EventType{app.EventCommentDeleted}, got)
+}
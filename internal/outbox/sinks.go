@@ -0,0 +1,79 @@
+package outbox
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/events"
+	"context"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+// Sink получает события из outbox. Событие может прийти повторно,
+// поэтому синк должен быть идемпотентным по event.ID.
+type Sink interface {
+	Name() string
+	Handle(ctx context.Context, event app.CommentEvent) error
+}
+
+// LogSink пишет события в лог
+type LogSink struct{}
+
+func NewLogSink() *LogSink {
+	return &LogSink{}
+}
+
+func (s *LogSink) Name() string {
+	return "log"
+}
+
+func (s *LogSink) Handle(ctx context.Context, event app.CommentEvent) error {
+	wbzlog.Logger.Info().
+		Str("id", event.ID.String()).
+		Str("type", string(event.Type)).
+		Str("comment_id", event.CommentID.String()).
+		Int64("revision", event.Revision).
+		Msg("comment event")
+	return nil
+}
+
+// BusSink публикует события в шину, откуда их получают SSE-клиенты всех инстансов.
+// Дубликаты отбрасывает events.Hub. События модерации в публичный поток не попадают.
+type BusSink struct {
+	bus events.Bus
+}
+
+func NewBusSink(bus events.Bus) *BusSink {
+	return &BusSink{bus: bus}
+}
+
+func (s *BusSink) Name() string {
+	return "bus"
+}
+
+func (s *BusSink) Handle(ctx context.Context, event app.CommentEvent) error {
+	if event.Type.Moderation() {
+		return nil
+	}
+	return s.bus.Publish(event)
+}
+
+// WebhookDispatcher ставит событие в доставку подписчикам вебхуков
+type WebhookDispatcher interface {
+	HandleEvent(event app.CommentEvent) error
+}
+
+// WebhookSink передает события подписчикам вебхуков
+type WebhookSink struct {
+	dispatcher WebhookDispatcher
+}
+
+func NewWebhookSink(dispatcher WebhookDispatcher) *WebhookSink {
+	return &WebhookSink{dispatcher: dispatcher}
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Handle(ctx context.Context, event app.CommentEvent) error {
+	return s.dispatcher.HandleEvent(event)
+}
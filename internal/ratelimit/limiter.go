@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rule — корзина токенов: Rate запросов за Per с запасом Burst (0 — равен Rate)
+type Rule struct {
+	Rate  int
+	Per   time.Duration
+	Burst int
+}
+
+// Enabled сообщает, задано ли ограничение
+func (r Rule) Enabled() bool {
+	return r.Rate > 0 && r.Per > 0
+}
+
+func (r Rule) capacity() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return float64(r.Rate)
+}
+
+// perSecond — скорость пополнения корзины
+func (r Rule) perSecond() float64 {
+	return float64(r.Rate) / r.Per.Seconds()
+}
+
+// Result — решение лимитера; RetryAfter заполнен, если запрос не пропущен
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Limiter списывает токен из корзины key
+type Limiter interface {
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take пополняет корзину за прошедшее время и пытается списать один токен
+func (b *bucket) take(rule Rule, now time.Time) Result {
+	capacity := rule.capacity()
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rule.perSecond())
+	}
+	b.updated = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return Result{Allowed: true}
+	}
+	wait := (1 - b.tokens) / rule.perSecond()
+	return Result{RetryAfter: time.Duration(math.Ceil(wait * float64(time.Second)))}
+}
+
+// full сообщает, что корзина успела бы наполниться и ее можно забыть
+func (b *bucket) full(rule Rule, now time.Time) bool {
+	return b.tokens+now.Sub(b.updated).Seconds()*rule.perSecond() >= rule.capacity()
+}
+
+// MemoryLimiter хранит корзины в памяти процесса: ограничения не разделяются между репликами
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	rules   map[string]Rule
+	now     func() time.Time
+	swept   time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		rules:   make(map[string]Rule),
+		now:     time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	if !rule.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rule.capacity(), updated: now}
+		l.buckets[key] = b
+	}
+	l.rules[key] = rule
+	return b.take(rule, now), nil
+}
+
+// sweep раз в минуту удаляет полные корзины, чтобы карта не росла бесконечно
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if b.full(l.rules[key], now) {
+			delete(l.buckets, key)
+			delete(l.rules, key)
+		}
+	}
+}
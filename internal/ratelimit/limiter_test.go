@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(now *time.Time) *MemoryLimiter {
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestMemoryLimiter_BurstThenRefill(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(&now)
+	rule := Rule{Rate: 1, Per: 10 * time.Second, Burst: 2}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		res, err := l.Allow(ctx, "ip:1", rule)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+	}
+	res, _ := l.Allow(ctx, "ip:1", rule)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 10*time.Second, res.RetryAfter)
+
+	other, _ := l.Allow(ctx, "ip:2", rule)
+	assert.True(t, other.Allowed)
+
+	now = now.Add(5 * time.Second)
+	res, _ = l.Allow(ctx, "ip:1", rule)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 5*time.Second, res.RetryAfter)
+
+	now = now.Add(5 * time.Second)
+	res, _ = l.Allow(ctx, "ip:1", rule)
+	assert.True(t, res.Allowed)
+}
+
+func TestMemoryLimiter_SweepsFullBuckets(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(&now)
+	rule := Rule{Rate: 10, Per: time.Second}
+
+	l.Allow(context.Background(), "author:a", rule)
+	now = now.Add(2 * time.Minute)
+	l.Allow(context.Background(), "author:b", rule)
+
+	assert.NotContains(t, l.buckets, "author:a")
+	assert.Contains(t, l.buckets, "author:b")
+}
+
+func TestMemoryLimiter_DisabledRule(t *testing.T) {
+	l := NewMemoryLimiter()
+	res, err := l.Allow(context.Background(), "thread:x", Rule{})
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Empty(t, l.buckets)
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// takeScript — та же корзина токенов, что в bucket.take, но атомарно в Redis.
+// Время берется у Redis, чтобы часы реплик сервиса не расходились.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or capacity
+local updated = tonumber(state[2]) or now
+if now > updated then
+	tokens = math.min(capacity, tokens + (now - updated) * rate)
+end
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate))
+return {allowed, wait}
+`)
+
+// RedisLimiter хранит корзины в Redis, поэтому ограничения общие для всех реплик
+type RedisLimiter struct {
+	client redis.Scripter
+	prefix string
+}
+
+func NewRedisLimiter(client redis.Scripter, prefix string) *RedisLimiter {
+	return &RedisLimiter{client: client, prefix: prefix}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	if !rule.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+	perMs := rule.perSecond() / 1000
+	res, err := takeScript.Run(ctx, l.client, []string{l.prefix + key}, rule.capacity(), perMs).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if res[0] == 1 {
+		return Result{Allowed: true}, nil
+	}
+	return Result{RetryAfter: time.Duration(res[1]) * time.Millisecond}, nil
+}
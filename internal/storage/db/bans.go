@@ -0,0 +1,224 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+const banColumns = `id, kind, authorID, ipHash, rootID, reason, expiresAt, createdAt, revokedAt`
+
+func scanBan(row rowScanner) (*app.Ban, error) {
+	var b app.Ban
+	if err := row.Scan(&b.ID, &b.Kind, &b.AuthorID, &b.IPHash, &b.RootID, &b.Reason, &b.ExpiresAt, &b.CreatedAt, &b.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// writeAudit добавляет запись в журнал аудита в транзакции изменения
+func (p *Postgres) writeAudit(ctx context.Context, tx *sql.Tx, actorID, action string, targetID uuid.UUID, data interface{}) error {
+	entry, err := app.NewAuditEntry(actorID, action, targetID, data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_log (id, actorID, action, targetID, data, createdAt)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, entry.ID, entry.ActorID, entry.Action, entry.TargetID, []byte(entry.Data), entry.CreatedAt)
+	return err
+}
+
+// SaveBan сохраняет бан и запись аудита одной транзакцией
+func (p *Postgres) SaveBan(ban *app.Ban, actorID string) error {
+	ctx := context.Background()
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO bans (id, kind, authorID, ipHash, rootID, reason, expiresAt, createdAt)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (id) DO NOTHING
+		`, ban.ID, ban.Kind, ban.AuthorID, ban.IPHash, ban.RootID, ban.Reason, ban.ExpiresAt, ban.CreatedAt); err != nil {
+			return err
+		}
+		return p.writeAudit(ctx, tx, actorID, app.AuditBanCreated, ban.ID, ban)
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute insert ban query")
+		return err
+	}
+	return nil
+}
+
+// RevokeBan снимает действующий бан и пишет аудит; снятый ранее бан — ErrNotFound
+func (p *Postgres) RevokeBan(id, actorID string) (*app.Ban, error) {
+	ctx := context.Background()
+	var ban *app.Ban
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		b, err := scanBan(tx.QueryRowContext(ctx, `
+			UPDATE bans SET revokedAt = CURRENT_TIMESTAMP
+			WHERE id = $1 AND revokedAt IS NULL
+			RETURNING `+banColumns, id))
+		if errors.Is(err, sql.ErrNoRows) {
+			ban = nil
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ban = b
+		return p.writeAudit(ctx, tx, actorID, app.AuditBanRevoked, ban.ID, ban)
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute revoke ban query")
+		return nil, err
+	}
+	if ban == nil {
+		return nil, app.ErrNotFound
+	}
+	return ban, nil
+}
+
+// GetBans возвращает баны, новые сверху; activeOnly оставляет только действующие
+func (p *Postgres) GetBans(activeOnly bool, page, pageSize int) ([]app.Ban, error) {
+	ctx := context.Background()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		SELECT `+banColumns+`
+		FROM bans
+		WHERE NOT $1 OR (revokedAt IS NULL AND (expiresAt IS NULL OR expiresAt > CURRENT_TIMESTAMP))
+		ORDER BY createdAt DESC, id
+		LIMIT $2 OFFSET $3;
+	`, activeOnly, pageSize, offset)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select bans query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	bans := []app.Ban{}
+	for rows.Next() {
+		b, err := scanBan(rows)
+		if err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan ban row")
+			return nil, err
+		}
+		bans = append(bans, *b)
+	}
+	if err := rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	return bans, nil
+}
+
+// GetActiveBan ищет действующий бан автора или адреса: глобальный или на ветку комментария parentID.
+// Возвращает nil, если бана нет.
+func (p *Postgres) GetActiveBan(authorID, ipHash, parentID string) (*app.Ban, error) {
+	ctx := context.Background()
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		SELECT `+banColumns+`
+		FROM bans
+		WHERE revokedAt IS NULL
+		AND (expiresAt IS NULL OR expiresAt > CURRENT_TIMESTAMP)
+		AND ((kind = 'author' AND authorID::text = $1) OR (kind = 'ip' AND $2 <> '' AND ipHash = $2))
+		AND (rootID IS NULL OR rootID = (SELECT rootID FROM comments WHERE id::text = $3))
+		ORDER BY expiresAt DESC NULLS FIRST
+		LIMIT 1
+	`, authorID, ipHash, parentID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select active ban query")
+		return nil, err
+	}
+	ban, err := scanBan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan ban row")
+		return nil, err
+	}
+	return ban, nil
+}
+
+// GetCommentOrigin возвращает ветку, автора и хэш адреса комментария
+func (p *Postgres) GetCommentOrigin(id string) (*app.CommentOrigin, error) {
+	ctx := context.Background()
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		`SELECT id, rootID, authorID, ipHash FROM comments WHERE id = $1`, id)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select comment origin query")
+		return nil, err
+	}
+	var o app.CommentOrigin
+	if err := row.Scan(&o.CommentID, &o.RootID, &o.AuthorID, &o.IPHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, app.ErrNotFound
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan comment origin")
+		return nil, err
+	}
+	return &o, nil
+}
+
+// GetAuditLog возвращает журнал аудита, новые записи сверху. Пустой targetID не фильтрует.
+func (p *Postgres) GetAuditLog(targetID string, page, pageSize int) ([]app.AuditEntry, error) {
+	ctx := context.Background()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		SELECT id, actorID, action, targetID, data, createdAt
+		FROM audit_log
+		WHERE ($1 = '' OR targetID::text = $1)
+		ORDER BY createdAt DESC, id
+		LIMIT $2 OFFSET $3;
+	`, targetID, pageSize, offset)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select audit log query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	entries := []app.AuditEntry{}
+	for rows.Next() {
+		var e app.AuditEntry
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetID, &data, &e.CreatedAt); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan audit log row")
+			return nil, err
+		}
+		e.Data = data
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// SaveFilterHits сохраняет срабатывания фильтров одной транзакцией
+func (p *Postgres) SaveFilterHits(hits []app.FilterHit) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO filter_hits (id, commentID, authorID, filter, action, reason, excerpt, createdAt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO NOTHING
+	`
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		for _, h := range hits {
+			if _, err := tx.ExecContext(ctx, query,
+				h.ID, h.CommentID, h.AuthorID, h.Filter, h.Action, h.Reason, h.Excerpt, h.CreatedAt,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute insert filter hits query")
+		return err
+	}
+	return nil
+}
+
+// GetFilterHits возвращает срабатывания, новые сверху. Пустые action и commentID не фильтруют.
+func (p *Postgres) GetFilterHits(action, commentID string, page, pageSize int) ([]app.FilterHit, error) {
+	ctx := context.Background()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	query := `
+		SELECT id, commentID, authorID, filter, action, reason, excerpt, createdAt
+		FROM filter_hits
+		WHERE ($1 = '' OR action = $1)
+		AND ($2 = '' OR commentID::text = $2)
+		ORDER BY createdAt DESC, id
+		LIMIT $3 OFFSET $4;
+	`
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		query, action, commentID, pageSize, offset)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select filter hits query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	hits := []app.FilterHit{}
+	for rows.Next() {
+		var h app.FilterHit
+		if err := rows.Scan(&h.ID, &h.CommentID, &h.AuthorID, &h.Filter, &h.Action, &h.Reason, &h.Excerpt, &h.CreatedAt); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan filter hit row")
+			return nil, err
+		}
+		hits = append(hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	return hits, nil
+}
+
+// CountDuplicates считает активные комментарии автора с тем же текстом после since, кроме excludeID
+func (p *Postgres) CountDuplicates(authorID, text string, excludeID *uuid.UUID, since time.Time) (int, error) {
+	ctx := context.Background()
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		SELECT COUNT(*) FROM comments
+		WHERE authorID = $1 AND createdAt > $3 AND status = 'active'
+		AND text = $2
+		AND id IS DISTINCT FROM $4
+	`, authorID, text, since, excludeID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute count duplicates query")
+		return 0, err
+	}
+	var n int
+	if err := row.Scan(&n); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan duplicates count")
+		return 0, err
+	}
+	return n, nil
+}
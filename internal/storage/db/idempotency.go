@@ -0,0 +1,81 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// ReserveIdempotencyKey занимает ключ под запрос requestHash. nil — ключ свободен и теперь занят вызывающим;
+// иначе возвращается существующая запись. Истекший ключ и ключ зависшего запроса (lockedUntil в прошлом)
+// занимаются заново. Без повторов: повтор после успешной вставки вернул бы собственную запись как чужую.
+func (p *Postgres) ReserveIdempotencyKey(scope, key, requestHash string, lockedUntil, expiresAt time.Time) (*app.IdempotencyRecord, error) {
+	ctx := context.Background()
+	var reserved bool
+	err := p.db.Master.QueryRowContext(ctx, `
+		WITH purged AS (
+			DELETE FROM idempotency_keys
+			WHERE expiresAt < CURRENT_TIMESTAMP AND NOT (scope = $1 AND key = $2)
+		)
+		INSERT INTO idempotency_keys (scope, key, requestHash, lockedUntil, expiresAt)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET requestHash = EXCLUDED.requestHash, status = 0, body = NULL, createdAt = CURRENT_TIMESTAMP,
+		    lockedUntil = EXCLUDED.lockedUntil, expiresAt = EXCLUDED.expiresAt
+		WHERE idempotency_keys.expiresAt < CURRENT_TIMESTAMP
+		   OR (idempotency_keys.status = 0 AND idempotency_keys.lockedUntil < CURRENT_TIMESTAMP)
+		RETURNING true
+	`, scope, key, requestHash, lockedUntil, expiresAt).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute reserve idempotency key query")
+		return nil, err
+	}
+
+	record := &app.IdempotencyRecord{Scope: scope, Key: key}
+	var status int
+	var body []byte
+	err = p.db.Master.QueryRowContext(ctx, `
+		SELECT requestHash, status, body FROM idempotency_keys WHERE scope = $1 AND key = $2
+	`, scope, key).Scan(&record.RequestHash, &status, &body)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to select idempotency key")
+		return nil, err
+	}
+	if status != 0 {
+		record.Response = &app.IdempotentResponse{Status: status, Body: body}
+	}
+	return record, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос, занявший ключ
+func (p *Postgres) CompleteIdempotencyKey(scope, key string, response app.IdempotentResponse) error {
+	ctx := context.Background()
+	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		UPDATE idempotency_keys SET status = $3, body = $4 WHERE scope = $1 AND key = $2
+	`, scope, key, response.Status, response.Body)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute complete idempotency key query")
+		return err
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ запроса, завершившегося ошибкой, чтобы клиент мог повторить его
+func (p *Postgres) ReleaseIdempotencyKey(scope, key string) error {
+	ctx := context.Background()
+	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status = 0
+	`, scope, key)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute release idempotency key query")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// fanOutInbox подписывает автора на ветку нового комментария и раскладывает комментарий
+// во входящие всех, кто подписан на его предков (кроме самого автора)
+func (p *Postgres) fanOutInbox(ctx context.Context, tx *sql.Tx, comment *app.Comment) error {
+	if comment.AuthorID != nil {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO thread_subscriptions (userID, commentID, createdAt)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (userID, commentID) DO NOTHING
+		`, comment.AuthorID, comment.ID, comment.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	if comment.ParentID == nil {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, ParentID FROM comments WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.ParentID
+			FROM comments c
+			INNER JOIN ancestors a ON c.id = a.ParentID
+		)
+		INSERT INTO inbox_items (userID, commentID, createdAt)
+		SELECT DISTINCT s.userID, $2::uuid, $3::timestamptz
+		FROM thread_subscriptions s
+		WHERE s.commentID IN (SELECT id FROM ancestors)
+		AND s.userID IS DISTINCT FROM $4
+		ON CONFLICT (userID, commentID) DO NOTHING
+	`, comment.ParentID, comment.ID, comment.CreatedAt, comment.AuthorID)
+	return err
+}
+
+// SubscribeThread подписывает пользователя на ветку с корнем commentID; повторная подписка не ошибка
+func (p *Postgres) SubscribeThread(userID, commentID string) error {
+	ctx := context.Background()
+	res, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		INSERT INTO thread_subscriptions (userID, commentID)
+		SELECT $1, id FROM comments WHERE id = $2 AND status = 'active'
+		ON CONFLICT (userID, commentID) DO UPDATE SET createdAt = thread_subscriptions.createdAt
+	`, userID, commentID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute insert thread subscription query")
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return app.ErrNotFound
+	}
+	return nil
+}
+
+func (p *Postgres) UnsubscribeThread(userID, commentID string) error {
+	ctx := context.Background()
+	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		`DELETE FROM thread_subscriptions WHERE userID = $1 AND commentID = $2`, userID, commentID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute delete thread subscription query")
+		return err
+	}
+	return nil
+}
+
+// GetInbox возвращает входящие пользователя, новые сверху
+func (p *Postgres) GetInbox(userID string, unreadOnly bool, page, pageSize int) ([]app.InboxItem, error) {
+	ctx := context.Background()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	query := `
+		SELECT c.id, c.text, c.html, c.status, c.createdAt, c.parentId, c.authorID, i.createdAt, i.readAt
+		FROM inbox_items i
+		JOIN comments c ON c.id = i.commentID
+		WHERE i.userID = $1
+		AND ($2 = false OR i.readAt IS NULL)
+		ORDER BY i.createdAt DESC, i.commentID
+		LIMIT $3 OFFSET $4;
+	`
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		query, userID, unreadOnly, pageSize, offset)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select inbox query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	items := []app.InboxItem{}
+	for rows.Next() {
+		var item app.InboxItem
+		c := &item.Comment
+		if err := rows.Scan(&c.ID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.ParentID, &c.AuthorID, &item.CreatedAt, &item.ReadAt); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan inbox row")
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+
+	refs := make([]*app.Comment, len(items))
+	for i := range items {
+		refs[i] = &items[i].Comment
+	}
+	if err := p.attachMentions(ctx, refs); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CountUnread считает непрочитанные по частичному индексу inbox_items_unread_idx
+func (p *Postgres) CountUnread(userID string) (int64, error) {
+	ctx := context.Background()
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		`SELECT COUNT(*) FROM inbox_items WHERE userID = $1 AND readAt IS NULL`, userID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute count unread query")
+		return 0, err
+	}
+	var n int64
+	if err := row.Scan(&n); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan unread count")
+		return 0, err
+	}
+	return n, nil
+}
+
+// MarkInboxRead отмечает прочитанными указанные комментарии или, если список пуст, все входящие
+func (p *Postgres) MarkInboxRead(userID string, commentIDs []uuid.UUID, readAt time.Time) (int64, error) {
+	ctx := context.Background()
+	var ids interface{}
+	if len(commentIDs) > 0 {
+		ids = pq.Array(commentIDs)
+	}
+	res, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		UPDATE inbox_items SET readAt = $3
+		WHERE userID = $1 AND readAt IS NULL
+		AND ($2::uuid[] IS NULL OR commentID = ANY($2))
+	`, userID, ids, readAt)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute mark inbox read query")
+		return 0, err
+	}
+	return res.RowsAffected()
+}
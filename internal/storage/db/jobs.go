@@ -0,0 +1,199 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+const jobColumns = `id, kind, payload, status, attempts, maxAttempts, runAt, lastError, createdAt, updatedAt`
+
+func (p *Postgres) EnqueueJob(ctx context.Context, job *app.Job, uniqueKey string) (bool, error) {
+	query := `
+		INSERT INTO jobs (id, kind, payload, status, maxAttempts, runAt, uniqueKey, createdAt, updatedAt)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9)
+		ON CONFLICT (uniqueKey) DO NOTHING
+	`
+	res, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query,
+		job.ID,
+		job.Kind,
+		[]byte(job.Payload),
+		job.Status,
+		job.MaxAttempts,
+		job.RunAt,
+		uniqueKey,
+		job.CreatedAt,
+		job.UpdatedAt,
+	)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute insert job query")
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (p *Postgres) ClaimJobs(ctx context.Context, kinds []string, limit int, lockedUntil time.Time) ([]app.Job, error) {
+	query := `
+		WITH ready AS (
+			SELECT id FROM jobs
+			WHERE kind = ANY($1)
+			AND ((status = 'pending' AND runAt <= now()) OR (status = 'running' AND lockedUntil < now()))
+			ORDER BY runAt
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE jobs j
+		SET status = 'running', attempts = j.attempts + 1, lockedUntil = $3, updatedAt = now()
+		FROM ready
+		WHERE j.id = ready.id
+		RETURNING j.id, j.kind, j.payload, j.status, j.attempts, j.maxAttempts, j.runAt, j.lastError, j.createdAt, j.updatedAt
+	`
+	rows, err := p.db.Master.QueryContext(ctx, query, pq.Array(kinds), limit, lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+func (p *Postgres) CompleteJob(ctx context.Context, id uuid.UUID) error {
+	return p.finishJob(ctx, `
+		UPDATE jobs SET status = 'done', lockedUntil = NULL, lastError = '', updatedAt = now()
+		WHERE id = $1 AND status = 'running'
+	`, id)
+}
+
+func (p *Postgres) RetryJob(ctx context.Context, id uuid.UUID, runAt time.Time, lastError string) error {
+	return p.finishJob(ctx, `
+		UPDATE jobs SET status = 'pending', lockedUntil = NULL, runAt = $2, lastError = $3, updatedAt = now()
+		WHERE id = $1 AND status = 'running'
+	`, id, runAt, lastError)
+}
+
+func (p *Postgres) FailJob(ctx context.Context, id uuid.UUID, lastError string) error {
+	return p.finishJob(ctx, `
+		UPDATE jobs SET status = 'failed', lockedUntil = NULL, lastError = $2, updatedAt = now()
+		WHERE id = $1 AND status = 'running'
+	`, id, lastError)
+}
+
+func (p *Postgres) finishJob(ctx context.Context, query string, args ...interface{}) error {
+	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, args...)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute update job query")
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) PurgeJobs(ctx context.Context, before time.Time) (int64, error) {
+	res, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		`DELETE FROM jobs WHERE status = 'done' AND updatedAt < $1`, before)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute purge jobs query")
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (p *Postgres) GetJobs(status string, page, pageSize int) ([]app.Job, error) {
+	ctx := context.Background()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE status = $1
+		ORDER BY updatedAt DESC
+		LIMIT $2 OFFSET $3;
+	`
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, status, pageSize, offset)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select jobs query")
+		return nil, err
+	}
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan job rows")
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (p *Postgres) RequeueJob(id string) (*app.Job, error) {
+	ctx := context.Background()
+	query := `
+		UPDATE jobs
+		SET status = 'pending', attempts = 0, runAt = now(), lastError = '', updatedAt = now()
+		WHERE id = $1 AND status = 'failed'
+		RETURNING ` + jobColumns
+	// UPDATE ... RETURNING должен идти на мастер, QueryRowWithRetry может выбрать реплику
+	job, err := scanJob(p.db.Master.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, app.ErrNotFound
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute requeue job query")
+		return nil, err
+	}
+	return job, nil
+}
+
+func (p *Postgres) DeleteJob(id string) error {
+	ctx := context.Background()
+	res, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		`DELETE FROM jobs WHERE id = $1 AND status = 'failed'`, id)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute delete job query")
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return app.ErrNotFound
+	}
+	return nil
+}
+
+func scanJob(row rowScanner) (*app.Job, error) {
+	var job app.Job
+	var payload []byte
+	if err := row.Scan(&job.ID, &job.Kind, &payload, &job.Status, &job.Attempts, &job.MaxAttempts,
+		&job.RunAt, &job.LastError, &job.CreatedAt, &job.UpdatedAt); err != nil {
+		return nil, err
+	}
+	job.Payload = payload
+	return &job, nil
+}
+
+func scanJobs(rows *sql.Rows) ([]app.Job, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	var jobs []app.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
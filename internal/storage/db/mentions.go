@@ -0,0 +1,160 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+// saveMentions разбирает упоминания в тексте комментария, сопоставляет их с пользователями
+// и заменяет записи comment_mentions. Результат сохраняется в comment.Mentions.
+func (p *Postgres) saveMentions(ctx context.Context, tx *sql.Tx, comment *app.Comment) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM comment_mentions WHERE commentID = $1`, comment.ID); err != nil {
+		return err
+	}
+	comment.Mentions = nil
+	tokens := app.ParseMentions(comment.Text)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT name, id FROM users WHERE name = ANY($1)`, pq.Array(app.MentionNames(tokens)))
+	if err != nil {
+		return err
+	}
+	users := make(map[string]uuid.UUID)
+	for rows.Next() {
+		var name string
+		var id uuid.UUID
+		if err := rows.Scan(&name, &id); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		users[name] = id
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	comment.Mentions = app.ResolveMentions(tokens, users)
+	for _, m := range comment.Mentions {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO comment_mentions (commentID, userID, spanOffset, spanLength)
+			VALUES ($1, $2, $3, $4)
+		`, comment.ID, m.UserID, m.Offset, m.Length)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachMentions одним запросом загружает упоминания для comments
+func (p *Postgres) attachMentions(ctx context.Context, comments []*app.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*app.Comment, len(comments))
+	ids := make([]uuid.UUID, 0, len(comments))
+	for _, c := range comments {
+		byID[c.ID] = c
+		ids = append(ids, c.ID)
+	}
+
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		SELECT commentID, userID, spanOffset, spanLength
+		FROM comment_mentions
+		WHERE commentID = ANY($1)
+		ORDER BY commentID, spanOffset
+	`, pq.Array(ids))
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select mentions query")
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	for rows.Next() {
+		var commentID uuid.UUID
+		var m app.Mention
+		if err := rows.Scan(&commentID, &m.UserID, &m.Offset, &m.Length); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan mention row")
+			return err
+		}
+		if c, ok := byID[commentID]; ok {
+			c.Mentions = append(c.Mentions, m)
+		}
+	}
+	return rows.Err()
+}
+
+// commentRefs возвращает указатели на элементы среза для attachMentions
+func commentRefs(comments []app.Comment) []*app.Comment {
+	refs := make([]*app.Comment, len(comments))
+	for i := range comments {
+		refs[i] = &comments[i]
+	}
+	return refs
+}
+
+// GetUserMentions возвращает активные комментарии, в которых упомянут пользователь, новые сверху
+func (p *Postgres) GetUserMentions(userID string, page, pageSize int) ([]app.Comment, error) {
+	ctx := context.Background()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	query := `
+		SELECT c.id, c.text, c.html, c.status, c.createdAt, c.parentId, c.authorID
+		FROM comments c
+		WHERE c.status = 'active'
+		AND EXISTS (SELECT 1 FROM comment_mentions m WHERE m.commentID = c.id AND m.userID = $1)
+		ORDER BY c.createdAt DESC
+		LIMIT $2 OFFSET $3;
+	`
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		query, userID, pageSize, offset)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select user mentions query")
+		return nil, err
+	}
+	comments, err := scanComments(rows)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan comment rows")
+		return nil, err
+	}
+	if err := p.attachMentions(ctx, commentRefs(comments)); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func scanComments(rows *sql.Rows) ([]app.Comment, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	comments := []app.Comment{}
+	for rows.Next() {
+		var c app.Comment
+		if err := rows.Scan(&c.ID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.ParentID, &c.AuthorID); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
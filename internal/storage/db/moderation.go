@@ -0,0 +1,294 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// IsPremoderated сообщает, включена ли премодерация в ветке комментария parentID
+func (p *Postgres) IsPremoderated(parentID string) (bool, error) {
+	ctx := context.Background()
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		SELECT COALESCE((
+			SELECT s.premoderation
+			FROM thread_settings s
+			JOIN comments c ON c.rootID = s.rootID
+			WHERE c.id = $1
+		), false)
+	`, parentID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select premoderation query")
+		return false, err
+	}
+	var enabled bool
+	if err := row.Scan(&enabled); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan premoderation flag")
+		return false, err
+	}
+	return enabled, nil
+}
+
+// SetPremoderation включает или выключает премодерацию для всей ветки, в которой находится commentID
+func (p *Postgres) SetPremoderation(commentID string, enabled bool) error {
+	ctx := context.Background()
+	res, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		INSERT INTO thread_settings (rootID, premoderation)
+		SELECT rootID, $2 FROM comments WHERE id = $1 AND status <> 'deleted'
+		ON CONFLICT (rootID) DO UPDATE SET premoderation = EXCLUDED.premoderation, updatedAt = CURRENT_TIMESTAMP
+	`, commentID, enabled)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute upsert thread settings query")
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return app.ErrNotFound
+	}
+	return nil
+}
+
+// GetSlowMode возвращает медленный режим ветки комментария parentID и время, когда автор
+// (или аноним с адреса ipHash) последний раз писал в эту ветку
+func (p *Postgres) GetSlowMode(parentID, authorID, ipHash string) (*app.SlowMode, error) {
+	ctx := context.Background()
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		SELECT s.slowModeSeconds, (
+			SELECT max(c.createdAt) FROM comments c
+			WHERE c.rootID = s.rootID
+			  AND CASE WHEN $2 <> '' THEN c.authorID = NULLIF($2, '')::uuid
+			           ELSE $3 <> '' AND c.authorID IS NULL AND c.ipHash = $3 END
+		)
+		FROM thread_settings s
+		JOIN comments p ON p.rootID = s.rootID
+		WHERE p.id = $1 AND s.slowModeSeconds > 0
+	`, parentID, authorID, ipHash)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select slow mode query")
+		return nil, err
+	}
+	var seconds int
+	var last sql.NullTime
+	if err := row.Scan(&seconds, &last); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &app.SlowMode{}, nil
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan slow mode")
+		return nil, err
+	}
+	mode := &app.SlowMode{Interval: time.Duration(seconds) * time.Second}
+	if last.Valid {
+		mode.LastPostedAt = &last.Time
+	}
+	return mode, nil
+}
+
+// SetSlowMode задает интервал медленного режима для всей ветки, в которой находится commentID
+func (p *Postgres) SetSlowMode(commentID string, interval time.Duration) error {
+	ctx := context.Background()
+	res, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		INSERT INTO thread_settings (rootID, slowModeSeconds)
+		SELECT rootID, $2 FROM comments WHERE id = $1 AND status <> 'deleted'
+		ON CONFLICT (rootID) DO UPDATE SET slowModeSeconds = EXCLUDED.slowModeSeconds, updatedAt = CURRENT_TIMESTAMP
+	`, commentID, int(interval/time.Second))
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute upsert thread settings query")
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return app.ErrNotFound
+	}
+	return nil
+}
+
+// GetModerationQueue возвращает комментарии в состоянии status, старые сверху
+func (p *Postgres) GetModerationQueue(status app.CommentStatus, page, pageSize int) ([]app.Comment, error) {
+	ctx := context.Background()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		SELECT id, text, html, status, createdAt, parentId, authorID, moderationReason
+		FROM comments
+		WHERE status = $1
+		ORDER BY createdAt, id
+		LIMIT $2 OFFSET $3;
+	`, status, pageSize, offset)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select moderation queue query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	comments := []app.Comment{}
+	for rows.Next() {
+		var c app.Comment
+		if err := rows.Scan(&c.ID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.ParentID, &c.AuthorID, &c.ModerationReason); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan moderation queue row")
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	if err := p.attachMentions(ctx, commentRefs(comments)); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// ModerateComments применяет действие к комментариям ids одной транзакцией и возвращает измененные.
+// Комментарии в неподходящем состоянии пропускаются. Одобренные публикуются так же, как новые
+// в SaveComment: журнал изменений, входящие подписчиков, событие created в outbox. Скрытые
+// уходят из входящих и публикуются как deleted.
+func (p *Postgres) ModerateComments(ids []uuid.UUID, action app.ModerationAction, reason string) ([]app.Comment, error) {
+	from, to, err := action.Transition()
+	if err != nil {
+		return nil, err
+	}
+	fromStatuses := make([]string, len(from))
+	for i, s := range from {
+		fromStatuses[i] = string(s)
+	}
+	ctx := context.Background()
+	query := `
+		UPDATE comments SET status = $3, moderationReason = $4, moderatedAt = CURRENT_TIMESTAMP
+		WHERE id = ANY($1) AND status = ANY($2)
+		RETURNING id, text, html, status, createdAt, parentId, authorID, moderationReason, rootID
+	`
+
+	var moderated []app.Comment
+	err = p.withTx(ctx, func(tx *sql.Tx) error {
+		moderated = nil
+		rows, err := tx.QueryContext(ctx, query, pq.Array(ids), pq.Array(fromStatuses), to, reason)
+		if err != nil {
+			return err
+		}
+		var roots []uuid.UUID
+		byRoot := make(map[uuid.UUID][]int)
+		for rows.Next() {
+			var c app.Comment
+			var rootID uuid.UUID
+			if err := rows.Scan(&c.ID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.ParentID, &c.AuthorID, &c.ModerationReason, &rootID); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			if _, ok := byRoot[rootID]; !ok {
+				roots = append(roots, rootID)
+			}
+			byRoot[rootID] = append(byRoot[rootID], len(moderated))
+			moderated = append(moderated, c)
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+
+		for _, rootID := range roots {
+			group := make([]*app.Comment, len(byRoot[rootID]))
+			for i, j := range byRoot[rootID] {
+				group[i] = &moderated[j]
+			}
+			switch to {
+			case app.StatusActive:
+				if err := p.publishApproved(ctx, tx, rootID, group); err != nil {
+					return err
+				}
+			case app.StatusHidden:
+				if err := p.publishHidden(ctx, tx, rootID, group); err != nil {
+					return err
+				}
+			}
+		}
+		for i := range moderated {
+			if err := p.enqueueEvent(ctx, tx, app.NewCommentEvent(action.Event(), moderated[i].ID, &moderated[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute moderate comments query")
+		return nil, err
+	}
+	if len(moderated) > 0 {
+		p.wakeOutbox()
+	}
+	return moderated, nil
+}
+
+// publishApproved проводит одобренные комментарии ветки rootID через журнал, входящие и outbox
+func (p *Postgres) publishApproved(ctx context.Context, tx *sql.Tx, rootID uuid.UUID, comments []*app.Comment) error {
+	commentIDs := idsOf(comments)
+	revision, err := p.recordChanges(ctx, tx, rootID, app.EventCommentCreated, commentIDs...)
+	if err != nil {
+		return err
+	}
+	// Ревизия публикации: для отметок прочтения комментарий новый с момента одобрения
+	if _, err := tx.ExecContext(ctx, `UPDATE comments SET revision = $2 WHERE id = ANY($1)`, pq.Array(commentIDs), revision); err != nil {
+		return err
+	}
+	// Одобрение закрывает жалобы, иначе следующая же жалоба снова скроет комментарий
+	if _, err := tx.ExecContext(ctx, `DELETE FROM comment_reports WHERE commentID = ANY($1)`, pq.Array(commentIDs)); err != nil {
+		return err
+	}
+	for _, c := range comments {
+		// Пересохраняем упоминания: за время модерации могли появиться пользователи с этими именами
+		if err := p.saveMentions(ctx, tx, c); err != nil {
+			return err
+		}
+		if err := p.fanOutInbox(ctx, tx, c); err != nil {
+			return err
+		}
+		event := app.NewCommentEvent(app.EventCommentCreated, c.ID, c)
+		event.Revision = revision
+		if err := p.enqueueEvent(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishHidden убирает скрытые комментарии ветки rootID из входящих и публикует их удаление
+func (p *Postgres) publishHidden(ctx context.Context, tx *sql.Tx, rootID uuid.UUID, comments []*app.Comment) error {
+	commentIDs := idsOf(comments)
+	revision, err := p.recordChanges(ctx, tx, rootID, app.EventCommentDeleted, commentIDs...)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM inbox_items WHERE commentID = ANY($1)`, pq.Array(commentIDs)); err != nil {
+		return err
+	}
+	for _, id := range commentIDs {
+		event := app.NewCommentEvent(app.EventCommentDeleted, id, nil)
+		event.Revision = revision
+		if err := p.enqueueEvent(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func idsOf(comments []*app.Comment) []uuid.UUID {
+	ids := make([]uuid.UUID, len(comments))
+	for i, c := range comments {
+		ids[i] = c.ID
+	}
+	return ids
+}
@@ -0,0 +1,237 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+// moveLockKey — ключ advisory-блокировки, которой сериализуются переносы: проверка на цикл
+// смотрит на предков нового родителя, и параллельный перенос не должен менять их до фиксации
+const moveLockKey = 7_417_295
+
+// MoveComment переносит комментарий id вместе с ответами под parentID (nil — в корень новой ветки).
+// Новый родитель должен быть опубликован, иначе ErrNotFound.
+// Перенос и оба родителя блокируются FOR UPDATE, поэтому ответ, добавляемый параллельно, либо
+// дождется переноса, либо будет перенесен вместе с веткой. rootID поддерева пересчитывается,
+// в журнал изменений старой и новой ветки пишется comment.moved, в аудит — запись о переносе.
+func (p *Postgres) MoveComment(id uuid.UUID, parentID *uuid.UUID, actorID string) (*app.CommentMove, error) {
+	ctx := context.Background()
+	var move *app.CommentMove
+	var rejected error
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		move, err = p.moveInTx(ctx, tx, id, parentID)
+		// Отказ случается до изменений: фиксируем пустую транзакцию и не повторяем ее ретраями
+		rejected = nil
+		if isMoveRejection(err) {
+			rejected = err
+			return nil
+		}
+		if err != nil || move.Noop() {
+			return err
+		}
+		audit := *move
+		audit.Comment = nil
+		return p.writeAudit(ctx, tx, actorID, app.AuditCommentMoved, id, audit)
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to move comment")
+		return nil, err
+	}
+	if rejected != nil {
+		return nil, rejected
+	}
+	if move.Noop() || move.Comment.Status != app.StatusActive {
+		return move, nil
+	}
+	p.wakeOutbox()
+	if err := p.attachMentions(ctx, []*app.Comment{move.Comment}); err != nil {
+		return nil, err
+	}
+	return move, nil
+}
+
+// isMoveRejection — ошибка переноса, вызванная запросом, а не базой
+func isMoveRejection(err error) bool {
+	return errors.Is(err, app.ErrNotFound) || errors.Is(err, app.ErrMoveParentNotFound) || errors.Is(err, app.ErrMoveCycle) ||
+		errors.Is(err, app.ErrAlreadyThread) || errors.Is(err, app.ErrSameThread)
+}
+
+// moveInTx выполняет перенос в транзакции tx: блокировки, проверка на цикл, пересчет rootID,
+// журнал изменений и событие в outbox. Аудит пишет вызывающий: перенос бывает частью разделения или слияния.
+func (p *Postgres) moveInTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, parentID *uuid.UUID) (*app.CommentMove, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, moveLockKey); err != nil {
+		return nil, err
+	}
+
+	locked := []uuid.UUID{id}
+	if parentID != nil {
+		locked = append(locked, *parentID)
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, ParentID, rootID, status FROM comments
+		WHERE id = ANY($1)
+		ORDER BY id
+		FOR UPDATE
+	`, pq.Array(locked))
+	if err != nil {
+		return nil, err
+	}
+	var comment, parent *lockedComment
+	for rows.Next() {
+		var c lockedComment
+		if err := rows.Scan(&c.id, &c.parentID, &c.rootID, &c.status); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		switch {
+		case c.id == id:
+			comment = &c
+		case parentID != nil && c.id == *parentID:
+			parent = &c
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if comment == nil || comment.status == app.StatusDeleted {
+		return nil, app.ErrNotFound
+	}
+	if parentID != nil && (parent == nil || parent.status == app.StatusDeleted) {
+		return nil, app.ErrMoveParentNotFound
+	}
+	// Под скрытым, ожидающим или отклоненным родителем поддерево пропало бы у читателей
+	if parent != nil && parent.status != app.StatusActive {
+		return nil, app.ErrNotFound
+	}
+
+	move := &app.CommentMove{
+		CommentID:    id,
+		FromParentID: comment.parentID,
+		ToParentID:   parentID,
+		FromRootID:   comment.rootID,
+		ToRootID:     id,
+	}
+	if parent != nil {
+		move.ToRootID = parent.rootID
+	}
+	if move.Noop() {
+		return move, nil
+	}
+	if parentID != nil {
+		if *parentID == id {
+			return nil, app.ErrMoveCycle
+		}
+		rows, err := tx.QueryContext(ctx, ancestorsQuery, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		ancestors, err := scanIDs(rows)
+		if err != nil {
+			return nil, err
+		}
+		for _, ancestorID := range ancestors {
+			if ancestorID == id {
+				return nil, app.ErrMoveCycle
+			}
+		}
+	}
+
+	rows, err = tx.QueryContext(ctx, ancestorsQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	if move.PreviousPath, err = scanIDs(rows); err != nil {
+		return nil, err
+	}
+	move.PreviousPath = append(move.PreviousPath, id)
+
+	// Блокируем поддерево, затем пересчитываем его новым запросом: в его снимок попадут
+	// ответы, зафиксированные, пока мы ждали блокировок
+	if _, err := tx.ExecContext(ctx, subtreeQuery+`
+		SELECT id FROM comments WHERE id IN (SELECT id FROM tree) ORDER BY id FOR UPDATE
+	`, id); err != nil {
+		return nil, err
+	}
+	var c app.Comment
+	err = tx.QueryRowContext(ctx, `
+		UPDATE comments SET ParentID = $2
+		WHERE id = $1
+		RETURNING id, text, html, status, createdAt, ParentID, authorID
+	`, id, parentID).Scan(&c.ID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.ParentID, &c.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	move.Comment = &c
+
+	rows, err = tx.QueryContext(ctx, subtreeQuery+`
+		UPDATE comments SET rootID = $2
+		WHERE id IN (SELECT id FROM tree)
+		RETURNING id, status
+	`, id, move.ToRootID)
+	if err != nil {
+		return nil, err
+	}
+	var visible []uuid.UUID
+	for rows.Next() {
+		var movedID uuid.UUID
+		var status app.CommentStatus
+		if err := rows.Scan(&movedID, &status); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		move.Moved++
+		if status == app.StatusActive {
+			visible = append(visible, movedID)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	// Скрытые и ожидающие модерации комментарии в журнал не попадают, как и при создании
+	if c.Status != app.StatusActive {
+		return move, nil
+	}
+	revision, err := p.recordChanges(ctx, tx, move.FromRootID, app.EventCommentMoved, id)
+	if err != nil {
+		return nil, err
+	}
+	if move.ToRootID != move.FromRootID {
+		if revision, err = p.recordChanges(ctx, tx, move.ToRootID, app.EventCommentMoved, visible...); err != nil {
+			return nil, err
+		}
+		// comments.revision сравнивается с отметками прочтения новой ветки, поэтому берется из ее нумерации:
+		// для читателей новой ветки перенесенные комментарии — новые
+		if _, err := tx.ExecContext(ctx, `UPDATE comments SET revision = $2 WHERE id = ANY($1)`, pq.Array(visible), revision); err != nil {
+			return nil, err
+		}
+	}
+	event := app.NewCommentEvent(app.EventCommentMoved, id, &c)
+	event.Revision = revision
+	event.PreviousPath = move.PreviousPath
+	return move, p.enqueueEvent(ctx, tx, event)
+}
+
+// subtreeQuery — CTE tree с комментарием $1 и всеми его ответами
+const subtreeQuery = `
+	WITH RECURSIVE tree AS (
+		SELECT id FROM comments WHERE id = $1
+		UNION ALL
+		SELECT c.id
+		FROM comments c
+		INNER JOIN tree t ON c.ParentID = t.id
+	)
+`
+
+type lockedComment struct {
+	id       uuid.UUID
+	parentID *uuid.UUID
+	rootID   uuid.UUID
+	status   app.CommentStatus
+}
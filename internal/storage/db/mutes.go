@@ -0,0 +1,111 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+// SaveMute добавляет автора в список скрытых пользователя и пишет аудит
+func (p *Postgres) SaveMute(mute *app.Mute) error {
+	ctx := context.Background()
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO user_mutes (userID, mutedID, createdAt)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (userID, mutedID) DO NOTHING
+		`, mute.UserID, mute.MutedID, mute.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		return p.writeAudit(ctx, tx, mute.UserID.String(), app.AuditMuteAdded, mute.MutedID, mute)
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute insert mute query")
+		return err
+	}
+	return nil
+}
+
+// DeleteMute убирает автора из списка скрытых; если его там не было — ErrNotFound
+func (p *Postgres) DeleteMute(userID, mutedID string) error {
+	ctx := context.Background()
+	var removed bool
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		var mute app.Mute
+		err := tx.QueryRowContext(ctx, `
+			DELETE FROM user_mutes WHERE userID = $1 AND mutedID = $2
+			RETURNING userID, mutedID, createdAt
+		`, userID, mutedID).Scan(&mute.UserID, &mute.MutedID, &mute.CreatedAt)
+		removed = err == nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return p.writeAudit(ctx, tx, userID, app.AuditMuteRemoved, mute.MutedID, mute)
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute delete mute query")
+		return err
+	}
+	if !removed {
+		return app.ErrNotFound
+	}
+	return nil
+}
+
+// GetMutes возвращает список скрытых авторов пользователя
+func (p *Postgres) GetMutes(userID string) ([]app.Mute, error) {
+	ctx := context.Background()
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		SELECT userID, mutedID, createdAt FROM user_mutes
+		WHERE userID = $1
+		ORDER BY createdAt DESC
+	`, userID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select mutes query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	mutes := []app.Mute{}
+	for rows.Next() {
+		var m app.Mute
+		if err := rows.Scan(&m.UserID, &m.MutedID, &m.CreatedAt); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan mute row")
+			return nil, err
+		}
+		mutes = append(mutes, m)
+	}
+	if err := rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	return mutes, nil
+}
+
+// GetMutedAuthors возвращает id авторов, скрытых пользователем
+func (p *Postgres) GetMutedAuthors(userID string) ([]uuid.UUID, error) {
+	mutes, err := p.GetMutes(userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(mutes))
+	for i, m := range mutes {
+		ids[i] = m.MutedID
+	}
+	return ids, nil
+}
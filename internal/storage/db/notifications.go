@@ -0,0 +1,111 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// SaveNotification сохраняет уведомление. Если такое уже есть (повтор задачи),
+// в n подставляются id и время отправки существующей записи.
+func (p *Postgres) SaveNotification(n *app.Notification) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO notifications (id, userID, kind, commentID, actorID, excerpt, createdAt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (userID, commentID, kind) DO UPDATE SET kind = EXCLUDED.kind
+		RETURNING id, createdAt, sentAt
+	`
+	err := p.db.Master.QueryRowContext(ctx, query,
+		n.ID,
+		n.UserID,
+		n.Kind,
+		n.CommentID,
+		n.ActorID,
+		n.Excerpt,
+		n.CreatedAt,
+	).Scan(&n.ID, &n.CreatedAt, &n.SentAt)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute upsert notification query")
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) MarkNotificationsSent(ids []uuid.UUID, sentAt time.Time) error {
+	ctx := context.Background()
+	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		`UPDATE notifications SET sentAt = $2 WHERE id = ANY($1) AND sentAt IS NULL`, pq.Array(ids), sentAt)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute mark notifications sent query")
+		return err
+	}
+	return nil
+}
+
+// GetDigestRecipients возвращает пользователей в режиме digest, у которых есть неотправленные уведомления
+func (p *Postgres) GetDigestRecipients() ([]app.User, error) {
+	ctx := context.Background()
+	query := `
+		SELECT u.id, u.name, u.email, u.notifyMode, u.createdAt
+		FROM users u
+		WHERE u.notifyMode = 'digest'
+		AND EXISTS (SELECT 1 FROM notifications n WHERE n.userID = u.id AND n.sentAt IS NULL)
+	`
+	rows, err := p.db.Master.QueryContext(ctx, query)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select digest recipients query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	var users []app.User
+	for rows.Next() {
+		var u app.User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.NotifyMode, &u.CreatedAt); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan user row")
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (p *Postgres) GetUnsentNotifications(userID uuid.UUID) ([]app.Notification, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, userID, kind, commentID, actorID, excerpt, createdAt, sentAt
+		FROM notifications
+		WHERE userID = $1 AND sentAt IS NULL
+		ORDER BY createdAt
+	`
+	rows, err := p.db.Master.QueryContext(ctx, query, userID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select notifications query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	var notifications []app.Notification
+	for rows.Next() {
+		var n app.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.CommentID, &n.ActorID, &n.Excerpt, &n.CreatedAt, &n.SentAt); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan notification row")
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
@@ -0,0 +1,195 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"commentTree/internal/events"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"sync"
+	"time"
+)
+
+// NOTIFY ограничивает payload 8000 байтами
+const maxNotifyPayload = 7900
+
+// NotifyBus — шина событий поверх Postgres LISTEN/NOTIFY, доставляет события всем инстансам
+type NotifyBus struct {
+	postgres     *Postgres
+	channel      string
+	minReconnect time.Duration
+	maxReconnect time.Duration
+	pingInterval time.Duration
+
+	mu       sync.RWMutex
+	handlers []events.Handler
+
+	listener *pq.Listener
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewNotifyBus(p *Postgres, cfg *config.AppConfig) *NotifyBus {
+	busCfg := cfg.EventBusConfig
+	channel := busCfg.Channel
+	if channel == "" {
+		channel = "comment_events"
+	}
+	minReconnect := busCfg.MinReconnect
+	if minReconnect <= 0 {
+		minReconnect = time.Second
+	}
+	maxReconnect := busCfg.MaxReconnect
+	if maxReconnect <= 0 {
+		maxReconnect = time.Minute
+	}
+	if maxReconnect < minReconnect {
+		maxReconnect = minReconnect
+	}
+	pingInterval := busCfg.PingInterval
+	if pingInterval <= 0 {
+		pingInterval = 90 * time.Second
+	}
+	return &NotifyBus{
+		postgres:     p,
+		channel:      channel,
+		minReconnect: minReconnect,
+		maxReconnect: maxReconnect,
+		pingInterval: pingInterval,
+	}
+}
+
+func (b *NotifyBus) Publish(event app.CommentEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		// текст не помещается в NOTIFY — подписчики догрузят комментарий по id
+		event.Comment = nil
+		if payload, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	p := b.postgres
+	_, err = p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		`SELECT pg_notify($1, $2)`, b.channel, string(payload))
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to publish event via pg_notify")
+		return err
+	}
+	return nil
+}
+
+func (b *NotifyBus) Subscribe(handler events.Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *NotifyBus) Start(ctx context.Context) error {
+	b.listener = pq.NewListener(b.postgres.dsn, b.minReconnect, b.maxReconnect, b.onListenerEvent)
+	b.done = make(chan struct{})
+	b.wg.Add(1)
+	go b.run()
+	return nil
+}
+
+func (b *NotifyBus) Stop(ctx context.Context) error {
+	close(b.done)
+	err := b.listener.Close()
+	b.wg.Wait()
+	return err
+}
+
+func (b *NotifyBus) run() {
+	defer b.wg.Done()
+
+	// Listen блокируется до установки соединения, поэтому не выполняем его в OnStart
+	if !b.listen() {
+		return
+	}
+
+	ticker := time.NewTicker(b.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case n := <-b.listener.Notify:
+			if n == nil {
+				// после переподключения часть уведомлений могла потеряться
+				wbzlog.Logger.Warn().Str("channel", b.channel).Msg("Event listener reconnected")
+				continue
+			}
+			b.dispatch(n.Extra)
+		case <-ticker.C:
+			go func() {
+				if err := b.listener.Ping(); err != nil {
+					wbzlog.Logger.Debug().Err(err).Msg("Event listener ping failed")
+				}
+			}()
+		}
+	}
+}
+
+func (b *NotifyBus) dispatch(payload string) {
+	var event app.CommentEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to decode event payload")
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(event)
+	}
+}
+
+func (b *NotifyBus) onListenerEvent(ev pq.ListenerEventType, err error) {
+	switch ev {
+	case pq.ListenerEventConnected:
+		wbzlog.Logger.Info().Str("channel", b.channel).Msg("Event listener connected")
+	case pq.ListenerEventDisconnected:
+		wbzlog.Logger.Error().Err(err).Str("channel", b.channel).Msg("Event listener disconnected")
+	case pq.ListenerEventConnectionAttemptFailed:
+		wbzlog.Logger.Error().Err(err).Str("channel", b.channel).Msg("Event listener connection attempt failed")
+	}
+}
+
+// listen подписывается на канал, повторяя неудачные попытки с паузой от minReconnect до maxReconnect:
+// без подписки инстанс не получал бы события других реплик до перезапуска. false — шина остановлена.
+func (b *NotifyBus) listen() bool {
+	delay := b.minReconnect
+	for {
+		err := b.listener.Listen(b.channel)
+		if err == nil || errors.Is(err, pq.ErrChannelAlreadyOpen) {
+			return true
+		}
+		select {
+		case <-b.done:
+			return false
+		default:
+		}
+		wbzlog.Logger.Error().Err(err).Str("channel", b.channel).Dur("retry_in", delay).Msg("Failed to listen event channel")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-b.done:
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+		delay *= 2
+		if delay > b.maxReconnect {
+			delay = b.maxReconnect
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+// SetOutboxWaker задает функцию, которую Postgres вызывает после коммита события в outbox,
+// чтобы релей забрал его без ожидания следующего опроса
+func (p *Postgres) SetOutboxWaker(wake func()) {
+	p.wake = wake
+}
+
+func (p *Postgres) wakeOutbox() {
+	if p.wake != nil {
+		p.wake()
+	}
+}
+
+// enqueueEvent записывает событие в outbox в той же транзакции, что и изменение комментария
+func (p *Postgres) enqueueEvent(ctx context.Context, tx *sql.Tx, event app.CommentEvent) error {
+	rows, err := tx.QueryContext(ctx, ancestorsQuery, event.CommentID)
+	if err != nil {
+		return err
+	}
+	ancestors, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+	event.Path = append(ancestors, event.CommentID)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbox (eventID, eventType, payload)
+		VALUES ($1, $2, $3)
+	`, event.ID, string(event.Type), payload)
+	return err
+}
+
+type outboxEntry struct {
+	seq      int64
+	payload  []byte
+	attempts int
+}
+
+// ProcessOutbox блокирует до limit самых старых недоставленных событий (занятые другими инстансами пропускаются),
+// передает их handle по порядку вместе с числом прошлых неудач и удаляет обработанные. На первой ошибке обработка
+// останавливается, чтобы не нарушить порядок: событие остается в outbox и будет повторено. Если handle вернул
+// app.ErrEventUndeliverable, событие помечается failedAt и обработка продолжается со следующего.
+//
+// Блокировки держатся, пока работают синки: так другой инстанс не возьмет те же события и не обгонит их
+// более поздними, а событие уходит из outbox в одной транзакции с отметкой о доставке. Синки поэтому должны
+// быть быстрыми — вебхуки лишь ставят задачи в очередь jobs, а шина отправляет NOTIFY.
+func (p *Postgres) ProcessOutbox(ctx context.Context, limit int, handle func(event app.CommentEvent, attempts int) error) (int, error) {
+	tx, err := p.db.Master.BeginTx(ctx, nil)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to begin outbox transaction")
+		return 0, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to rollback outbox transaction")
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT seq, payload, attempts
+		FROM outbox
+		WHERE failedAt IS NULL
+		ORDER BY seq
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select outbox query")
+		return 0, err
+	}
+	var entries []outboxEntry
+	for rows.Next() {
+		var e outboxEntry
+		if err := rows.Scan(&e.seq, &e.payload, &e.attempts); err != nil {
+			_ = rows.Close()
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan outbox row")
+			return 0, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Close(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		return 0, err
+	}
+
+	processed := 0
+	for _, e := range entries {
+		var event app.CommentEvent
+		if err := json.Unmarshal(e.payload, &event); err != nil {
+			// такое событие не обработается никогда — не блокируем им очередь
+			wbzlog.Logger.Error().Err(err).Int64("seq", e.seq).Msg("Dropping malformed outbox event")
+		} else if err := handle(event, e.attempts); err != nil {
+			undeliverable := errors.Is(err, app.ErrEventUndeliverable)
+			_, uerr := tx.ExecContext(ctx, `
+				UPDATE outbox
+				SET attempts = attempts + 1, lastError = $2,
+				    failedAt = CASE WHEN $3 THEN now() ELSE failedAt END
+				WHERE seq = $1
+			`, e.seq, err.Error(), undeliverable)
+			if uerr != nil {
+				wbzlog.Logger.Error().Err(uerr).Msg("Failed to record outbox failure")
+				return processed, uerr
+			}
+			if !undeliverable {
+				break
+			}
+			processed++
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM outbox WHERE seq = $1`, e.seq); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to execute delete outbox query")
+			return processed, err
+		}
+		processed++
+	}
+
+	if err := tx.Commit(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to commit outbox transaction")
+		return 0, err
+	}
+	return processed, nil
+}
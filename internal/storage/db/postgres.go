@@ -5,7 +5,10 @@ import (
 	"commentTree/internal/config"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	wbdb "github.com/wb-go/wbf/dbpg"
 	"github.com/wb-go/wbf/retry"
 	wbzlog "github.com/wb-go/wbf/zlog"
@@ -13,8 +16,10 @@ import (
 )
 
 type Postgres struct {
-	db  *wbdb.DB
-	cfg *config.RetrysConfig
+	db   *wbdb.DB
+	cfg  *config.RetrysConfig
+	dsn  string
+	wake func()
 }
 
 func NewPostgres(cfg *config.AppConfig) (*Postgres, error) {
@@ -49,7 +54,7 @@ func NewPostgres(cfg *config.AppConfig) (*Postgres, error) {
 		return nil, err
 	}
 	wbzlog.Logger.Info().Msg("Connected to Postgres")
-	return &Postgres{db: db, cfg: &cfg.RetrysConfig}, nil
+	return &Postgres{db: db, cfg: &cfg.RetrysConfig, dsn: masterDSN}, nil
 }
 
 func (p *Postgres) Close() error {
@@ -70,31 +75,93 @@ func (p *Postgres) Close() error {
 	return nil
 }
 
-func (p *Postgres) SaveComment(text, parentID string) (*app.Comment, error) {
+// SaveComment сохраняет комментарий в состоянии status. Комментарий, ожидающий модерации,
+// не попадает в журнал изменений, входящие и outbox: это происходит при одобрении (ModerateComments).
+func (p *Postgres) SaveComment(text, parentID, authorID, ipHash string, status app.CommentStatus) (*app.Comment, error) {
 
 	comment, err := app.NewComment(parentID, text)
 	if err != nil {
 		return nil, err
 	}
+	if err := comment.SetAuthor(authorID); err != nil {
+		return nil, err
+	}
+	comment.Status = status
+	published := status == app.StatusActive
 	ctx := context.Background()
 	query := `
-		INSERT INTO comments (id, text, createdAt, ParentID, status)
-		VALUES($1, $2, $3, $4, 'active')
+		INSERT INTO comments (id, text, html, createdAt, ParentID, status, rootID, authorID, revision, ipHash)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
-	_, err = p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query,
-		comment.ID,
-		comment.Text,
-		comment.CreatedAt,
-		comment.ParentID,
-	)
+	err = p.withTx(ctx, func(tx *sql.Tx) error {
+		// id создается до повторов withTx: если прошлая попытка зафиксировалась, но ответ потерялся,
+		// комментарий уже сохранен, и вставлять его второй раз не нужно
+		var saved bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1)`, comment.ID).Scan(&saved); err != nil {
+			return err
+		}
+		if saved {
+			return nil
+		}
+		// FOR SHARE: перенос родителя (MoveComment) ждет вставки ответа, а вставка — переноса
+		var rootID uuid.UUID
+		err := tx.QueryRowContext(ctx, `SELECT COALESCE((SELECT rootID FROM comments WHERE id = $1 FOR SHARE), $2)`,
+			comment.ParentID, comment.ID).Scan(&rootID)
+		if err != nil {
+			return err
+		}
+		var revision int64
+		if published {
+			revision, err = p.recordChanges(ctx, tx, rootID, app.EventCommentCreated, comment.ID)
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, query,
+			comment.ID,
+			comment.Text,
+			comment.HTML,
+			comment.CreatedAt,
+			comment.ParentID,
+			comment.Status,
+			rootID,
+			comment.AuthorID,
+			revision,
+			ipHash,
+		); err != nil {
+			return err
+		}
+		if err := p.saveMentions(ctx, tx, comment); err != nil {
+			return err
+		}
+		if !published {
+			return nil
+		}
+		if err := p.fanOutInbox(ctx, tx, comment); err != nil {
+			return err
+		}
+		event := app.NewCommentEvent(app.EventCommentCreated, comment.ID, comment)
+		event.Revision = revision
+		return p.enqueueEvent(ctx, tx, event)
+	})
 	if err != nil {
 		wbzlog.Logger.Error().Err(err).Msg("Failed to execute insert comment query")
 		return nil, err
 	}
+	if published {
+		p.wakeOutbox()
+	}
 	return comment, nil
 }
 
-func (p *Postgres) GetComments(parentId string, sortAsc string, page, pageSize int) ([]app.Comment, error) {
+// visibleTo — условие видимости комментария alias для зрителя из параметра $n:
+// активные видны всем, ожидающие модерации — только автору
+func visibleTo(alias string, n int) string {
+	return fmt.Sprintf("(%[1]s.status = 'active' OR (%[1]s.status = 'pending' AND %[1]s.authorID::text = $%[2]d))", alias, n)
+}
+
+// GetComments возвращает комментарии, видимые viewerID (пустой — анонимный зритель)
+func (p *Postgres) GetComments(parentId, viewerID string, sortAsc string, page, pageSize int) ([]app.Comment, error) {
 	ctx := context.Background()
 
 	if page < 1 {
@@ -114,31 +181,32 @@ func (p *Postgres) GetComments(parentId string, sortAsc string, page, pageSize i
 	var args []interface{}
 
 	if parentId == "" {
-		// Все активные комментарии
+		// Все видимые комментарии, кроме корней веток, слитых в другие
 		query = fmt.Sprintf(`
-			SELECT id, text, createdAt, parentId 
-			FROM comments 
-			WHERE status = 'active'
+			SELECT id, text, html, status, createdAt, parentId, authorID
+			FROM comments
+			WHERE %s
+			AND NOT EXISTS (SELECT 1 FROM thread_redirects r WHERE r.fromRootID = comments.id)
 			ORDER BY createdAt %s
 			LIMIT $1 OFFSET $2;
-		`, order)
-		args = []interface{}{pageSize, offset}
+		`, visibleTo("comments", 3), order)
+		args = []interface{}{pageSize, offset, viewerID}
 	} else {
 		// Рекурсивное дерево с указанным parentId
 		query = fmt.Sprintf(`
 			WITH RECURSIVE tree AS (
-				SELECT * FROM comments WHERE id = $1
+				SELECT * FROM comments WHERE id = $1 AND %s
 				UNION ALL
 				SELECT c.*
 				FROM comments c
 				INNER JOIN tree t ON c.ParentID = t.id
-				WHERE c.status = 'active'
+				WHERE %s
 			)
-			SELECT id, text, createdAt, parentId FROM tree
+			SELECT id, text, html, status, createdAt, parentId, authorID FROM tree
 			ORDER BY createdAt %s
 			LIMIT $2 OFFSET $3;
-		`, order)
-		args = []interface{}{parentId, pageSize, offset}
+		`, visibleTo("comments", 4), visibleTo("c", 4), order)
+		args = []interface{}{parentId, pageSize, offset, viewerID}
 	}
 
 	rows, err := p.db.QueryWithRetry(
@@ -160,7 +228,7 @@ func (p *Postgres) GetComments(parentId string, sortAsc string, page, pageSize i
 	var comments []app.Comment
 	for rows.Next() {
 		var c app.Comment
-		err := rows.Scan(&c.ID, &c.Text, &c.CreatedAt, &c.ParentID)
+		err := rows.Scan(&c.ID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.ParentID, &c.AuthorID)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return nil, nil
@@ -175,6 +243,9 @@ func (p *Postgres) GetComments(parentId string, sortAsc string, page, pageSize i
 		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
 		return nil, err
 	}
+	if err := p.attachMentions(ctx, commentRefs(comments)); err != nil {
+		return nil, err
+	}
 	return comments, nil
 }
 
@@ -191,18 +262,62 @@ func (p *Postgres) DeleteComments(id string) error {
 		)
 		UPDATE comments
 		SET status = 'deleted'
-		WHERE id IN (SELECT id FROM tree);
+		WHERE id IN (SELECT id FROM tree) AND status <> 'deleted'
+		RETURNING id, rootID;
 	`
 
-	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, id)
+	commentID, err := uuid.Parse(id)
 	if err != nil {
-		wbzlog.Logger.Error().Err(err).Msg("Failed to execute insert comment query")
+		wbzlog.Logger.Error().Err(err).Msg("invalid comment id")
 		return err
 	}
+
+	enqueued := false
+	err = p.withTx(ctx, func(tx *sql.Tx) error {
+		enqueued = false
+		rows, err := tx.QueryContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		var rootID uuid.UUID
+		var deleted []uuid.UUID
+		for rows.Next() {
+			var commentID uuid.UUID
+			if err := rows.Scan(&commentID, &rootID); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			deleted = append(deleted, commentID)
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		if len(deleted) == 0 {
+			return nil
+		}
+		revision, err := p.recordChanges(ctx, tx, rootID, app.EventCommentDeleted, deleted...)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM inbox_items WHERE commentID = ANY($1)`, pq.Array(deleted)); err != nil {
+			return err
+		}
+		event := app.NewCommentEvent(app.EventCommentDeleted, commentID, nil)
+		event.Revision = revision
+		enqueued = true
+		return p.enqueueEvent(ctx, tx, event)
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute delete comments query")
+		return err
+	}
+	if enqueued {
+		p.wakeOutbox()
+	}
 	return nil
 }
 
-func (p *Postgres) SearchComments(text string, sortAsc string, page, pageSize int) ([]app.Comment, error) {
+func (p *Postgres) SearchComments(text, viewerID string, sortAsc string, page, pageSize int) ([]app.Comment, error) {
 	ctx := context.Background()
 
 	if page < 1 {
@@ -219,17 +334,17 @@ func (p *Postgres) SearchComments(text string, sortAsc string, page, pageSize in
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, text, createdAt, parentId
+		SELECT id, text, html, status, createdAt, parentId, authorID
 		FROM comments
-		WHERE status = 'active'
+		WHERE %s
 		AND to_tsvector('simple', text) @@ plainto_tsquery('simple', $1)
 		ORDER BY createdAt %s
 		LIMIT $2 OFFSET $3;
-	`, order)
+	`, visibleTo("comments", 4), order)
 
 	rows, err := p.db.QueryWithRetry(ctx,
 		retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
-		query, text, pageSize, offset)
+		query, text, pageSize, offset, viewerID)
 	if err != nil {
 		wbzlog.Logger.Error().Err(err).Msg("Failed to execute search comments query")
 		return nil, err
@@ -243,7 +358,7 @@ func (p *Postgres) SearchComments(text string, sortAsc string, page, pageSize in
 	var comments []app.Comment
 	for rows.Next() {
 		var c app.Comment
-		err := rows.Scan(&c.ID, &c.Text, &c.CreatedAt, &c.ParentID)
+		err := rows.Scan(&c.ID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.ParentID, &c.AuthorID)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return nil, nil
@@ -258,6 +373,242 @@ func (p *Postgres) SearchComments(text string, sortAsc string, page, pageSize in
 		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
 		return nil, err
 	}
+	if err := p.attachMentions(ctx, commentRefs(comments)); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (p *Postgres) GetComment(id string) (*app.Comment, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, text, html, status, createdAt, parentId, authorID
+		FROM comments
+		WHERE id = $1 AND status IN ('active', 'pending')
+	`
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, id)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select comment query")
+		return nil, err
+	}
+	var c app.Comment
+	if err := row.Scan(&c.ID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.ParentID, &c.AuthorID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, app.ErrNotFound
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan comment row")
+		return nil, err
+	}
+	if err := p.attachMentions(ctx, []*app.Comment{&c}); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// UpdateComment заменяет текст активного или ожидающего модерации комментария, пересчитывает HTML
+// и упоминания. Правка ожидающего комментария не публикуется: он еще не виден другим.
+func (p *Postgres) UpdateComment(id, text string) (*app.Comment, error) {
+	if text == "" {
+		return nil, errors.New("text is empty")
+	}
+	var edited app.Comment
+	edited.SetText(text)
+	ctx := context.Background()
+	query := `
+		UPDATE comments SET text = $2, html = $3
+		WHERE id = $1 AND status IN ('active', 'pending')
+		RETURNING id, text, html, status, createdAt, parentId, authorID, rootID
+	`
+	var comment app.Comment
+	found := false
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		comment = app.Comment{}
+		var rootID uuid.UUID
+		err := tx.QueryRowContext(ctx, query, id, edited.Text, edited.HTML).
+			Scan(&comment.ID, &comment.Text, &comment.HTML, &comment.Status, &comment.CreatedAt, &comment.ParentID, &comment.AuthorID, &rootID)
+		// Отсутствие комментария не повторяем ретраями транзакции
+		found = !errors.Is(err, sql.ErrNoRows)
+		if err != nil {
+			if !found {
+				return nil
+			}
+			return err
+		}
+		if err := p.saveMentions(ctx, tx, &comment); err != nil {
+			return err
+		}
+		if comment.Status != app.StatusActive {
+			return nil
+		}
+		revision, err := p.recordChanges(ctx, tx, rootID, app.EventCommentEdited, comment.ID)
+		if err != nil {
+			return err
+		}
+		event := app.NewCommentEvent(app.EventCommentEdited, comment.ID, &comment)
+		event.Revision = revision
+		return p.enqueueEvent(ctx, tx, event)
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute update comment query")
+		return nil, err
+	}
+	if !found {
+		return nil, app.ErrNotFound
+	}
+	if comment.Status == app.StatusActive {
+		p.wakeOutbox()
+	}
+	return &comment, nil
+}
+
+// ancestorsQuery выбирает предков комментария $1 от корня к родителю
+const ancestorsQuery = `
+	WITH RECURSIVE ancestors AS (
+		SELECT id, ParentID, 0 AS depth FROM comments WHERE id = $1
+		UNION ALL
+		SELECT c.id, c.ParentID, a.depth + 1
+		FROM comments c
+		INNER JOIN ancestors a ON c.id = a.ParentID
+	)
+	SELECT id FROM ancestors
+	WHERE depth > 0
+	ORDER BY depth DESC;
+`
+
+func (p *Postgres) GetAncestorIDs(id string) ([]uuid.UUID, error) {
+	ctx := context.Background()
 
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, ancestorsQuery, id)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select ancestors query")
+		return nil, err
+	}
+	ids, err := scanIDs(rows)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan ancestor rows")
+		return nil, err
+	}
+	return ids, nil
+}
+
+func scanIDs(rows *sql.Rows) ([]uuid.UUID, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetCommentPath возвращает цепочку от корня ветки до комментария id. ErrNotFound, если комментарий
+// или кто-то из его предков не виден viewerID: в дереве такой комментарий тоже недостижим.
+func (p *Postgres) GetCommentPath(id, viewerID string) ([]app.Comment, error) {
+	ctx := context.Background()
+	query := fmt.Sprintf(`
+		WITH RECURSIVE path AS (
+			SELECT c.id, c.text, c.html, c.status, c.createdAt, c.ParentID, c.authorID, 0 AS depth
+			FROM comments c WHERE c.id = $1
+			UNION ALL
+			SELECT c.id, c.text, c.html, c.status, c.createdAt, c.ParentID, c.authorID, p.depth + 1
+			FROM comments c
+			INNER JOIN path p ON c.id = p.ParentID
+		)
+		SELECT id, text, html, status, createdAt, ParentID, authorID, %s
+		FROM path
+		ORDER BY depth DESC
+	`, visibleTo("path", 2))
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, id, viewerID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select comment path query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	var path []app.Comment
+	for rows.Next() {
+		var c app.Comment
+		var visible bool
+		if err := rows.Scan(&c.ID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.ParentID, &c.AuthorID, &visible); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan comment path row")
+			return nil, err
+		}
+		if !visible {
+			return nil, app.ErrNotFound
+		}
+		path = append(path, c)
+	}
+	if err := rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	if len(path) == 0 {
+		return nil, app.ErrNotFound
+	}
+	if err := p.attachMentions(ctx, commentRefs(path)); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// GetDescendants возвращает видимых viewerID потомков комментария id не глубже depth уровней,
+// не больше limit, ближние уровни первыми
+func (p *Postgres) GetDescendants(id, viewerID string, depth, limit int) ([]app.Comment, error) {
+	ctx := context.Background()
+	query := fmt.Sprintf(`
+		WITH RECURSIVE tree AS (
+			SELECT c.id, c.text, c.html, c.status, c.createdAt, c.ParentID, c.authorID, 1 AS depth
+			FROM comments c WHERE c.ParentID = $1 AND %s
+			UNION ALL
+			SELECT c.id, c.text, c.html, c.status, c.createdAt, c.ParentID, c.authorID, t.depth + 1
+			FROM comments c
+			INNER JOIN tree t ON c.ParentID = t.id
+			WHERE t.depth < $3 AND %s
+		)
+		SELECT id, text, html, status, createdAt, ParentID, authorID
+		FROM tree
+		ORDER BY depth, createdAt
+		LIMIT $4
+	`, visibleTo("c", 2), visibleTo("c", 2))
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, id, viewerID, depth, limit)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select descendants query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	var comments []app.Comment
+	for rows.Next() {
+		var c app.Comment
+		if err := rows.Scan(&c.ID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.ParentID, &c.AuthorID); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan comment row")
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	if err := p.attachMentions(ctx, commentRefs(comments)); err != nil {
+		return nil, err
+	}
 	return comments, nil
 }
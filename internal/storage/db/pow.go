@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// CountRecentAnonymous считает анонимные комментарии, созданные после since
+func (p *Postgres) CountRecentAnonymous(since time.Time) (int, error) {
+	ctx := context.Background()
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		`SELECT COUNT(*) FROM comments WHERE authorID IS NULL AND createdAt > $1`, since)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute count anonymous comments query")
+		return 0, err
+	}
+	var n int
+	if err := row.Scan(&n); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan anonymous comments count")
+		return 0, err
+	}
+	return n, nil
+}
+
+// RedeemChallenge отмечает задачу использованной; false — ее уже предъявляли.
+// Без повторов: повтор после успешной вставки выглядел бы как повторное предъявление.
+// Заодно удаляются записи о задачах с истекшим сроком.
+func (p *Postgres) RedeemChallenge(id string, expiresAt time.Time) (bool, error) {
+	ctx := context.Background()
+	res, err := p.db.Master.ExecContext(ctx, `
+		WITH purged AS (
+			DELETE FROM pow_redeemed WHERE expiresAt < CURRENT_TIMESTAMP
+		)
+		INSERT INTO pow_redeemed (id, expiresAt) VALUES ($1, $2)
+		ON CONFLICT (id) DO NOTHING
+	`, id, expiresAt)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute redeem challenge query")
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
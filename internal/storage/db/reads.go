@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+// GetUnreadIDs возвращает из commentIDs комментарии, созданные в ветке после отметки пользователя.
+// Свои комментарии новыми не считаются.
+func (p *Postgres) GetUnreadIDs(userID string, commentIDs []uuid.UUID) ([]uuid.UUID, error) {
+	ctx := context.Background()
+	query := `
+		SELECT c.id
+		FROM comments c
+		LEFT JOIN thread_reads r ON r.userID = $1 AND r.rootID = c.rootID
+		WHERE c.id = ANY($2)
+		AND c.revision > COALESCE(r.revision, 0)
+		AND c.authorID IS DISTINCT FROM $1
+	`
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		query, userID, pq.Array(commentIDs))
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select unread comments query")
+		return nil, err
+	}
+	ids, err := scanIDs(rows)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan unread comment rows")
+		return nil, err
+	}
+	return ids, nil
+}
+
+// MarkThreadsRead сдвигает отметки пользователя в ветках commentIDs до самой поздней из показанных ревизий.
+// Отметка только растет, поэтому чтение старой страницы не делает новые комментарии прочитанными обратно.
+func (p *Postgres) MarkThreadsRead(userID string, commentIDs []uuid.UUID) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO thread_reads (userID, rootID, revision)
+		SELECT $1, rootID, MAX(revision)
+		FROM comments
+		WHERE id = ANY($2)
+		GROUP BY rootID
+		ON CONFLICT (userID, rootID) DO UPDATE
+		SET revision = GREATEST(thread_reads.revision, EXCLUDED.revision), updatedAt = CURRENT_TIMESTAMP
+	`
+	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		query, userID, pq.Array(commentIDs))
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute mark threads read query")
+		return err
+	}
+	return nil
+}
+
+// GetFirstUnread возвращает самый ранний непрочитанный комментарий в ветке parentID или nil
+func (p *Postgres) GetFirstUnread(userID, parentID string) (*uuid.UUID, error) {
+	ctx := context.Background()
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT id FROM comments WHERE id = $2
+			UNION ALL
+			SELECT c.id
+			FROM comments c
+			INNER JOIN tree t ON c.ParentID = t.id
+			WHERE c.status = 'active'
+		)
+		SELECT c.id
+		FROM comments c
+		LEFT JOIN thread_reads r ON r.userID = $1 AND r.rootID = c.rootID
+		WHERE c.id IN (SELECT id FROM tree)
+		AND c.status = 'active'
+		AND c.revision > COALESCE(r.revision, 0)
+		AND c.authorID IS DISTINCT FROM $1
+		ORDER BY c.revision
+		LIMIT 1;
+	`
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		query, userID, parentID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select first unread query")
+		return nil, err
+	}
+	ids, err := scanIDs(rows)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan first unread row")
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return &ids[0], nil
+}
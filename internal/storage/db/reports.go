@@ -0,0 +1,148 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+// weightArrays раскладывает веса категорий в параллельные массивы для unnest
+func weightArrays(weights app.ReportWeights) (interface{}, interface{}) {
+	reasons := make([]string, 0, len(weights))
+	values := make([]int64, 0, len(weights))
+	for reason, weight := range weights {
+		reasons = append(reasons, string(reason))
+		values = append(values, int64(weight))
+	}
+	return pq.Array(reasons), pq.Array(values)
+}
+
+// SaveReport сохраняет жалобу (повторная жалоба того же пользователя меняет категорию)
+// и возвращает взвешенную сумму жалоб на комментарий от пользователей с уровнем не ниже minLevel
+func (p *Postgres) SaveReport(report *app.Report, weights app.ReportWeights, minLevel app.TrustLevel) (int, error) {
+	ctx := context.Background()
+	reasons, values := weightArrays(weights)
+	var score int
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO comment_reports (id, commentID, reporterID, reason, reporterLevel, createdAt)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (commentID, reporterID) DO UPDATE SET reason = EXCLUDED.reason, reporterLevel = EXCLUDED.reporterLevel
+			RETURNING id, createdAt
+		`, report.ID, report.CommentID, report.ReporterID, report.Reason, report.ReporterLevel, report.CreatedAt).Scan(&report.ID, &report.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(w.weight), 0)
+			FROM comment_reports r
+			JOIN unnest($2::text[], $3::int[]) AS w(reason, weight) ON w.reason = r.reason
+			WHERE r.commentID = $1 AND r.reporterLevel >= $4
+		`, report.CommentID, reasons, values, minLevel).Scan(&score)
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute upsert report query")
+		return 0, err
+	}
+	return score, nil
+}
+
+// GetReportQueue возвращает комментарии с жалобами, самые серьезные сверху
+func (p *Postgres) GetReportQueue(weights app.ReportWeights, page, pageSize int) ([]app.ReportSummary, error) {
+	ctx := context.Background()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	reasons, values := weightArrays(weights)
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		WITH counts AS (
+			SELECT commentID, reason, COUNT(*) AS n, MAX(createdAt) AS lastReportedAt
+			FROM comment_reports
+			GROUP BY commentID, reason
+		), agg AS (
+			SELECT c.commentID,
+				SUM(c.n * COALESCE(w.weight, 0))::bigint AS score,
+				SUM(c.n)::bigint AS reports,
+				MAX(c.lastReportedAt) AS lastReportedAt,
+				json_object_agg(c.reason, c.n) AS reasons
+			FROM counts c
+			LEFT JOIN unnest($1::text[], $2::int[]) AS w(reason, weight) ON w.reason = c.reason
+			GROUP BY c.commentID
+		)
+		SELECT c.id, c.text, c.html, c.status, c.createdAt, c.parentId, c.authorID, c.moderationReason,
+			a.score, a.reports, a.lastReportedAt, a.reasons
+		FROM agg a
+		JOIN comments c ON c.id = a.commentID
+		WHERE c.status IN ('active', 'hidden')
+		ORDER BY a.score DESC, a.lastReportedAt DESC, c.id
+		LIMIT $3 OFFSET $4;
+	`, reasons, values, pageSize, offset)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select report queue query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	summaries := []app.ReportSummary{}
+	for rows.Next() {
+		var s app.ReportSummary
+		var rawReasons []byte
+		c := &s.Comment
+		if err := rows.Scan(&c.ID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.ParentID, &c.AuthorID, &c.ModerationReason,
+			&s.Score, &s.Reports, &s.LastReportedAt, &rawReasons); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan report queue row")
+			return nil, err
+		}
+		if err := json.Unmarshal(rawReasons, &s.Reasons); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to decode report reasons")
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+	if err := rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// SaveVote сохраняет голос (0 удаляет его) и возвращает сумму голосов за комментарий
+func (p *Postgres) SaveVote(vote app.Vote) (int, error) {
+	ctx := context.Background()
+	var score int
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if vote.Value == 0 {
+			_, err = tx.ExecContext(ctx, `DELETE FROM comment_votes WHERE commentID = $1 AND voterID = $2`, vote.CommentID, vote.VoterID)
+		} else {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO comment_votes (commentID, voterID, value)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (commentID, voterID) DO UPDATE SET value = EXCLUDED.value
+			`, vote.CommentID, vote.VoterID, vote.Value)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(value), 0) FROM comment_votes WHERE commentID = $1`, vote.CommentID).Scan(&score)
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute save vote query")
+		return 0, err
+	}
+	return score, nil
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// withTx выполняет fn в транзакции на мастере, повторяя всю транзакцию по стратегии ретраев
+func (p *Postgres) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return retry.Do(func() error {
+		tx, err := p.db.Master.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				wbzlog.Logger.Error().Err(rbErr).Msg("Failed to rollback transaction")
+			}
+			return err
+		}
+		return tx.Commit()
+	}, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs})
+}
+
+// recordChanges увеличивает ревизию ветки и записывает изменения комментариев в журнал.
+// Строка thread_revisions блокируется до конца транзакции, поэтому ревизии ветки монотонны.
+func (p *Postgres) recordChanges(ctx context.Context, tx *sql.Tx, rootID uuid.UUID, changeType app.EventType, commentIDs ...uuid.UUID) (int64, error) {
+	var revision int64
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO thread_revisions (rootID, revision)
+		VALUES ($1, 1)
+		ON CONFLICT (rootID) DO UPDATE SET revision = thread_revisions.revision + 1
+		RETURNING revision
+	`, rootID).Scan(&revision)
+	if err != nil {
+		return 0, err
+	}
+	for _, commentID := range commentIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO comment_changes (rootID, revision, commentID, type)
+			VALUES ($1, $2, $3, $4)
+		`, rootID, revision, commentID, string(changeType))
+		if err != nil {
+			return 0, err
+		}
+	}
+	return revision, nil
+}
+
+// GetChanges возвращает изменения в ветке parentId после ревизии since и текущую ревизию ветки.
+// Если after не нулевое, дополнительно отбрасываются изменения, сделанные до этого момента.
+// Переносы возвращаются по всей ветке: ушедший из parentId комментарий в нее уже не входит.
+func (p *Postgres) GetChanges(parentId string, since int64, after time.Time) (*app.ChangeSet, error) {
+	ctx := context.Background()
+	strategy := retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}
+
+	row, err := p.db.QueryRowWithRetry(ctx, strategy, `
+		SELECT COALESCE(tr.revision, 0)
+		FROM comments c
+		LEFT JOIN thread_revisions tr ON tr.rootID = c.rootID
+		WHERE c.id = $1
+	`, parentId)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select thread revision query")
+		return nil, err
+	}
+	set := &app.ChangeSet{Changes: []app.CommentChange{}}
+	if err := row.Scan(&set.Revision); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, app.ErrNotFound
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan thread revision")
+		return nil, err
+	}
+	if set.Revision <= since {
+		return set, nil
+	}
+
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT id FROM comments WHERE id = $1
+			UNION ALL
+			SELECT c.id
+			FROM comments c
+			INNER JOIN tree t ON c.ParentID = t.id
+		)
+		SELECT ch.revision, ch.type, ch.commentID, c.ParentID, c.text, c.html, c.status, c.createdAt, c.authorID, ch.createdAt
+		FROM comment_changes ch
+		JOIN comments c ON c.id = ch.commentID
+		WHERE ch.rootID = (SELECT rootID FROM comments WHERE id = $1)
+		AND ch.revision > $2
+		AND ($3::timestamptz IS NULL OR ch.createdAt > $3)
+		AND (ch.commentID IN (SELECT id FROM tree) OR ch.type = 'comment.moved')
+		ORDER BY ch.revision, ch.commentID;
+	`
+	var afterArg interface{}
+	if !after.IsZero() {
+		afterArg = after
+	}
+	rows, err := p.db.QueryWithRetry(ctx, strategy, query, parentId, since, afterArg)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select comment changes query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	for rows.Next() {
+		var ch app.CommentChange
+		var c app.Comment
+		var changeType string
+		if err := rows.Scan(&ch.Revision, &changeType, &ch.CommentID, &ch.ParentID, &c.Text, &c.HTML, &c.Status, &c.CreatedAt, &c.AuthorID, &ch.ChangedAt); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan comment change row")
+			return nil, err
+		}
+		ch.Type = app.EventType(changeType)
+		if ch.Type != app.EventCommentDeleted {
+			c.ID = ch.CommentID
+			c.ParentID = ch.ParentID
+			ch.Comment = &c
+		}
+		set.Changes = append(set.Changes, ch)
+	}
+	if err = rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	var changed []*app.Comment
+	for _, ch := range set.Changes {
+		if ch.Comment != nil {
+			changed = append(changed, ch.Comment)
+		}
+	}
+	if err := p.attachMentions(ctx, changed); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
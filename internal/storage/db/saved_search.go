@@ -0,0 +1,194 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+func (p *Postgres) SaveSavedSearch(search *app.SavedSearch) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO saved_searches (id, query, parentID, webhookURL, createdAt)
+		VALUES($1, $2, $3, $4, $5)
+	`
+	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query,
+		search.ID,
+		search.Query,
+		search.ParentID,
+		search.WebhookURL,
+		search.CreatedAt,
+	)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute insert saved search query")
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) UpdateSavedSearch(search *app.SavedSearch) error {
+	ctx := context.Background()
+	query := `
+		UPDATE saved_searches
+		SET query = $2, parentID = $3, webhookURL = $4
+		WHERE id = $1
+	`
+	res, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query,
+		search.ID,
+		search.Query,
+		search.ParentID,
+		search.WebhookURL,
+	)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute update saved search query")
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return app.ErrNotFound
+	}
+	return nil
+}
+
+func (p *Postgres) DeleteSavedSearch(id string) error {
+	ctx := context.Background()
+	query := `DELETE FROM saved_searches WHERE id = $1`
+	res, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, id)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute delete saved search query")
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return app.ErrNotFound
+	}
+	return nil
+}
+
+func (p *Postgres) GetSavedSearch(id string) (*app.SavedSearch, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, query, parentID, webhookURL, createdAt
+		FROM saved_searches
+		WHERE id = $1
+	`
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, app.ErrNotFound
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select saved search query")
+		return nil, err
+	}
+	var s app.SavedSearch
+	if err := row.Scan(&s.ID, &s.Query, &s.ParentID, &s.WebhookURL, &s.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, app.ErrNotFound
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan saved search row")
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (p *Postgres) GetSavedSearches() ([]app.SavedSearch, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, query, parentID, webhookURL, createdAt
+		FROM saved_searches
+		ORDER BY createdAt ASC
+	`
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select saved searches query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	var searches []app.SavedSearch
+	for rows.Next() {
+		var s app.SavedSearch
+		if err := rows.Scan(&s.ID, &s.Query, &s.ParentID, &s.WebhookURL, &s.CreatedAt); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan saved search row")
+			return nil, err
+		}
+		searches = append(searches, s)
+	}
+	if err = rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	return searches, nil
+}
+
+func (p *Postgres) SaveAlert(alert *app.Alert) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO alerts (id, searchID, commentID, createdAt)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT (searchID, commentID) DO NOTHING
+	`
+	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query,
+		alert.ID,
+		alert.SearchID,
+		alert.CommentID,
+		alert.CreatedAt,
+	)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute insert alert query")
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) GetAlerts(searchID string, page, pageSize int) ([]app.Alert, error) {
+	ctx := context.Background()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	query := `
+		SELECT a.id, a.searchID, a.commentID, s.query, c.text, a.createdAt
+		FROM alerts a
+		JOIN saved_searches s ON s.id = a.searchID
+		JOIN comments c ON c.id = a.commentID
+		WHERE ($1 = '' OR a.searchID::text = $1)
+		ORDER BY a.createdAt DESC
+		LIMIT $2 OFFSET $3;
+	`
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, searchID, pageSize, offset)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select alerts query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	var alerts []app.Alert
+	for rows.Next() {
+		var a app.Alert
+		if err := rows.Scan(&a.ID, &a.SearchID, &a.CommentID, &a.Query, &a.Text, &a.CreatedAt); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan alert row")
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	if err = rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	return alerts, nil
+}
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+// SplitThread выносит комментарий id с ответами в отдельную ветку и оставляет на его месте
+// служебный комментарий со ссылкой на нее. Все выполняется одной транзакцией с блокировками MoveComment.
+func (p *Postgres) SplitThread(id uuid.UUID, actorID string) (*app.ThreadSplit, error) {
+	ctx := context.Background()
+	var split *app.ThreadSplit
+	var rejected error
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		split, err = p.splitInTx(ctx, tx, id, actorID)
+		rejected = nil
+		if isMoveRejection(err) {
+			rejected = err
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to split thread")
+		return nil, err
+	}
+	if rejected != nil {
+		return nil, rejected
+	}
+	p.wakeOutbox()
+	return split, nil
+}
+
+func (p *Postgres) splitInTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, actorID string) (*app.ThreadSplit, error) {
+	move, err := p.moveInTx(ctx, tx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if move.Noop() {
+		return nil, app.ErrAlreadyThread
+	}
+
+	link := app.NewThreadLink(*move.FromParentID, id)
+	revision, err := p.recordChanges(ctx, tx, move.FromRootID, app.EventCommentCreated, link.ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO comments (id, text, html, createdAt, ParentID, status, rootID, revision)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, link.ID, link.Text, link.HTML, link.CreatedAt, link.ParentID, link.Status, move.FromRootID, revision); err != nil {
+		return nil, err
+	}
+	event := app.NewCommentEvent(app.EventCommentCreated, link.ID, link)
+	event.Revision = revision
+	if err := p.enqueueEvent(ctx, tx, event); err != nil {
+		return nil, err
+	}
+
+	split := &app.ThreadSplit{
+		ThreadID:   id,
+		FromRootID: move.FromRootID,
+		Moved:      move.Moved,
+		Link:       link,
+		Move:       *move,
+	}
+	if err := p.writeAudit(ctx, tx, actorID, app.AuditThreadSplit, id, split); err != nil {
+		return nil, err
+	}
+	return split, nil
+}
+
+// MergeThreads переносит ответы корня ветки sourceID под корень ветки targetID (обе задаются любым
+// своим комментарием) и перенаправляет ключ исходной ветки на целевую. Корень исходной ветки удаляется
+// с событием comment.deleted, удаленные ответы остаются на месте.
+func (p *Postgres) MergeThreads(sourceID, targetID uuid.UUID, actorID string) (*app.ThreadMerge, error) {
+	ctx := context.Background()
+	var merge *app.ThreadMerge
+	var rejected error
+	err := p.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		merge, err = p.mergeInTx(ctx, tx, sourceID, targetID, actorID)
+		rejected = nil
+		if isMoveRejection(err) {
+			rejected = err
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to merge threads")
+		return nil, err
+	}
+	if rejected != nil {
+		return nil, rejected
+	}
+	if len(merge.Moves) > 0 || merge.RootDeleted {
+		p.wakeOutbox()
+	}
+	return merge, nil
+}
+
+func (p *Postgres) mergeInTx(ctx context.Context, tx *sql.Tx, sourceID, targetID uuid.UUID, actorID string) (*app.ThreadMerge, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, moveLockKey); err != nil {
+		return nil, err
+	}
+	fromRootID, err := p.resolveThread(ctx, tx, sourceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, app.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	toRootID, err := p.resolveThread(ctx, tx, targetID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, app.ErrMoveParentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fromRootID == toRootID {
+		return nil, app.ErrSameThread
+	}
+	// Ответы переносятся только под опубликованный корень, как и при обычном переносе
+	var toStatus app.CommentStatus
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM comments WHERE id = $1`, toRootID).Scan(&toStatus); err != nil {
+		return nil, err
+	}
+	if toStatus != app.StatusActive {
+		return nil, app.ErrMoveParentNotFound
+	}
+
+	// Корень блокируется до выбора ответов: новый ответ ему дождется слияния (SaveComment берет FOR SHARE)
+	var fromStatus app.CommentStatus
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM comments WHERE id = $1 FOR UPDATE`, fromRootID).Scan(&fromStatus); err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM comments
+		WHERE ParentID = $1 AND status <> 'deleted'
+		ORDER BY createdAt, id
+	`, fromRootID)
+	if err != nil {
+		return nil, err
+	}
+	roots, err := scanIDs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	merge := &app.ThreadMerge{FromRootID: fromRootID, ToRootID: toRootID, Roots: []uuid.UUID{}}
+	for _, rootID := range roots {
+		move, err := p.moveInTx(ctx, tx, rootID, &toRootID)
+		if err != nil {
+			return nil, err
+		}
+		merge.Roots = append(merge.Roots, rootID)
+		merge.Moved += move.Moved
+		merge.Moves = append(merge.Moves, *move)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE thread_redirects SET toRootID = $2 WHERE toRootID = $1`, fromRootID, toRootID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO thread_redirects (fromRootID, toRootID)
+		VALUES ($1, $2)
+		ON CONFLICT (fromRootID) DO UPDATE SET toRootID = EXCLUDED.toRootID, createdAt = CURRENT_TIMESTAMP
+	`, fromRootID, toRootID); err != nil {
+		return nil, err
+	}
+
+	// Пустой корень удаляется: его ключ уже ведет в целевую ветку, а сам он без ответов остался бы
+	// видимым сиротой в ленте и по постоянной ссылке
+	if _, err := tx.ExecContext(ctx, `UPDATE comments SET status = 'deleted' WHERE id = $1`, fromRootID); err != nil {
+		return nil, err
+	}
+	if fromStatus == app.StatusActive {
+		if err := p.publishHidden(ctx, tx, fromRootID, []*app.Comment{{ID: fromRootID}}); err != nil {
+			return nil, err
+		}
+		merge.RootDeleted = true
+	}
+	if err := p.writeAudit(ctx, tx, actorID, app.AuditThreadMerged, fromRootID, merge); err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+// resolveThread возвращает корень ветки комментария id с учетом перенаправлений; sql.ErrNoRows, если его нет
+func (p *Postgres) resolveThread(ctx context.Context, tx *sql.Tx, id uuid.UUID) (uuid.UUID, error) {
+	var rootID uuid.UUID
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(r.toRootID, c.rootID)
+		FROM comments c
+		LEFT JOIN thread_redirects r ON r.fromRootID = c.rootID
+		WHERE c.id = $1 AND c.status <> 'deleted'
+	`, id).Scan(&rootID)
+	return rootID, err
+}
+
+// GetThreadRedirect возвращает ветку, в которую слита ветка rootID, или nil
+func (p *Postgres) GetThreadRedirect(rootID string) (*uuid.UUID, error) {
+	ctx := context.Background()
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		SELECT toRootID FROM thread_redirects WHERE fromRootID = $1
+	`, rootID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select thread redirect query")
+		return nil, err
+	}
+	var toRootID uuid.UUID
+	if err := row.Scan(&toRootID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan thread redirect")
+		return nil, err
+	}
+	return &toRootID, nil
+}
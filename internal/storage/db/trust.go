@@ -0,0 +1,59 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+// GetTrustSignals собирает показатели автора для уровня доверия: возраст аккаунта,
+// опубликованные комментарии и жалобы на комментарии, которые остались скрытыми
+func (p *Postgres) GetTrustSignals(userID string) (*app.TrustSignals, error) {
+	ctx := context.Background()
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, `
+		SELECT u.id, u.createdAt,
+			(SELECT COUNT(*) FROM comments c WHERE c.authorID = u.id AND c.status = 'active'),
+			(SELECT COUNT(*) FROM comment_reports r
+				JOIN comments c ON c.id = r.commentID
+				WHERE c.authorID = u.id AND c.status = 'hidden'),
+			(SELECT COALESCE(SUM(v.value), 0) FROM comment_votes v
+				JOIN comments c ON c.id = v.commentID
+				WHERE c.authorID = u.id AND c.status = 'active')
+		FROM users u
+		WHERE u.id = $1
+	`, userID)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select trust signals query")
+		return nil, err
+	}
+	var s app.TrustSignals
+	if err := row.Scan(&s.UserID, &s.MemberSince, &s.ApprovedComments, &s.UpheldReports, &s.VotesReceived); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, app.ErrNotFound
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan trust signals")
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CountRecentComments считает комментарии автора после since во всех состояниях
+func (p *Postgres) CountRecentComments(authorID string, since time.Time) (int, error) {
+	ctx := context.Background()
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		`SELECT COUNT(*) FROM comments WHERE authorID = $1 AND createdAt > $2`, authorID, since)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute count recent comments query")
+		return 0, err
+	}
+	var n int
+	if err := row.Scan(&n); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan recent comments count")
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+func (p *Postgres) SaveUser(u *app.User) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO users (id, name, email, notifyMode, role, createdAt, tokenHash)
+		VALUES($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query,
+		u.ID,
+		u.Name,
+		u.Email,
+		u.NotifyMode,
+		u.Role,
+		u.CreatedAt,
+		u.TokenHash,
+	)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute insert user query")
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) GetUser(id string) (*app.User, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, name, email, notifyMode, role, createdAt
+		FROM users
+		WHERE id = $1
+	`
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, id)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select user query")
+		return nil, err
+	}
+	var u app.User
+	if err := row.Scan(&u.ID, &u.Name, &u.Email, &u.NotifyMode, &u.Role, &u.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, app.ErrNotFound
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan user row")
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetUserByTokenHash находит пользователя по хешу токена доступа
+func (p *Postgres) GetUserByTokenHash(hash string) (*app.User, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, name, email, notifyMode, role, createdAt
+		FROM users
+		WHERE tokenHash = $1
+	`
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, hash)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select user by token query")
+		return nil, err
+	}
+	var u app.User
+	if err := row.Scan(&u.ID, &u.Name, &u.Email, &u.NotifyMode, &u.Role, &u.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, app.ErrNotFound
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan user row")
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (p *Postgres) UpdateNotifyMode(id, mode string) error {
+	ctx := context.Background()
+	res, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		`UPDATE users SET notifyMode = $2 WHERE id = $1`, id, mode)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute update notify mode query")
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return app.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package db
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/retry"
+	wbzlog "github.com/wb-go/wbf/zlog"
+)
+
+func (p *Postgres) SaveWebhookSubscription(sub *app.WebhookSubscription) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO webhook_subscriptions (id, url, secret, events, subjectID, createdAt)
+		VALUES($1, $2, $3, $4, $5, $6)
+	`
+	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query,
+		sub.ID,
+		sub.URL,
+		sub.Secret,
+		pq.Array(eventTypesToStrings(sub.Events)),
+		sub.SubjectID,
+		sub.CreatedAt,
+	)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute insert webhook subscription query")
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) DeleteWebhookSubscription(id string) error {
+	ctx := context.Background()
+	res, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs},
+		`DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute delete webhook subscription query")
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return app.ErrNotFound
+	}
+	return nil
+}
+
+func (p *Postgres) GetWebhookSubscription(id string) (*app.WebhookSubscription, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, url, secret, events, subjectID, createdAt
+		FROM webhook_subscriptions
+		WHERE id = $1
+	`
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, id)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select webhook subscription query")
+		return nil, err
+	}
+	sub, err := scanWebhookSubscription(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, app.ErrNotFound
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan webhook subscription row")
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (p *Postgres) GetWebhookSubscriptions() ([]app.WebhookSubscription, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, url, secret, events, subjectID, createdAt
+		FROM webhook_subscriptions
+		ORDER BY createdAt ASC
+	`
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select webhook subscriptions query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	var subs []app.WebhookSubscription
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan webhook subscription row")
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	if err = rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (p *Postgres) SaveWebhookDelivery(d *app.WebhookDelivery) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO webhook_deliveries (id, subscriptionID, eventType, payload, status, attempts, responseCode, error, createdAt, deliveredAt)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE
+		SET status = EXCLUDED.status,
+			attempts = EXCLUDED.attempts,
+			responseCode = EXCLUDED.responseCode,
+			error = EXCLUDED.error,
+			deliveredAt = EXCLUDED.deliveredAt
+	`
+	_, err := p.db.ExecWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query,
+		d.ID,
+		d.SubscriptionID,
+		string(d.EventType),
+		d.Payload,
+		d.Status,
+		d.Attempts,
+		d.ResponseCode,
+		d.Error,
+		d.CreatedAt,
+		d.DeliveredAt,
+	)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute upsert webhook delivery query")
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) GetWebhookDelivery(id string) (*app.WebhookDelivery, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, subscriptionID, eventType, payload, status, attempts, responseCode, error, createdAt, deliveredAt
+		FROM webhook_deliveries
+		WHERE id = $1
+	`
+	row, err := p.db.QueryRowWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, id)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select webhook delivery query")
+		return nil, err
+	}
+	d, err := scanWebhookDelivery(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, app.ErrNotFound
+		}
+		wbzlog.Logger.Error().Err(err).Msg("Failed to scan webhook delivery row")
+		return nil, err
+	}
+	return d, nil
+}
+
+func (p *Postgres) GetWebhookDeliveries(subscriptionID string, page, pageSize int) ([]app.WebhookDelivery, error) {
+	ctx := context.Background()
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	query := `
+		SELECT id, subscriptionID, eventType, payload, status, attempts, responseCode, error, createdAt, deliveredAt
+		FROM webhook_deliveries
+		WHERE subscriptionID = $1
+		ORDER BY createdAt DESC
+		LIMIT $2 OFFSET $3;
+	`
+	rows, err := p.db.QueryWithRetry(ctx, retry.Strategy{Attempts: p.cfg.Attempts, Delay: p.cfg.Delay, Backoff: p.cfg.Backoffs}, query, subscriptionID, pageSize, offset)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Failed to execute select webhook deliveries query")
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to close rows")
+		}
+	}()
+
+	var deliveries []app.WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			wbzlog.Logger.Error().Err(err).Msg("Failed to scan webhook delivery row")
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	if err = rows.Err(); err != nil {
+		wbzlog.Logger.Error().Err(err).Msg("Row iteration error")
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhookSubscription(row rowScanner) (*app.WebhookSubscription, error) {
+	var sub app.WebhookSubscription
+	var eventTypes []string
+	if err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, pq.Array(&eventTypes), &sub.SubjectID, &sub.CreatedAt); err != nil {
+		return nil, err
+	}
+	for _, t := range eventTypes {
+		sub.Events = append(sub.Events, app.EventType(t))
+	}
+	return &sub, nil
+}
+
+func scanWebhookDelivery(row rowScanner) (*app.WebhookDelivery, error) {
+	var d app.WebhookDelivery
+	var eventType string
+	if err := row.Scan(&d.ID, &d.SubscriptionID, &eventType, &d.Payload, &d.Status, &d.Attempts, &d.ResponseCode, &d.Error, &d.CreatedAt, &d.DeliveredAt); err != nil {
+		return nil, err
+	}
+	d.EventType = app.EventType(eventType)
+	return &d, nil
+}
+
+func eventTypesToStrings(types []app.EventType) []string {
+	result := make([]string, 0, len(types))
+	for _, t := range types {
+		result = append(result, string(t))
+	}
+	return result
+}
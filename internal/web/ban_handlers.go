@@ -0,0 +1,141 @@
+package web
+
+import (
+	"commentTree/internal/app/domain"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type BanReqCreate struct {
+	Kind      string     `json:"kind" binding:"required,oneof=author ip"`
+	UserID    string     `json:"user_id"`
+	IP        string     `json:"ip"`
+	CommentID string     `json:"comment_id"`
+	ThreadID  string     `json:"thread_id"`
+	Reason    string     `json:"reason" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type BanHandler struct {
+	banService BanService
+}
+
+type BanService interface {
+	CreateBan(req app.BanRequest, actorID string) (*app.Ban, error)
+	RevokeBan(id, actorID string) (*app.Ban, error)
+	GetBans(activeOnly bool, page, pageSize int) ([]app.Ban, error)
+	GetAuditLog(targetID string, page, pageSize int) ([]app.AuditEntry, error)
+}
+
+func NewBanHandler(banService BanService) *BanHandler {
+	return &BanHandler{
+		banService: banService,
+	}
+}
+
+// CreateBan godoc
+// @Summary      Ban Author Or Address
+// @Description  Запрещает автору (kind=author) или адресу (kind=ip) оставлять комментарии во всем сервисе или в ветке thread_id.
+// @Description  Автора и адрес можно указать явно (user_id, ip) или взять из комментария comment_id. Без expires_at бан бессрочный.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        ban  body  BanReqCreate  true  "Ban"
+// @Success      201  {object}  app.Ban                  "Ban"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid input data"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /admin/bans [post]
+func (h *BanHandler) CreateBan(ctx *wbgin.Context) {
+	var req BanReqCreate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	ban, err := h.banService.CreateBan(app.BanRequest{
+		Kind:      app.BanKind(req.Kind),
+		UserID:    req.UserID,
+		IP:        req.IP,
+		CommentID: req.CommentID,
+		ThreadID:  req.ThreadID,
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
+	}, currentUserID(ctx))
+	if writeContentError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, ban)
+}
+
+// RevokeBan godoc
+// @Summary      Revoke Ban
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "Ban ID"
+// @Success      200  {object}  app.Ban        "Revoked ban"
+// @Failure      404  {object}  ErrorResponse  "Ban not found or already revoked"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /admin/bans/{id} [delete]
+func (h *BanHandler) RevokeBan(ctx *wbgin.Context) {
+	ban, err := h.banService.RevokeBan(ctx.Param("id"), currentUserID(ctx))
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, ban)
+}
+
+// GetBans godoc
+// @Summary      List Bans
+// @Tags         admin
+// @Produce      json
+// @Param        active     query  bool  false  "Только действующие"
+// @Param        page       query  int   false  "Номер страницы" default(1)
+// @Param        page_size  query  int   false  "Размер страницы" default(50)
+// @Success      200  {array}   app.Ban        "Bans"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /admin/bans [get]
+func (h *BanHandler) GetBans(ctx *wbgin.Context) {
+	pageInt, _ := strconv.Atoi(ctx.Query("page"))
+	pageSizeInt, _ := strconv.Atoi(ctx.Query("page_size"))
+
+	bans, err := h.banService.GetBans(ctx.Query("active") == "true", pageInt, pageSizeInt)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, bans)
+}
+
+// GetAuditLog godoc
+// @Summary      Audit Log
+// @Description  Кто и когда выдал или снял бан, скрыл автора или вернул его. data — состояние объекта после действия.
+// @Tags         admin
+// @Produce      json
+// @Param        target_id  query  string  false  "Ban ID or muted author ID"
+// @Param        page       query  int     false  "Номер страницы" default(1)
+// @Param        page_size  query  int     false  "Размер страницы" default(50)
+// @Success      200  {array}   app.AuditEntry           "Entries"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid filter"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /admin/audit [get]
+func (h *BanHandler) GetAuditLog(ctx *wbgin.Context) {
+	pageInt, _ := strconv.Atoi(ctx.Query("page"))
+	pageSizeInt, _ := strconv.Atoi(ctx.Query("page_size"))
+
+	entries, err := h.banService.GetAuditLog(ctx.Query("target_id"), pageInt, pageSizeInt)
+	if writeContentError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"commentTree/internal/app/domain"
+	"errors"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"net/http"
+)
+
+type ChallengeHandler struct {
+	challengeService ChallengeService
+}
+
+type ChallengeService interface {
+	Required(userID string) (bool, error)
+	Issue() (*app.Challenge, error)
+	Redeem(token, solution string) error
+}
+
+func NewChallengeHandler(challengeService ChallengeService) *ChallengeHandler {
+	return &ChallengeHandler{
+		challengeService: challengeService,
+	}
+}
+
+// IssueChallenge godoc
+// @Summary      Proof-of-Work Challenge
+// @Description  Выдает подписанную задачу: найти строку s, при которой sha256(token + ":" + s) начинается
+// @Description  с difficulty нулевых бит. Решение передается с анонимным комментарием в заголовках
+// @Description  X-PoW-Challenge (token) и X-PoW-Solution (s); каждая задача принимается один раз.
+// @Tags         comments
+// @Produce      json
+// @Success      200  {object}  app.Challenge  "Challenge"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /challenge [get]
+func (h *ChallengeHandler) IssueChallenge(ctx *wbgin.Context) {
+	challenge, err := h.challengeService.Issue()
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, challenge)
+}
+
+// RequireProof — middleware маршрута: анонимный запрос и запрос пользователя с низким уровнем доверия
+// проходят только с решенной задачей, 403 иначе
+func (h *ChallengeHandler) RequireProof(ctx *wbgin.Context) {
+	required, err := h.challengeService.Required(currentUserID(ctx))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !required {
+		ctx.Next()
+		return
+	}
+	err = h.challengeService.Redeem(ctx.GetHeader("X-PoW-Challenge"), ctx.GetHeader("X-PoW-Solution"))
+	if errors.Is(err, app.ErrChallengeRequired) || errors.Is(err, app.ErrChallengeInvalid) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		return
+	}
+	ctx.Next()
+}
@@ -0,0 +1,110 @@
+package web
+
+import (
+	"commentTree/internal/app/domain"
+	"context"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ChangesHandler struct {
+	changesService ChangesService
+}
+
+type ChangesService interface {
+	WaitChanges(ctx context.Context, parentId string, since int64) (*app.ChangeSet, error)
+	GetDelta(parentId string, since int64, after time.Time) (*app.TreeDelta, error)
+}
+
+func NewChangesHandler(changesService ChangesService) *ChangesHandler {
+	return &ChangesHandler{
+		changesService: changesService,
+	}
+}
+
+// GetChanges godoc
+// @Summary      Long-poll Comment Changes
+// @Description  Возвращает изменения в ветке parent после ревизии since. Если изменений нет, запрос ждет их появления до таймаута и возвращает пустой список с текущей ревизией.
+// @Tags         comments
+// @Produce      json
+// @Param        parent  query  string  true   "ID корня ветки"
+// @Param        since   query  int     false  "Ревизия ветки, известная клиенту" default(0)
+// @Success      200  {object}  app.ChangeSet  "Изменения и текущая ревизия ветки"
+// @Failure      400  {object}  ErrorResponse  "Invalid parent id or revision"
+// @Failure      404  {object}  ErrorResponse  "Comment not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /comments/changes [get]
+func (h *ChangesHandler) GetChanges(ctx *wbgin.Context) {
+	parentId := ctx.Query("parent")
+	if parentId == "" {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "parent is required"})
+		return
+	}
+	var since int64
+	if s := ctx.Query("since"); s != "" {
+		var err error
+		since, err = strconv.ParseInt(s, 10, 64)
+		if err != nil || since < 0 {
+			ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "invalid since revision"})
+			return
+		}
+	}
+
+	set, err := h.changesService.WaitChanges(ctx.Request.Context(), parentId, since)
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, set)
+}
+
+// GetDelta godoc
+// @Summary      Tree Delta
+// @Description  Возвращает созданные, измененные и удаленные узлы ветки parent после ревизии since или момента since_time,
+// @Description  чтобы клиент мог обновить уже загруженное дерево без полной перезагрузки.
+// @Tags         comments
+// @Produce      json
+// @Param        parent      query  string  true   "ID корня ветки"
+// @Param        since       query  int     false  "Ревизия ветки, известная клиенту" default(0)
+// @Param        since_time  query  string  false  "Момент последней синхронизации (RFC3339)"
+// @Success      200  {object}  app.TreeDelta  "Дельта дерева и текущая ревизия ветки"
+// @Failure      400  {object}  ErrorResponse  "Invalid parent id, revision or time"
+// @Failure      404  {object}  ErrorResponse  "Comment not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /comments/delta [get]
+func (h *ChangesHandler) GetDelta(ctx *wbgin.Context) {
+	parentId := ctx.Query("parent")
+	if parentId == "" {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "parent is required"})
+		return
+	}
+	var since int64
+	if s := ctx.Query("since"); s != "" {
+		var err error
+		since, err = strconv.ParseInt(s, 10, 64)
+		if err != nil || since < 0 {
+			ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "invalid since revision"})
+			return
+		}
+	}
+	var after time.Time
+	if s := ctx.Query("since_time"); s != "" {
+		var err error
+		after, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "invalid since_time, expected RFC3339"})
+			return
+		}
+	}
+
+	delta, err := h.changesService.GetDelta(parentId, since, after)
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, delta)
+}
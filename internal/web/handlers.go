@@ -2,25 +2,43 @@ package web
 
 import (
 	"commentTree/internal/app/domain"
+	"errors"
+	"github.com/google/uuid"
 	wbgin "github.com/wb-go/wbf/ginext"
 	"net/http"
 	"strconv"
+	"time"
 )
 
+// maxCommentBody — предел тела запроса с комментарием. Длину текста проверяет content policy,
+// предел лишь не дает читать в память тело любого размера.
+const maxCommentBody = 1 << 20
+
 type CommentReqCreate struct {
 	ParentId string `json:"parent_id"`
 	Text     string `json:"text" binding:"required"`
 }
 
+type CommentReqEdit struct {
+	Text string `json:"text" binding:"required"`
+}
+
 type CommentHandler struct {
 	commentService CommentService
 }
 
 type CommentService interface {
 	GetComments(parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error)
-	SearchComments(text string, parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error)
-	DeleteComments(id string) error
-	CreateComment(text, parentID string) (*app.Comment, error)
+	SearchComments(text string, parentId, viewerID string, sortAsc string, page, pageSize int) ([]app.CommentNode, error)
+	DeleteComments(id string, actor *app.User) error
+	CreateComment(text, parentID, authorID, clientIP string) (*app.Comment, error)
+	EditComment(id, text, editorID string) (*app.Comment, error)
+	GetCommentsForUser(userID, parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error)
+	FirstUnread(userID, parentId string) (*uuid.UUID, error)
+	GetComment(id, viewerID string) (*app.Comment, error)
+	GetAncestors(id, viewerID string) ([]app.Comment, error)
+	GetContext(id, viewerID string, up, down int) (*app.CommentNode, error)
+	ThreadRedirect(parentID string) (string, error)
 }
 
 func NewCommentHandler(commentService CommentService) *CommentHandler {
@@ -34,26 +52,89 @@ type ErrorResponse struct {
 	Error string `json:"error" example:"invalid input data"`
 }
 
+// ValidationErrorResponse — ошибка проверки содержимого с нарушениями по полям
+type ValidationErrorResponse struct {
+	Error  string           `json:"error" example:"validation failed"`
+	Fields []app.FieldError `json:"fields"`
+}
+
+// RejectedResponse — комментарий отклонен фильтром содержимого
+type RejectedResponse struct {
+	Error  string `json:"error" example:"rejected"`
+	Filter string `json:"filter" example:"domains"`
+	Reason string `json:"reason" example:"blocked link domain: spam.example"`
+}
+
+// BannedResponse — автор или адрес забанен; expires_at пуст у бессрочного бана
+type BannedResponse struct {
+	Error     string     `json:"error" example:"banned"`
+	Reason    string     `json:"reason" example:"spam"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// writeContentError отвечает 400 на *app.ValidationError и 422 на *app.RejectedError
+func writeContentError(ctx *wbgin.Context, err error) bool {
+	var verr *app.ValidationError
+	if errors.As(err, &verr) {
+		ctx.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "validation failed", Fields: verr.Errors})
+		return true
+	}
+	var rerr *app.RejectedError
+	if errors.As(err, &rerr) {
+		ctx.JSON(http.StatusUnprocessableEntity, RejectedResponse{Error: "rejected", Filter: rerr.Verdict.Filter, Reason: rerr.Verdict.Reason})
+		return true
+	}
+	return false
+}
+
 // CreateComment godoc
 // @Summary      Create Comment
-// @Description  Создает новый комментарий, можно указать ParentId для вложенного комментария
+// @Description  Создает новый комментарий, можно указать ParentId для вложенного комментария.
+// @Description  С токеном пользователя комментарий сохраняется от его имени, иначе анонимно.
+// @Description  Анонимный комментарий требует решенной задачи из GET /challenge в X-PoW-Challenge и X-PoW-Solution.
 // @Tags         comments
 // @Accept       json
 // @Produce      json
-// @Param        comment  body  CommentReqCreate  true  "Comment to create"
+// @Param        Authorization    header  string  false  "Bearer <token>"
+// @Param        Idempotency-Key  header  string  false  "Повтор с тем же ключом и телом вернет исходный ответ"
+// @Param        X-PoW-Challenge  header  string  false  "Challenge token (anonymous only)"
+// @Param        X-PoW-Solution   header  string  false  "Challenge solution (anonymous only)"
+// @Param        comment          body  CommentReqCreate  true  "Comment to create"
 // @Success      201  {object}  app.Comment  "Created comment"
-// @Failure      400  {object}  ErrorResponse  "Invalid input data"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid input data"
+// @Failure      403  {object}  BannedResponse  "Author or address is banned, or proof of work is missing"
+// @Failure      409  {object}  ErrorResponse  "Idempotency key reused with a different body or still in progress"
+// @Failure      422  {object}  RejectedResponse  "Rejected by content filter"
+// @Failure      429  {object}  ErrorResponse  "Rate limit, slow mode of the thread or the author's trust level exceeded"
 // @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
 // @Router       /comments [post]
 func (h *CommentHandler) CreateComment(ctx *wbgin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxCommentBody)
 	var req CommentReqCreate
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
 		return
 	}
 
-	comm, err := h.commentService.CreateComment(req.Text, req.ParentId)
-
+	comm, err := h.commentService.CreateComment(req.Text, req.ParentId, currentUserID(ctx), ctx.ClientIP())
+	if writeContentError(ctx, err) {
+		return
+	}
+	var banned *app.BannedError
+	if errors.As(err, &banned) {
+		ctx.JSON(http.StatusForbidden, BannedResponse{Error: "banned", Reason: banned.Ban.Reason, ExpiresAt: banned.Ban.ExpiresAt})
+		return
+	}
+	var slow *app.SlowModeError
+	if errors.As(err, &slow) {
+		writeRetryAfter(ctx, slow.RetryAfter)
+		ctx.JSON(http.StatusTooManyRequests, wbgin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, app.ErrRateLimited) {
+		ctx.JSON(http.StatusTooManyRequests, wbgin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
 		return
@@ -62,15 +143,63 @@ func (h *CommentHandler) CreateComment(ctx *wbgin.Context) {
 	ctx.JSON(http.StatusCreated, comm)
 }
 
+// EditComment godoc
+// @Summary      Edit Comment
+// @Description  Меняет текст комментария и пересчитывает упоминания. Редактировать может только автор.
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string          true  "Bearer <token>"
+// @Param        id             path    string          true  "Comment ID"
+// @Param        comment        body    CommentReqEdit  true  "New text"
+// @Success      200  {object}  app.Comment    "Edited comment"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid input data"
+// @Failure      401  {object}  ErrorResponse  "Unknown or missing user"
+// @Failure      403  {object}  ErrorResponse  "Not the author"
+// @Failure      404  {object}  ErrorResponse  "Comment not found"
+// @Failure      422  {object}  RejectedResponse  "Rejected by content filter"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /comments/{id} [put]
+func (h *CommentHandler) EditComment(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	var req CommentReqEdit
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	comm, err := h.commentService.EditComment(ctx.Param("id"), req.Text, user.ID.String())
+	if writeContentError(ctx, err) {
+		return
+	}
+	if errors.Is(err, app.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, wbgin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, comm)
+}
+
 // DeleteComments godoc
 // @Summary      Delete Comment
-// @Description  Удаляет комментарий и все его дочерние комментарии по ID
+// @Description  Удаляет комментарий и все его дочерние комментарии по ID. Удалить может автор или модератор.
 // @Tags         comments
 // @Accept       json
 // @Produce      json
-// @Param        id   path   string  true  "Comment ID"
+// @Param        Authorization  header  string  true  "Bearer <token>"
+// @Param        id             path    string  true  "Comment ID"
 // @Success      204  {string}  string  "Comment deleted successfully"
 // @Failure      400  {object}  ErrorResponse  "Invalid comment ID"
+// @Failure      401  {object}  ErrorResponse  "Unknown or missing user"
+// @Failure      403  {object}  ErrorResponse  "Not the author"
+// @Failure      404  {object}  ErrorResponse  "Comment not found"
 // @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
 // @Router       /comments/{id} [delete]
 func (h *CommentHandler) DeleteComments(ctx *wbgin.Context) {
@@ -79,10 +208,18 @@ func (h *CommentHandler) DeleteComments(ctx *wbgin.Context) {
 		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "id is required"})
 		return
 	}
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
 
-	err := h.commentService.DeleteComments(id)
+	err := h.commentService.DeleteComments(id, user)
+	if errors.Is(err, app.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, wbgin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		writeLookupError(ctx, err)
 		return
 	}
 
@@ -91,16 +228,22 @@ func (h *CommentHandler) DeleteComments(ctx *wbgin.Context) {
 
 // GetComments godoc
 // @Summary      Get Comments
-// @Description  Получает комментарии по parentId, поддерживает фильтр search, пагинацию и сортировку
+// @Description  Получает комментарии по parentId, поддерживает фильтр search, пагинацию и сортировку.
+// @Description  С токеном пользователя узлы, появившиеся после прошлого визита в ветку, помечаются is_new,
+// @Description  а jump=unread возвращает id первого непрочитанного комментария ветки в заголовке X-First-Unread.
+// @Description  Запрос ветки, слитой в другую, перенаправляется (308) на целевую ветку.
 // @Tags         comments
 // @Accept       json
 // @Produce      json
-// @Param        parent     query  string  false  "Parent ID (если не указан, можно использовать search)"
-// @Param        search     query  string  false  "Текст для поиска комментариев"
-// @Param        page       query  int     false  "Номер страницы" default(1)
-// @Param        page_size  query  int     false  "Размер страницы" default(10)
-// @Param        sort       query  string  false  "Сортировка asc/desc" default(asc)
+// @Param        Authorization  header string  false  "Bearer <token>"
+// @Param        parent         query  string  false  "Parent ID (если не указан, можно использовать search)"
+// @Param        jump           query  string  false  "unread — найти первый непрочитанный комментарий ветки parent"
+// @Param        search         query  string  false  "Текст для поиска комментариев"
+// @Param        page           query  int     false  "Номер страницы" default(1)
+// @Param        page_size      query  int     false  "Размер страницы" default(10)
+// @Param        sort           query  string  false  "Сортировка asc/desc" default(asc)
 // @Success      200  {array}   app.CommentNode  "Список комментариев с деревом вложенности"
+// @Success      308  "Thread was merged, Location points to the target thread"
 // @Failure      400  {object}  ErrorResponse    "Invalid parent id"
 // @Failure      503  {object}  ErrorResponse    "Service unavailable (DB error)"
 // @Router       /comments [get]
@@ -113,12 +256,41 @@ func (h *CommentHandler) GetComments(ctx *wbgin.Context) {
 	pageInt, _ := strconv.Atoi(page)
 	pageSizeInt, _ := strconv.Atoi(pageSize)
 
+	if parentId != "" {
+		target, err := h.commentService.ThreadRedirect(parentId)
+		if err != nil {
+			ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+			return
+		}
+		if target != "" {
+			query := ctx.Request.URL.Query()
+			query.Set("parent", target)
+			ctx.Redirect(http.StatusPermanentRedirect, ctx.Request.URL.Path+"?"+query.Encode())
+			return
+		}
+	}
+
+	user := currentUser(ctx)
+	if user != nil && parentId != "" && ctx.Query("jump") == "unread" {
+		// Ищем до загрузки страницы: показ страницы сдвигает отметку прочтения
+		first, err := h.commentService.FirstUnread(user.ID.String(), parentId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+			return
+		}
+		if first != nil {
+			ctx.Header("X-First-Unread", first.String())
+		}
+	}
+
 	var err error
 	var nodes []app.CommentNode
-	if search == "" {
+	if search == "" && user != nil {
+		nodes, err = h.commentService.GetCommentsForUser(user.ID.String(), parentId, sort, pageInt, pageSizeInt)
+	} else if search == "" {
 		nodes, err = h.commentService.GetComments(parentId, sort, pageInt, pageSizeInt)
 	} else {
-		nodes, err = h.commentService.SearchComments(search, parentId, sort, pageInt, pageSizeInt)
+		nodes, err = h.commentService.SearchComments(search, parentId, currentUserID(ctx), sort, pageInt, pageSizeInt)
 	}
 	if err != nil {
 		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
@@ -126,3 +298,84 @@ func (h *CommentHandler) GetComments(ctx *wbgin.Context) {
 	}
 	ctx.JSON(http.StatusOK, nodes)
 }
+
+// GetComment godoc
+// @Summary      Get Comment
+// @Description  Один комментарий по id. 404, если он или кто-то из его предков не виден запрашивающему.
+// @Tags         comments
+// @Produce      json
+// @Param        Authorization  header  string  false  "Bearer <token>"
+// @Param        id             path    string  true   "Comment ID"
+// @Success      200  {object}  app.Comment    "Comment"
+// @Failure      404  {object}  ErrorResponse  "Comment not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /comments/{id} [get]
+func (h *CommentHandler) GetComment(ctx *wbgin.Context) {
+	comment, err := h.commentService.GetComment(ctx.Param("id"), currentUserID(ctx))
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, comment)
+}
+
+// GetAncestors godoc
+// @Summary      Get Comment Ancestors
+// @Description  Предки комментария от корня ветки к родителю — «хлебные крошки». У корня список пуст.
+// @Tags         comments
+// @Produce      json
+// @Param        Authorization  header  string  false  "Bearer <token>"
+// @Param        id             path    string  true   "Comment ID"
+// @Success      200  {array}   app.Comment    "Ancestors"
+// @Failure      404  {object}  ErrorResponse  "Comment not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /comments/{id}/ancestors [get]
+func (h *CommentHandler) GetAncestors(ctx *wbgin.Context) {
+	ancestors, err := h.commentService.GetAncestors(ctx.Param("id"), currentUserID(ctx))
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	if ancestors == nil {
+		ancestors = []app.Comment{}
+	}
+	ctx.JSON(http.StatusOK, ancestors)
+}
+
+// GetContext godoc
+// @Summary      Comment Permalink Context
+// @Description  Дерево для постоянной ссылки: up ближайших предков цепочкой, сам комментарий с target=true
+// @Description  и down уровней ответов на него. up — не больше 50, down — не больше 10.
+// @Tags         comments
+// @Produce      json
+// @Param        Authorization  header  string  false  "Bearer <token>"
+// @Param        id             path    string  true   "Comment ID"
+// @Param        up             query   int     false  "Сколько предков показать" default(3)
+// @Param        down           query   int     false  "Сколько уровней ответов показать" default(3)
+// @Success      200  {object}  app.CommentNode          "Context tree"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid up or down"
+// @Failure      404  {object}  ErrorResponse            "Comment not found"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /comments/{id}/context [get]
+func (h *CommentHandler) GetContext(ctx *wbgin.Context) {
+	up, err := strconv.Atoi(ctx.DefaultQuery("up", "3"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "up must be a number"})
+		return
+	}
+	down, err := strconv.Atoi(ctx.DefaultQuery("down", "3"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "down must be a number"})
+		return
+	}
+
+	node, err := h.commentService.GetContext(ctx.Param("id"), currentUserID(ctx), up, down)
+	if writeContentError(ctx, err) {
+		return
+	}
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, node)
+}
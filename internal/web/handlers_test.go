@@ -3,41 +3,80 @@ package web
 import (
 	"bytes"
 	"commentTree/internal/app/domain"
+	"commentTree/internal/config"
+	"commentTree/internal/ratelimit"
 	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	wbgin "github.com/wb-go/wbf/ginext"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type MockCommentService struct {
-	createCommentFunc  func(text, parentID string) (*app.Comment, error)
+	createCommentFunc  func(text, parentID, authorID, clientIP string) (*app.Comment, error)
 	getCommentsFunc    func(parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error)
-	searchCommentsFunc func(text string, parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error)
-	deleteCommentsFunc func(id string) error
+	searchCommentsFunc func(text string, parentId, viewerID string, sortAsc string, page, pageSize int) ([]app.CommentNode, error)
+	deleteCommentsFunc func(id string, actor *app.User) error
+	editCommentFunc    func(id, text, editorID string) (*app.Comment, error)
+	getForUserFunc     func(userID, parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error)
+	firstUnreadFunc    func(userID, parentId string) (*uuid.UUID, error)
+	getCommentFunc     func(id, viewerID string) (*app.Comment, error)
+	getAncestorsFunc   func(id, viewerID string) ([]app.Comment, error)
+	getContextFunc     func(id, viewerID string, up, down int) (*app.CommentNode, error)
+	redirects          map[string]string
 }
 
-func (m *MockCommentService) CreateComment(text, parentID string) (*app.Comment, error) {
-	return m.createCommentFunc(text, parentID)
+func (m *MockCommentService) ThreadRedirect(parentID string) (string, error) {
+	return m.redirects[parentID], nil
+}
+
+func (m *MockCommentService) GetComment(id, viewerID string) (*app.Comment, error) {
+	return m.getCommentFunc(id, viewerID)
+}
+
+func (m *MockCommentService) GetAncestors(id, viewerID string) ([]app.Comment, error) {
+	return m.getAncestorsFunc(id, viewerID)
+}
+
+func (m *MockCommentService) GetContext(id, viewerID string, up, down int) (*app.CommentNode, error) {
+	return m.getContextFunc(id, viewerID, up, down)
+}
+
+func (m *MockCommentService) GetCommentsForUser(userID, parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
+	return m.getForUserFunc(userID, parentId, sortAsc, page, pageSize)
+}
+
+func (m *MockCommentService) FirstUnread(userID, parentId string) (*uuid.UUID, error) {
+	return m.firstUnreadFunc(userID, parentId)
+}
+
+func (m *MockCommentService) CreateComment(text, parentID, authorID, clientIP string) (*app.Comment, error) {
+	return m.createCommentFunc(text, parentID, authorID, clientIP)
+}
+
+func (m *MockCommentService) EditComment(id, text, editorID string) (*app.Comment, error) {
+	return m.editCommentFunc(id, text, editorID)
 }
 
 func (m *MockCommentService) GetComments(parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
 	return m.getCommentsFunc(parentId, sortAsc, page, pageSize)
 }
 
-func (m *MockCommentService) SearchComments(text string, parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
-	return m.searchCommentsFunc(text, parentId, sortAsc, page, pageSize)
+func (m *MockCommentService) SearchComments(text string, parentId, viewerID string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
+	return m.searchCommentsFunc(text, parentId, viewerID, sortAsc, page, pageSize)
 }
 
-func (m *MockCommentService) DeleteComments(id string) error {
-	return m.deleteCommentsFunc(id)
+func (m *MockCommentService) DeleteComments(id string, actor *app.User) error {
+	return m.deleteCommentsFunc(id, actor)
 }
 
 func TestCreateComment_Success(t *testing.T) {
 	mock := &MockCommentService{
-		createCommentFunc: func(text, parentID string) (*app.Comment, error) {
+		createCommentFunc: func(text, parentID, authorID, clientIP string) (*app.Comment, error) {
 			id := uuid.New()
 			parentUUID := uuid.Nil
 			if parentID != "" {
@@ -103,7 +142,7 @@ func TestCreateComment_MissingText(t *testing.T) {
 
 func TestCreateComment_ServiceError(t *testing.T) {
 	mock := &MockCommentService{
-		createCommentFunc: func(text, parentID string) (*app.Comment, error) {
+		createCommentFunc: func(text, parentID, authorID, clientIP string) (*app.Comment, error) {
 			return nil, errors.New("database error")
 		},
 	}
@@ -125,9 +164,110 @@ func TestCreateComment_ServiceError(t *testing.T) {
 	}
 }
 
+func TestCreateComment_ValidationError(t *testing.T) {
+	mock := &MockCommentService{
+		createCommentFunc: func(text, parentID, authorID, clientIP string) (*app.Comment, error) {
+			return nil, &app.ValidationError{Errors: []app.FieldError{{Field: "text", Code: "too_long", Message: "too long"}}}
+		},
+	}
+	handler := NewCommentHandler(mock)
+
+	jsonBody, _ := json.Marshal(CommentReqCreate{Text: "Test comment"})
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/comments", bytes.NewReader(jsonBody))
+
+	handler.CreateComment(ctx)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	var resp ValidationErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Fields) != 1 || resp.Fields[0].Code != "too_long" {
+		t.Errorf("unexpected fields %+v", resp.Fields)
+	}
+}
+
+func TestCreateComment_Rejected(t *testing.T) {
+	mock := &MockCommentService{
+		createCommentFunc: func(text, parentID, authorID, clientIP string) (*app.Comment, error) {
+			return nil, &app.RejectedError{Verdict: app.FilterVerdict{Action: app.FilterReject, Filter: "domains", Reason: "blocked"}}
+		},
+	}
+	handler := NewCommentHandler(mock)
+
+	jsonBody, _ := json.Marshal(CommentReqCreate{Text: "Test comment"})
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/comments", bytes.NewReader(jsonBody))
+
+	handler.CreateComment(ctx)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	var resp RejectedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Filter != "domains" {
+		t.Errorf("expected filter domains, got %q", resp.Filter)
+	}
+}
+
+func TestCreateComment_RateLimited(t *testing.T) {
+	mock := &MockCommentService{
+		createCommentFunc: func(text, parentID, authorID, clientIP string) (*app.Comment, error) {
+			return nil, app.ErrRateLimited
+		},
+	}
+	handler := NewCommentHandler(mock)
+
+	jsonBody, _ := json.Marshal(CommentReqCreate{Text: "Test comment"})
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/comments", bytes.NewReader(jsonBody))
+
+	handler.CreateComment(ctx)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+}
+
+func TestCreateComment_Banned(t *testing.T) {
+	mock := &MockCommentService{
+		createCommentFunc: func(text, parentID, authorID, clientIP string) (*app.Comment, error) {
+			return nil, &app.BannedError{Ban: app.Ban{Kind: app.BanIP, Reason: "spam"}}
+		},
+	}
+	handler := NewCommentHandler(mock)
+
+	jsonBody, _ := json.Marshal(CommentReqCreate{Text: "Test comment"})
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/comments", bytes.NewReader(jsonBody))
+
+	handler.CreateComment(ctx)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+	var resp BannedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Reason != "spam" {
+		t.Errorf("expected reason spam, got %q", resp.Reason)
+	}
+}
+
 func TestDeleteComments_Success(t *testing.T) {
 	mock := &MockCommentService{
-		deleteCommentsFunc: func(id string) error {
+		deleteCommentsFunc: func(id string, actor *app.User) error {
 			return nil
 		},
 	}
@@ -136,6 +276,7 @@ func TestDeleteComments_Success(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Params = []gin.Param{{Key: "id", Value: "550e8400-e29b-41d4-a716-446655440000"}}
+	ctx.Set(userContextKey, &app.User{ID: uuid.New()})
 
 	handler.DeleteComments(ctx)
 
@@ -157,9 +298,36 @@ func TestDeleteComments_MissingId(t *testing.T) {
 	}
 }
 
+func TestDeleteComments_RequiresOwner(t *testing.T) {
+	handler := NewCommentHandler(&MockCommentService{
+		deleteCommentsFunc: func(id string, actor *app.User) error {
+			return app.ErrForbidden
+		},
+	})
+
+	for name, tc := range map[string]struct {
+		user *app.User
+		code int
+	}{
+		"anonymous":  {nil, http.StatusUnauthorized},
+		"not author": {&app.User{ID: uuid.New()}, http.StatusForbidden},
+	} {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Params = []gin.Param{{Key: "id", Value: "550e8400-e29b-41d4-a716-446655440000"}}
+		if tc.user != nil {
+			ctx.Set(userContextKey, tc.user)
+		}
+		handler.DeleteComments(ctx)
+		if w.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d", name, tc.code, w.Code)
+		}
+	}
+}
+
 func TestDeleteComments_ServiceError(t *testing.T) {
 	mock := &MockCommentService{
-		deleteCommentsFunc: func(id string) error {
+		deleteCommentsFunc: func(id string, actor *app.User) error {
 			return errors.New("ошибка БД")
 		},
 	}
@@ -168,6 +336,7 @@ func TestDeleteComments_ServiceError(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Params = []gin.Param{{Key: "id", Value: "550e8400-e29b-41d4-a716-446655440000"}}
+	ctx.Set(userContextKey, &app.User{ID: uuid.New()})
 
 	handler.DeleteComments(ctx)
 
@@ -197,7 +366,7 @@ func TestGetComments_Success(t *testing.T) {
 
 func TestGetComments_WithSearch(t *testing.T) {
 	mock := &MockCommentService{
-		searchCommentsFunc: func(text string, parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
+		searchCommentsFunc: func(text string, parentId, viewerID string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
 			return []app.CommentNode{}, nil
 		},
 	}
@@ -232,3 +401,598 @@ func TestGetComments_ServiceError(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
 	}
 }
+
+func TestEditComment_RequiresUser(t *testing.T) {
+	handler := NewCommentHandler(&MockCommentService{})
+
+	jsonBody, _ := json.Marshal(CommentReqEdit{Text: "Edited"})
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPut, "/comments/123", bytes.NewReader(jsonBody))
+
+	handler.EditComment(ctx)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestEditComment_Forbidden(t *testing.T) {
+	mock := &MockCommentService{
+		editCommentFunc: func(id, text, editorID string) (*app.Comment, error) {
+			return nil, app.ErrForbidden
+		},
+	}
+	handler := NewCommentHandler(mock)
+
+	jsonBody, _ := json.Marshal(CommentReqEdit{Text: "Edited"})
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPut, "/comments/123", bytes.NewReader(jsonBody))
+	ctx.Set(userContextKey, &app.User{ID: uuid.New(), Name: "alice"})
+
+	handler.EditComment(ctx)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestGetComments_JumpToFirstUnread(t *testing.T) {
+	userID := uuid.New()
+	firstUnread := uuid.New()
+	mock := &MockCommentService{
+		firstUnreadFunc: func(uid, parentId string) (*uuid.UUID, error) {
+			return &firstUnread, nil
+		},
+		getForUserFunc: func(uid, parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
+			if uid != userID.String() {
+				t.Errorf("expected user %s, got %s", userID, uid)
+			}
+			return []app.CommentNode{{Comment: app.Comment{ID: firstUnread}, IsNew: true}}, nil
+		},
+	}
+	handler := NewCommentHandler(mock)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/comments?parent="+uuid.New().String()+"&jump=unread", nil)
+	ctx.Set(userContextKey, &app.User{ID: userID, Name: "alice"})
+
+	handler.GetComments(ctx)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := w.Header().Get("X-First-Unread"); got != firstUnread.String() {
+		t.Errorf("expected X-First-Unread %s, got %q", firstUnread, got)
+	}
+}
+
+// stubThreads — корни веток; lookups считает обращения к базе
+type stubThreads struct {
+	roots   map[string]string
+	lookups int
+}
+
+func (s *stubThreads) ThreadRoot(commentID string) (string, error) {
+	s.lookups++
+	if root, ok := s.roots[commentID]; ok {
+		return root, nil
+	}
+	return commentID, nil
+}
+
+func TestRateLimiter_Handle(t *testing.T) {
+	cfg := &config.AppConfig{}
+	cfg.RateLimitConfig.Routes = []config.RouteLimitConfig{{
+		Method: http.MethodPost,
+		Path:   "/api/comments",
+		Author: config.RateRuleConfig{Rate: 1, Per: time.Minute},
+		Thread: config.RateRuleConfig{Rate: 2, Per: time.Minute},
+	}}
+	threads := &stubThreads{roots: map[string]string{"reply": "root"}}
+	limiter := NewRateLimiter(ratelimit.NewMemoryLimiter(), threads, cfg)
+	users := map[string]*app.User{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		users[name] = &app.User{ID: uuid.New(), Name: name}
+	}
+
+	engine := gin.New()
+	engine.Use(NewUserHandler(&stubUsers{tokens: users}).Identify, limiter.Handle)
+	var parents []string
+	engine.POST("/api/comments", func(ctx *gin.Context) {
+		var req CommentReqCreate
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return
+		}
+		parents = append(parents, req.ParentId)
+		ctx.Status(http.StatusCreated)
+	})
+
+	post := func(token, parentID string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(CommentReqCreate{ParentId: parentID, Text: "hi"})
+		req := httptest.NewRequest(http.MethodPost, "/api/comments", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	spoof := func(userID string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(CommentReqCreate{Text: "hi"})
+		req := httptest.NewRequest(http.MethodPost, "/api/comments", bytes.NewReader(body))
+		req.Header.Set("X-User-Id", userID)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post("alice", "reply"); w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	lookups := threads.lookups
+	w := post("alice", "root")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("expected Retry-After 60, got %q", got)
+	}
+	if threads.lookups != lookups {
+		t.Errorf("thread must not be resolved for a request rejected by the author bucket")
+	}
+	// Чужой публичный id без токена не дает новой корзины автора
+	if w := spoof(users["bob"].ID.String()); w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if w := spoof(users["carol"].ID.String()); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unverified ids must share the client address bucket, got status %d", w.Code)
+	}
+
+	// Ветка общая у reply и root: третий комментарий в нее превышает лимит ветки
+	if w := post("bob", "root"); w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if w := post("carol", "reply"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if len(parents) != 3 || parents[0] != "reply" {
+		t.Errorf("handler must still read the body, got parents %v", parents)
+	}
+}
+
+func TestRateLimiter_Handle_KeysOnConnectionAddress(t *testing.T) {
+	cfg := &config.AppConfig{}
+	cfg.RateLimitConfig.Routes = []config.RouteLimitConfig{{
+		Method: http.MethodPost,
+		Path:   "/api/comments",
+		IP:     config.RateRuleConfig{Rate: 1, Per: time.Minute},
+		Thread: config.RateRuleConfig{Rate: 10, Per: time.Minute},
+	}}
+	limiter := NewRateLimiter(ratelimit.NewMemoryLimiter(), &stubThreads{}, cfg)
+
+	engine := gin.New()
+	// как в di.newRouter без server.trusted_proxies
+	if err := engine.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	engine.POST("/api/comments", limiter.Handle, func(ctx *gin.Context) { ctx.Status(http.StatusCreated) })
+
+	post := func(forwardedFor string, body []byte) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/comments", bytes.NewReader(body))
+		req.RemoteAddr = "198.51.100.1:40000"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post("203.0.113.1", []byte(`{}`)); code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, code)
+	}
+	if code := post("203.0.113.2", []byte(`{}`)); code != http.StatusTooManyRequests {
+		t.Errorf("spoofed X-Forwarded-For must not open a new bucket, got status %d", code)
+	}
+
+	other := NewRateLimiter(ratelimit.NewMemoryLimiter(), &stubThreads{}, cfg)
+	engine = gin.New()
+	engine.POST("/api/comments", other.Handle, func(ctx *gin.Context) { ctx.Status(http.StatusCreated) })
+	if code := post("", bytes.Repeat([]byte(" "), maxCommentBody+1)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d for oversized body, got %d", http.StatusRequestEntityTooLarge, code)
+	}
+}
+
+// stubChallenges освобождает от задачи только пользователя trusted
+type stubChallenges struct {
+	trusted  string
+	redeemed []string
+}
+
+func (s *stubChallenges) Required(userID string) (bool, error) { return userID != s.trusted, nil }
+
+func (s *stubChallenges) Issue() (*app.Challenge, error) { return &app.Challenge{}, nil }
+
+func (s *stubChallenges) Redeem(token, solution string) error {
+	if token == "" {
+		return app.ErrChallengeRequired
+	}
+	if solution != "42" {
+		return app.ErrChallengeInvalid
+	}
+	s.redeemed = append(s.redeemed, token)
+	return nil
+}
+
+func TestChallengeHandler_RequireProof(t *testing.T) {
+	trusted, newbie := &app.User{ID: uuid.New()}, &app.User{ID: uuid.New()}
+	challenges := &stubChallenges{trusted: trusted.ID.String()}
+	handler := NewChallengeHandler(challenges)
+	users := NewUserHandler(&stubUsers{tokens: map[string]*app.User{"trusted": trusted, "newbie": newbie}})
+
+	engine := gin.New()
+	engine.POST("/api/comments", users.Identify, handler.RequireProof, func(ctx *gin.Context) {
+		ctx.Status(http.StatusCreated)
+	})
+
+	post := func(headers map[string]string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/comments", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post(nil); code != http.StatusForbidden {
+		t.Errorf("anonymous without proof: expected %d, got %d", http.StatusForbidden, code)
+	}
+	if code := post(map[string]string{"X-PoW-Challenge": "t", "X-PoW-Solution": "1"}); code != http.StatusForbidden {
+		t.Errorf("wrong solution: expected %d, got %d", http.StatusForbidden, code)
+	}
+	if code := post(map[string]string{"X-PoW-Challenge": "t", "X-PoW-Solution": "42"}); code != http.StatusCreated {
+		t.Errorf("solved: expected %d, got %d", http.StatusCreated, code)
+	}
+	if code := post(map[string]string{"Authorization": "Bearer trusted"}); code != http.StatusCreated {
+		t.Errorf("trusted user: expected %d, got %d", http.StatusCreated, code)
+	}
+	if code := post(map[string]string{"Authorization": "Bearer newbie"}); code != http.StatusForbidden {
+		t.Errorf("low-trust user without proof: expected %d, got %d", http.StatusForbidden, code)
+	}
+	if code := post(map[string]string{"X-User-Id": trusted.ID.String()}); code != http.StatusForbidden {
+		t.Errorf("trusted user's public id without token: expected %d, got %d", http.StatusForbidden, code)
+	}
+	if len(challenges.redeemed) != 1 {
+		t.Errorf("expected one redeemed challenge, got %d", len(challenges.redeemed))
+	}
+}
+
+// memoryIdempotency — IdempotencyService в памяти с той же логикой сравнения хешей
+type memoryIdempotency struct {
+	hashes    map[string]string
+	responses map[string]*app.IdempotentResponse
+}
+
+func (m *memoryIdempotency) Begin(scope, key, requestHash string) (*app.IdempotentResponse, error) {
+	id := scope + "/" + key
+	hash, ok := m.hashes[id]
+	if !ok {
+		m.hashes[id] = requestHash
+		return nil, nil
+	}
+	if hash != requestHash {
+		return nil, app.ErrIdempotencyMismatch
+	}
+	if m.responses[id] == nil {
+		return nil, app.ErrIdempotencyInFlight
+	}
+	return m.responses[id], nil
+}
+
+func (m *memoryIdempotency) Complete(scope, key string, response app.IdempotentResponse) error {
+	m.responses[scope+"/"+key] = &response
+	return nil
+}
+
+func (m *memoryIdempotency) Release(scope, key string) error {
+	delete(m.hashes, scope+"/"+key)
+	return nil
+}
+
+func TestIdempotency_Handle(t *testing.T) {
+	store := &memoryIdempotency{hashes: map[string]string{}, responses: map[string]*app.IdempotentResponse{}}
+	calls := 0
+	handler := NewCommentHandler(&MockCommentService{
+		createCommentFunc: func(text, parentID, authorID, clientIP string) (*app.Comment, error) {
+			calls++
+			if text == "fail" {
+				return nil, errors.New("db is down")
+			}
+			return &app.Comment{ID: uuid.New(), Text: text}, nil
+		},
+	})
+	engine := gin.New()
+	engine.POST("/api/comments", NewIdempotency(store).Handle, handler.CreateComment)
+
+	postFrom := func(addr, key, text string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(CommentReqCreate{Text: text})
+		req := httptest.NewRequest(http.MethodPost, "/api/comments", bytes.NewReader(body))
+		req.RemoteAddr = addr
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	post := func(key, text string) *httptest.ResponseRecorder {
+		return postFrom("192.0.2.1:1234", key, text)
+	}
+
+	first := post("k1", "hello")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, first.Code)
+	}
+	repeat := post("k1", "hello")
+	if repeat.Code != http.StatusCreated || repeat.Body.String() != first.Body.String() {
+		t.Errorf("repeat must return the original response, got %d %s", repeat.Code, repeat.Body.String())
+	}
+	if repeat.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("expected Idempotent-Replayed header on repeat")
+	}
+	if w := post("k1", "other"); w.Code != http.StatusConflict {
+		t.Errorf("expected status %d for a different body, got %d", http.StatusConflict, w.Code)
+	}
+
+	// Неудачный запрос освобождает ключ, повтор выполняется заново
+	post("k2", "fail")
+	post("k2", "fail")
+	if calls != 3 {
+		t.Errorf("expected 3 service calls, got %d", calls)
+	}
+
+	// Тело сверх предела отклоняется до обработчика
+	req := httptest.NewRequest(http.MethodPost, "/api/comments", bytes.NewReader(make([]byte, maxCommentBody+1)))
+	req.Header.Set("Idempotency-Key", "k3")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d for an oversized body, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+
+	// Анонимы с разных адресов не видят ответы друг друга по тому же ключу
+	other := postFrom("198.51.100.7:4321", "k1", "hello")
+	if other.Code != http.StatusCreated || other.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("another client must get its own response, got %d replayed=%q", other.Code, other.Header().Get("Idempotent-Replayed"))
+	}
+	if calls != 4 {
+		t.Errorf("expected 4 service calls, got %d", calls)
+	}
+}
+
+func TestGetContext(t *testing.T) {
+	var gotUp, gotDown int
+	handler := NewCommentHandler(&MockCommentService{
+		getContextFunc: func(id, viewerID string, up, down int) (*app.CommentNode, error) {
+			gotUp, gotDown = up, down
+			if id == "missing" {
+				return nil, app.ErrNotFound
+			}
+			return &app.CommentNode{Comment: app.Comment{ID: uuid.New()}, Target: true}, nil
+		},
+	})
+	engine := gin.New()
+	engine.GET("/api/comments/:id/context", handler.GetContext)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	w := get("/api/comments/abc/context")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if gotUp != 3 || gotDown != 3 {
+		t.Errorf("expected default up=3 down=3, got up=%d down=%d", gotUp, gotDown)
+	}
+	var node app.CommentNode
+	if err := json.Unmarshal(w.Body.Bytes(), &node); err != nil || !node.Target {
+		t.Errorf("expected target node, got %s", w.Body.String())
+	}
+
+	get("/api/comments/abc/context?up=1&down=0")
+	if gotUp != 1 || gotDown != 0 {
+		t.Errorf("expected up=1 down=0, got up=%d down=%d", gotUp, gotDown)
+	}
+	if w := get("/api/comments/abc/context?up=x"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := get("/api/comments/missing/context"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// stubMover реализует только MoveComment, остальные методы ModerationService тесту не нужны
+type stubMover struct {
+	ModerationService
+	parentID string
+}
+
+func (s *stubMover) SplitThread(id, actorID string) (*app.ThreadSplit, error) {
+	if id == "root" {
+		return nil, app.ErrAlreadyThread
+	}
+	return &app.ThreadSplit{Moved: 1}, nil
+}
+
+func (s *stubMover) MoveComment(id, parentID, actorID string) (*app.CommentMove, error) {
+	s.parentID = parentID
+	if parentID == id {
+		return nil, app.ErrMoveCycle
+	}
+	return &app.CommentMove{Moved: 1}, nil
+}
+
+func TestMoveComment(t *testing.T) {
+	mover := &stubMover{}
+	engine := gin.New()
+	engine.POST("/api/admin/comments/:id/move", NewModerationHandler(mover).MoveComment)
+
+	post := func(id, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/comments/"+id+"/move", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post("abc", `{"parent_id": "def"}`); w.Code != http.StatusOK || mover.parentID != "def" {
+		t.Errorf("expected move under def, got status %d parent %q", w.Code, mover.parentID)
+	}
+	if w := post("abc", `{"parent_id": null}`); w.Code != http.StatusOK || mover.parentID != "" {
+		t.Errorf("expected move to root, got status %d parent %q", w.Code, mover.parentID)
+	}
+	if w := post("abc", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without parent_id, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := post("abc", `{"parent_id": "abc"}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status %d on cycle, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestSplitThread(t *testing.T) {
+	engine := gin.New()
+	engine.POST("/api/admin/comments/:id/split", NewModerationHandler(&stubMover{}).SplitThread)
+
+	for id, code := range map[string]int{"reply": http.StatusOK, "root": http.StatusConflict} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/comments/"+id+"/split", nil))
+		if w.Code != code {
+			t.Errorf("split %s: expected status %d, got %d", id, code, w.Code)
+		}
+	}
+}
+
+func TestGetComments_RedirectsMergedThread(t *testing.T) {
+	merged, target := uuid.New().String(), uuid.New().String()
+	handler := NewCommentHandler(&MockCommentService{
+		redirects: map[string]string{merged: target},
+		getCommentsFunc: func(parentId string, sortAsc string, page, pageSize int) ([]app.CommentNode, error) {
+			return []app.CommentNode{}, nil
+		},
+	})
+	engine := gin.New()
+	engine.GET("/api/comments", handler.GetComments)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/comments?parent="+merged+"&page=2", nil))
+	if w.Code != http.StatusPermanentRedirect {
+		t.Fatalf("expected status %d, got %d", http.StatusPermanentRedirect, w.Code)
+	}
+	if location := w.Header().Get("Location"); location != "/api/comments?page=2&parent="+target {
+		t.Errorf("unexpected Location %q", location)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/comments?parent="+target, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d for target thread, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestRequireModerator(t *testing.T) {
+	engine := gin.New()
+	engine.Use(func(ctx *gin.Context) {
+		if role := ctx.GetHeader("X-Role"); role != "" {
+			ctx.Set(userContextKey, &app.User{ID: uuid.New(), Name: "alice", Role: role})
+		}
+	})
+	admin := engine.Group("/api/admin", NewUserHandler(nil).RequireModerator)
+	admin.GET("/moderation/queue", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	for role, code := range map[string]int{
+		"":                http.StatusUnauthorized,
+		app.RoleUser:      http.StatusForbidden,
+		app.RoleModerator: http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/moderation/queue", nil)
+		req.Header.Set("X-Role", role)
+		engine.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Errorf("role %q: expected status %d, got %d", role, code, w.Code)
+		}
+	}
+}
+
+// stubUsers — UserService, знающий пользователей только по токенам
+type stubUsers struct {
+	UserService
+	tokens map[string]*app.User
+}
+
+func (s *stubUsers) Authenticate(token string) (*app.User, error) {
+	if user, ok := s.tokens[token]; ok {
+		return user, nil
+	}
+	return nil, app.ErrUnauthorized
+}
+
+func TestIdentify_AcceptsOnlyBearerToken(t *testing.T) {
+	moderator := &app.User{ID: uuid.New(), Name: "mod", Role: app.RoleModerator}
+	users := NewUserHandler(&stubUsers{tokens: map[string]*app.User{"secret": moderator}})
+	engine := gin.New()
+	api := engine.Group("/api", users.Identify)
+	admin := api.Group("/admin", users.RequireModerator)
+	admin.GET("/audit", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	for name, tc := range map[string]struct {
+		header, value string
+		code          int
+	}{
+		"bearer token":       {"Authorization", "Bearer secret", http.StatusOK},
+		"public id":          {"X-User-Id", moderator.ID.String(), http.StatusUnauthorized},
+		"id as token":        {"Authorization", "Bearer " + moderator.ID.String(), http.StatusUnauthorized},
+		"unsupported scheme": {"Authorization", "Basic secret", http.StatusUnauthorized},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/audit", nil)
+		req.Header.Set(tc.header, tc.value)
+		engine.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d", name, tc.code, w.Code)
+		}
+	}
+}
+
+func TestRegisterRoutes_ModeratorOnlyTools(t *testing.T) {
+	cfg := &config.AppConfig{}
+	engine := &wbgin.Engine{Engine: gin.New()}
+	RegisterRoutes(engine, NewCommentHandler(nil), NewSavedSearchHandler(nil), NewStreamHandler(nil, cfg), NewChangesHandler(nil),
+		NewWebhookHandler(nil), NewJobHandler(nil), NewUserHandler(nil), NewInboxHandler(nil), NewModerationHandler(nil),
+		NewReportHandler(nil), NewBanHandler(nil), NewChallengeHandler(nil), NewIdempotency(nil),
+		NewRateLimiter(ratelimit.NewMemoryLimiter(), &stubThreads{}, cfg))
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/admin/searches"},
+		{http.MethodPost, "/api/admin/searches"},
+		{http.MethodGet, "/api/admin/alerts"},
+		{http.MethodGet, "/api/admin/webhooks"},
+		{http.MethodPost, "/api/admin/webhooks"},
+		{http.MethodGet, "/api/admin/webhooks/abc/deliveries"},
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(route.method, route.path, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected status %d for an anonymous request, got %d", route.method, route.path, http.StatusUnauthorized, w.Code)
+		}
+	}
+	for _, path := range []string{"/api/searches", "/api/alerts", "/api/webhooks"} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: expected status %d, got %d", path, http.StatusNotFound, w.Code)
+		}
+	}
+}
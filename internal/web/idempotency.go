@@ -0,0 +1,104 @@
+package web
+
+import (
+	"bytes"
+	"commentTree/internal/app/domain"
+	"errors"
+	"github.com/gin-gonic/gin"
+	wbgin "github.com/wb-go/wbf/ginext"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"io"
+	"net/http"
+)
+
+type IdempotencyService interface {
+	Begin(scope, key, requestHash string) (*app.IdempotentResponse, error)
+	Complete(scope, key string, response app.IdempotentResponse) error
+	Release(scope, key string) error
+}
+
+// Idempotency — middleware маршрута для заголовка Idempotency-Key. Ключи разделены по пользователю из Identify,
+// у анонимных запросов — по адресу клиента. Анонимный повтор с другого адреса (например, мобильный клиент
+// сменил сеть) выполняется как новый запрос; клиентам, которым это важно, нужен токен пользователя.
+type Idempotency struct {
+	idempotencyService IdempotencyService
+}
+
+func NewIdempotency(idempotencyService IdempotencyService) *Idempotency {
+	return &Idempotency{
+		idempotencyService: idempotencyService,
+	}
+}
+
+// recordingWriter копирует тело ответа, чтобы сохранить его для повторов
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Handle выполняет запрос один раз на ключ: успешный ответ сохраняется и отдается повторам
+// с заголовком Idempotent-Replayed, неудачный освобождает ключ. Тот же ключ с другим телом — 409.
+func (m *Idempotency) Handle(ctx *wbgin.Context) {
+	key := ctx.GetHeader("Idempotency-Key")
+	if key == "" {
+		ctx.Next()
+		return
+	}
+	// тело читается до проверок обработчика, поэтому его размер ограничен тем же пределом
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxCommentBody))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	scope := authorKey(ctx)
+	saved, err := m.idempotencyService.Begin(scope, key, app.RequestHash(ctx.Request.Method, ctx.FullPath(), body))
+	if writeContentError(ctx, err) {
+		ctx.Abort()
+		return
+	}
+	if errors.Is(err, app.ErrIdempotencyMismatch) || errors.Is(err, app.ErrIdempotencyInFlight) {
+		ctx.AbortWithStatusJSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if saved != nil {
+		ctx.Header("Idempotent-Replayed", "true")
+		ctx.Data(saved.Status, "application/json; charset=utf-8", saved.Body)
+		ctx.Abort()
+		return
+	}
+
+	writer := &recordingWriter{ResponseWriter: ctx.Writer}
+	ctx.Writer = writer
+	ctx.Next()
+
+	status := writer.Status()
+	if status >= 200 && status < 300 {
+		err = m.idempotencyService.Complete(scope, key, app.IdempotentResponse{Status: status, Body: writer.body.Bytes()})
+	} else {
+		err = m.idempotencyService.Release(scope, key)
+	}
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Str("key", key).Msg("failed to store idempotency key")
+	}
+}
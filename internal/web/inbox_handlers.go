@@ -0,0 +1,137 @@
+package web
+
+import (
+	"commentTree/internal/app/domain"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"net/http"
+	"strconv"
+)
+
+type InboxReqRead struct {
+	CommentIds []string `json:"comment_ids" binding:"omitempty,dive,uuid"`
+}
+
+type InboxHandler struct {
+	inboxService InboxService
+}
+
+type InboxService interface {
+	Subscribe(userID, commentID string) error
+	Unsubscribe(userID, commentID string) error
+	GetInbox(userID string, unreadOnly bool, page, pageSize int) (*app.Inbox, error)
+	MarkRead(userID string, commentIDs []string) (int64, error)
+}
+
+func NewInboxHandler(inboxService InboxService) *InboxHandler {
+	return &InboxHandler{
+		inboxService: inboxService,
+	}
+}
+
+// SubscribeThread godoc
+// @Summary      Subscribe To Thread
+// @Description  Подписывает текущего пользователя на ветку: новые комментарии под ней попадут во входящие
+// @Tags         inbox
+// @Param        Authorization  header  string  true  "Bearer <token>"
+// @Param        id             path    string  true  "Comment ID (root of the subtree)"
+// @Success      204  {string}  string         "Subscribed"
+// @Failure      401  {object}  ErrorResponse  "Unknown or missing user"
+// @Failure      404  {object}  ErrorResponse  "Comment not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /comments/{id}/subscription [post]
+func (h *InboxHandler) SubscribeThread(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	if err := h.inboxService.Subscribe(user.ID.String(), ctx.Param("id")); err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// UnsubscribeThread godoc
+// @Summary      Unsubscribe From Thread
+// @Tags         inbox
+// @Param        Authorization  header  string  true  "Bearer <token>"
+// @Param        id             path    string  true  "Comment ID"
+// @Success      204  {string}  string         "Unsubscribed"
+// @Failure      401  {object}  ErrorResponse  "Unknown or missing user"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /comments/{id}/subscription [delete]
+func (h *InboxHandler) UnsubscribeThread(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	if err := h.inboxService.Unsubscribe(user.ID.String(), ctx.Param("id")); err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetInbox godoc
+// @Summary      Personal Inbox
+// @Description  Новые комментарии в ветках, на которые подписан пользователь, новые сверху. unread=false включает прочитанные.
+// @Tags         inbox
+// @Produce      json
+// @Param        Authorization  header  string  true   "Bearer <token>"
+// @Param        unread         query   bool    false  "Только непрочитанные" default(true)
+// @Param        page           query   int     false  "Номер страницы" default(1)
+// @Param        page_size      query   int     false  "Размер страницы" default(50)
+// @Success      200  {object}  app.Inbox      "Inbox page"
+// @Failure      401  {object}  ErrorResponse  "Unknown or missing user"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /me/inbox [get]
+func (h *InboxHandler) GetInbox(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	unreadOnly := ctx.Query("unread") != "false"
+	pageInt, _ := strconv.Atoi(ctx.Query("page"))
+	pageSizeInt, _ := strconv.Atoi(ctx.Query("page_size"))
+
+	inbox, err := h.inboxService.GetInbox(user.ID.String(), unreadOnly, pageInt, pageSizeInt)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, inbox)
+}
+
+// MarkInboxRead godoc
+// @Summary      Mark Inbox As Read
+// @Description  Отмечает прочитанными comment_ids, а без них — всю ленту. Возвращает число оставшихся непрочитанных.
+// @Tags         inbox
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string        true   "Bearer <token>"
+// @Param        read           body    InboxReqRead  false  "Comments to mark"
+// @Success      200  {object}  map[string]int64  "unread_count"
+// @Failure      400  {object}  ErrorResponse     "Invalid input data"
+// @Failure      401  {object}  ErrorResponse     "Unknown or missing user"
+// @Failure      503  {object}  ErrorResponse     "Service unavailable (DB error)"
+// @Router       /me/inbox/read [post]
+func (h *InboxHandler) MarkInboxRead(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	var req InboxReqRead
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	unread, err := h.inboxService.MarkRead(user.ID.String(), req.CommentIds)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, wbgin.H{"unread_count": unread})
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"commentTree/internal/app/domain"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"net/http"
+	"strconv"
+)
+
+type JobHandler struct {
+	jobService JobService
+}
+
+type JobService interface {
+	GetFailedJobs(page, pageSize int) ([]app.Job, error)
+	RetryJob(id string) (*app.Job, error)
+	DiscardJob(id string) error
+}
+
+func NewJobHandler(jobService JobService) *JobHandler {
+	return &JobHandler{
+		jobService: jobService,
+	}
+}
+
+// GetFailedJobs godoc
+// @Summary      List Failed Jobs
+// @Description  Фоновые задачи, исчерпавшие попытки, новые сверху
+// @Tags         admin
+// @Produce      json
+// @Param        page       query  int  false  "Номер страницы" default(1)
+// @Param        page_size  query  int  false  "Размер страницы" default(50)
+// @Success      200  {array}   app.Job        "Failed jobs"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /admin/jobs [get]
+func (h *JobHandler) GetFailedJobs(ctx *wbgin.Context) {
+	pageInt, _ := strconv.Atoi(ctx.Query("page"))
+	pageSizeInt, _ := strconv.Atoi(ctx.Query("page_size"))
+
+	jobs, err := h.jobService.GetFailedJobs(pageInt, pageSizeInt)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, jobs)
+}
+
+// RetryJob godoc
+// @Summary      Retry Failed Job
+// @Description  Возвращает упавшую задачу в очередь с обнуленным счетчиком попыток
+// @Tags         admin
+// @Produce      json
+// @Param        id   path  string  true  "Job ID"
+// @Success      200  {object}  app.Job        "Requeued job"
+// @Failure      404  {object}  ErrorResponse  "Failed job not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /admin/jobs/{id}/retry [post]
+func (h *JobHandler) RetryJob(ctx *wbgin.Context) {
+	job, err := h.jobService.RetryJob(ctx.Param("id"))
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, job)
+}
+
+// DiscardJob godoc
+// @Summary      Discard Failed Job
+// @Tags         admin
+// @Param        id   path  string  true  "Job ID"
+// @Success      204  {string}  string         "Job discarded"
+// @Failure      404  {object}  ErrorResponse  "Failed job not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /admin/jobs/{id} [delete]
+func (h *JobHandler) DiscardJob(ctx *wbgin.Context) {
+	if err := h.jobService.DiscardJob(ctx.Param("id")); err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,313 @@
+package web
+
+import (
+	"commentTree/internal/app/domain"
+	"encoding/json"
+	"errors"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ModerationReqDecision struct {
+	CommentIDs []string `json:"comment_ids" binding:"required"`
+	Action     string   `json:"action" binding:"required"`
+	Reason     string   `json:"reason"`
+}
+
+// ModerationReqThread — настройки ветки; меняются только переданные поля
+type ModerationReqThread struct {
+	Premoderation   *bool `json:"premoderation"`
+	SlowModeSeconds *int  `json:"slow_mode_seconds"`
+}
+
+// ModerationReqMove — новый родитель комментария; null делает комментарий корнем новой ветки.
+// Поле обязательно, чтобы пустое тело случайно не вынесло ветку в корень.
+type ModerationReqMove struct {
+	ParentID json.RawMessage `json:"parent_id" swaggertype:"string" example:"5b0c1e9a-7a39-4c43-9b84-4f1bb1d2c7a1"`
+}
+
+type ModerationReqMerge struct {
+	Into string `json:"into" binding:"required"`
+}
+
+type ModerationHandler struct {
+	moderationService ModerationService
+}
+
+type ModerationService interface {
+	GetFilterHits(action, commentID string, page, pageSize int) ([]app.FilterHit, error)
+	GetQueue(status string, page, pageSize int) ([]app.Comment, error)
+	Moderate(ids []string, action, reason string) (*app.ModerationResult, error)
+	SetPremoderation(commentID string, enabled bool) error
+	SetSlowMode(commentID string, interval time.Duration) error
+	GetUserTrust(userID string) (*app.Trust, error)
+	MoveComment(id, parentID, actorID string) (*app.CommentMove, error)
+	SplitThread(id, actorID string) (*app.ThreadSplit, error)
+	MergeThreads(id, into, actorID string) (*app.ThreadMerge, error)
+}
+
+func NewModerationHandler(moderationService ModerationService) *ModerationHandler {
+	return &ModerationHandler{
+		moderationService: moderationService,
+	}
+}
+
+// GetFilterHits godoc
+// @Summary      List Filter Hits
+// @Description  Какие фильтры содержимого сработали и почему, новые сверху. У отклоненных комментариев comment_id пуст.
+// @Tags         admin
+// @Produce      json
+// @Param        action      query  string  false  "flag | reject"
+// @Param        comment_id  query  string  false  "Comment ID"
+// @Param        page        query  int     false  "Номер страницы" default(1)
+// @Param        page_size   query  int     false  "Размер страницы" default(50)
+// @Success      200  {array}   app.FilterHit            "Filter hits"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid filter"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /admin/moderation/filter-hits [get]
+func (h *ModerationHandler) GetFilterHits(ctx *wbgin.Context) {
+	pageInt, _ := strconv.Atoi(ctx.Query("page"))
+	pageSizeInt, _ := strconv.Atoi(ctx.Query("page_size"))
+
+	hits, err := h.moderationService.GetFilterHits(ctx.Query("action"), ctx.Query("comment_id"), pageInt, pageSizeInt)
+	if writeContentError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, hits)
+}
+
+// GetQueue godoc
+// @Summary      Moderation Queue
+// @Description  Комментарии, ожидающие модерации (или скрытые и отклоненные), старые сверху
+// @Tags         admin
+// @Produce      json
+// @Param        status     query  string  false  "pending | hidden | rejected" default(pending)
+// @Param        page       query  int     false  "Номер страницы" default(1)
+// @Param        page_size  query  int     false  "Размер страницы" default(50)
+// @Success      200  {array}   app.Comment              "Comments"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid status"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /admin/moderation/queue [get]
+func (h *ModerationHandler) GetQueue(ctx *wbgin.Context) {
+	pageInt, _ := strconv.Atoi(ctx.Query("page"))
+	pageSizeInt, _ := strconv.Atoi(ctx.Query("page_size"))
+
+	comments, err := h.moderationService.GetQueue(ctx.Query("status"), pageInt, pageSizeInt)
+	if writeContentError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, comments)
+}
+
+// Moderate godoc
+// @Summary      Moderate Comments
+// @Description  Одобряет, отклоняет или скрывает комментарии. Комментарии в неподходящем состоянии попадают в skipped.
+// @Description  approve: pending, hidden, rejected -> active; reject: pending -> rejected; hide: active -> hidden.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        decision  body  ModerationReqDecision  true  "Decision"
+// @Success      200  {object}  app.ModerationResult     "Result"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid input data"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /admin/moderation/decisions [post]
+func (h *ModerationHandler) Moderate(ctx *wbgin.Context) {
+	var req ModerationReqDecision
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.moderationService.Moderate(req.CommentIDs, req.Action, req.Reason)
+	if writeContentError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// SetThreadSettings godoc
+// @Summary      Thread Settings
+// @Description  Премодерация и медленный режим для всей ветки, в которой находится комментарий.
+// @Description  slow_mode_seconds — не чаще одного комментария автора за столько секунд, 0 выключает режим.
+// @Tags         admin
+// @Accept       json
+// @Param        id      path  string               true  "Comment ID"
+// @Param        thread  body  ModerationReqThread  true  "Settings"
+// @Success      204  "Updated"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid input data"
+// @Failure      404  {object}  ErrorResponse  "Comment not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /admin/moderation/threads/{id} [put]
+func (h *ModerationHandler) SetThreadSettings(ctx *wbgin.Context) {
+	var req ModerationReqThread
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Premoderation == nil && req.SlowModeSeconds == nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "premoderation or slow_mode_seconds is required"})
+		return
+	}
+
+	if req.Premoderation != nil {
+		if err := h.moderationService.SetPremoderation(ctx.Param("id"), *req.Premoderation); err != nil {
+			writeLookupError(ctx, err)
+			return
+		}
+	}
+	if req.SlowModeSeconds != nil {
+		err := h.moderationService.SetSlowMode(ctx.Param("id"), time.Duration(*req.SlowModeSeconds)*time.Second)
+		if writeContentError(ctx, err) {
+			return
+		}
+		if err != nil {
+			writeLookupError(ctx, err)
+			return
+		}
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetUserTrust godoc
+// @Summary      User Trust Level
+// @Description  Текущий уровень доверия автора, показатели, из которых он получен, и привилегии уровня
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "User ID"
+// @Success      200  {object}  app.Trust      "Trust level"
+// @Failure      404  {object}  ErrorResponse  "User not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /admin/moderation/users/{id}/trust [get]
+func (h *ModerationHandler) GetUserTrust(ctx *wbgin.Context) {
+	trust, err := h.moderationService.GetUserTrust(ctx.Param("id"))
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, trust)
+}
+
+// MoveComment godoc
+// @Summary      Move Comment
+// @Description  Переносит комментарий вместе с ответами под другого родителя, в том числе в другую ветку;
+// @Description  parent_id = null делает комментарий корнем новой ветки. Перенос под себя или свой ответ — 409,
+// @Description  под скрытый, ожидающий модерации или отклоненный комментарий — 404.
+// @Description  Старая и новая ветки получают событие comment.moved, перенос пишется в журнал аудита.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path  string             true  "Comment ID"
+// @Param        move  body  ModerationReqMove  true  "New parent"
+// @Success      200  {object}  app.CommentMove          "Move"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid parent"
+// @Failure      404  {object}  ErrorResponse            "Comment or published parent not found"
+// @Failure      409  {object}  ErrorResponse            "Move would create a cycle"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /admin/comments/{id}/move [post]
+func (h *ModerationHandler) MoveComment(ctx *wbgin.Context) {
+	var req ModerationReqMove
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+	if len(req.ParentID) == 0 {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "parent_id is required, use null to move to the root"})
+		return
+	}
+	var parentID *string
+	if err := json.Unmarshal(req.ParentID, &parentID); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "parent_id must be a string or null"})
+		return
+	}
+	newParentID := ""
+	if parentID != nil {
+		newParentID = *parentID
+	}
+
+	move, err := h.moderationService.MoveComment(ctx.Param("id"), newParentID, currentUserID(ctx))
+	if writeMoveError(ctx, err) {
+		return
+	}
+	ctx.JSON(http.StatusOK, move)
+}
+
+// SplitThread godoc
+// @Summary      Split Thread
+// @Description  Выносит комментарий вместе с ответами в отдельную ветку. На его прежнем месте остается служебный
+// @Description  комментарий без автора со ссылкой на новую ветку. Корень ветки разделить нельзя — 409.
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "Comment ID"
+// @Success      200  {object}  app.ThreadSplit  "New thread and link"
+// @Failure      404  {object}  ErrorResponse    "Comment not found"
+// @Failure      409  {object}  ErrorResponse    "Comment is already a thread root"
+// @Failure      503  {object}  ErrorResponse    "Service unavailable (DB error)"
+// @Router       /admin/comments/{id}/split [post]
+func (h *ModerationHandler) SplitThread(ctx *wbgin.Context) {
+	split, err := h.moderationService.SplitThread(ctx.Param("id"), currentUserID(ctx))
+	if writeMoveError(ctx, err) {
+		return
+	}
+	ctx.JSON(http.StatusOK, split)
+}
+
+// MergeThreads godoc
+// @Summary      Merge Threads
+// @Description  Переносит ответы корня ветки комментария id под корень ветки комментария into. Запросы дерева
+// @Description  исходной ветки после этого перенаправляются на целевую, а ее корень удаляется с событием comment.deleted.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id     path  string              true  "Any comment of the source thread"
+// @Param        merge  body  ModerationReqMerge  true  "Any comment of the target thread"
+// @Success      200  {object}  app.ThreadMerge          "Merge"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid target"
+// @Failure      404  {object}  ErrorResponse            "Comment not found"
+// @Failure      409  {object}  ErrorResponse            "Comments are already in the same thread"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /admin/comments/{id}/merge [post]
+func (h *ModerationHandler) MergeThreads(ctx *wbgin.Context) {
+	var req ModerationReqMerge
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	merge, err := h.moderationService.MergeThreads(ctx.Param("id"), req.Into, currentUserID(ctx))
+	if writeMoveError(ctx, err) {
+		return
+	}
+	ctx.JSON(http.StatusOK, merge)
+}
+
+// writeMoveError отвечает на ошибку переноса, разделения или слияния: 400 на неверные данные,
+// 409 на невозможную операцию, 404 на отсутствующий комментарий. Возвращает false, если ошибки нет.
+func writeMoveError(ctx *wbgin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if writeContentError(ctx, err) {
+		return true
+	}
+	if errors.Is(err, app.ErrMoveCycle) || errors.Is(err, app.ErrAlreadyThread) || errors.Is(err, app.ErrSameThread) {
+		ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return true
+	}
+	writeLookupError(ctx, err)
+	return true
+}
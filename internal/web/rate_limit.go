@@ -0,0 +1,161 @@
+package web
+
+import (
+	"bytes"
+	"commentTree/internal/config"
+	"commentTree/internal/ratelimit"
+	"encoding/json"
+	"errors"
+	"fmt"
+	wbgin "github.com/wb-go/wbf/ginext"
+	wbzlog "github.com/wb-go/wbf/zlog"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ThreadResolver находит корень ветки, в которой находится комментарий
+type ThreadResolver interface {
+	ThreadRoot(commentID string) (string, error)
+}
+
+type routeLimits struct {
+	author ratelimit.Rule
+	ip     ratelimit.Rule
+	thread ratelimit.Rule
+}
+
+// RateLimiter ограничивает частоту запросов к маршрутам из конфигурации по автору, адресу и ветке
+type RateLimiter struct {
+	limiter ratelimit.Limiter
+	threads ThreadResolver
+	routes  map[string]routeLimits
+}
+
+func NewRateLimiter(limiter ratelimit.Limiter, threads ThreadResolver, cfg *config.AppConfig) *RateLimiter {
+	routes := make(map[string]routeLimits, len(cfg.RateLimitConfig.Routes))
+	for _, r := range cfg.RateLimitConfig.Routes {
+		routes[r.Method+" "+r.Path] = routeLimits{
+			author: rateRule(r.Author),
+			ip:     rateRule(r.IP),
+			thread: rateRule(r.Thread),
+		}
+	}
+	return &RateLimiter{
+		limiter: limiter,
+		threads: threads,
+		routes:  routes,
+	}
+}
+
+func rateRule(c config.RateRuleConfig) ratelimit.Rule {
+	return ratelimit.Rule{Rate: c.Rate, Per: c.Per, Burst: c.Burst}
+}
+
+// Handle — middleware группы после Identify: автор уже проверен. Сначала проверяются дешевые корзины
+// автора и адреса, ветка из базы определяется только для прошедших их запросов.
+// Ошибка хранилища лимитов не блокирует запрос.
+func (l *RateLimiter) Handle(ctx *wbgin.Context) {
+	route := ctx.Request.Method + " " + ctx.FullPath()
+	limits, ok := l.routes[route]
+	if !ok {
+		ctx.Next()
+		return
+	}
+
+	if !l.allow(ctx, route, "author", authorKey(ctx), limits.author) || !l.allow(ctx, route, "ip", ctx.ClientIP(), limits.ip) {
+		return
+	}
+	if limits.thread.Enabled() {
+		thread, err := l.threadKey(ctx)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if !l.allow(ctx, route, "thread", thread, limits.thread) {
+			return
+		}
+	}
+	ctx.Next()
+}
+
+// allow списывает запрос из корзины scope:key и отвечает 429, если она пуста
+func (l *RateLimiter) allow(ctx *wbgin.Context, route, scope, key string, rule ratelimit.Rule) bool {
+	if key == "" || !rule.Enabled() {
+		return true
+	}
+	res, err := l.limiter.Allow(ctx.Request.Context(), fmt.Sprintf("%s:%s:%s", route, scope, key), rule)
+	if err != nil {
+		wbzlog.Logger.Error().Err(err).Str("route", route).Msg("rate limiter unavailable")
+		return true
+	}
+	if !res.Allowed {
+		writeRetryAfter(ctx, res.RetryAfter)
+		ctx.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{Error: "too many requests"})
+		return false
+	}
+	return true
+}
+
+// authorKey — пользователь из Identify или адрес анонимного клиента
+func authorKey(ctx *wbgin.Context) string {
+	if user := currentUser(ctx); user != nil {
+		return "user:" + user.ID.String()
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+// threadKey — корень ветки из :id маршрута или parent_id тела запроса; пусто для новой ветки
+func (l *RateLimiter) threadKey(ctx *wbgin.Context) (string, error) {
+	id := ctx.Param("id")
+	if id == "" {
+		parentID, err := peekParentID(ctx)
+		if err != nil {
+			return "", err
+		}
+		id = parentID
+	}
+	if id == "" {
+		return "", nil
+	}
+	root, err := l.threads.ThreadRoot(id)
+	if err != nil {
+		return id, nil
+	}
+	return root, nil
+}
+
+// peekParentID читает parent_id из JSON-тела не больше maxCommentBody, оставляя тело для обработчика
+func peekParentID(ctx *wbgin.Context) (string, error) {
+	if ctx.Request.Body == nil {
+		return "", nil
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxCommentBody))
+	if err != nil {
+		return "", err
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	var req struct {
+		ParentId string `json:"parent_id"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return "", nil
+	}
+	return req.ParentId, nil
+}
+
+// writeRetryAfter выставляет Retry-After в целых секундах, не меньше одной
+func writeRetryAfter(ctx *wbgin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	ctx.Header("Retry-After", strconv.Itoa(seconds))
+}
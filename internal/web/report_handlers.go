@@ -0,0 +1,140 @@
+package web
+
+import (
+	"commentTree/internal/app/domain"
+	"errors"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"net/http"
+	"strconv"
+)
+
+type ReportReqCreate struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type VoteReq struct {
+	Value *int `json:"value" binding:"required"`
+}
+
+type ReportHandler struct {
+	reportService ReportService
+}
+
+type ReportService interface {
+	Report(commentID, reporterID, reason string) (*app.Report, error)
+	GetReports(page, pageSize int) ([]app.ReportSummary, error)
+	Vote(commentID, voterID string, value int) (*app.CommentScore, error)
+}
+
+func NewReportHandler(reportService ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+	}
+}
+
+// ReportComment godoc
+// @Summary      Report Comment
+// @Description  Жалоба текущего пользователя на комментарий. От одного пользователя хранится одна жалоба, повторная меняет категорию.
+// @Description  В ответе только собственная жалоба: чужие жалобы пользователям не видны.
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string           true  "Bearer <token>"
+// @Param        id             path    string           true  "Comment ID"
+// @Param        report         body    ReportReqCreate  true  "spam | abuse | off_topic"
+// @Success      201  {object}  app.Report               "Report"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid reason"
+// @Failure      401  {object}  ErrorResponse            "Unknown or missing user"
+// @Failure      404  {object}  ErrorResponse            "Comment not found"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /comments/{id}/report [post]
+func (h *ReportHandler) ReportComment(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	var req ReportReqCreate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.reportService.Report(ctx.Param("id"), user.ID.String(), req.Reason)
+	if writeContentError(ctx, err) {
+		return
+	}
+	if errors.Is(err, app.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, wbgin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, report)
+}
+
+// VoteComment godoc
+// @Summary      Vote For Comment
+// @Description  Голос текущего пользователя за чужой комментарий: 1 или -1, 0 отзывает голос. Повторный голос заменяет прежний.
+// @Description  Сумма голосов за комментарии автора учитывается в его уровне доверия.
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string   true  "Bearer <token>"
+// @Param        id             path    string   true  "Comment ID"
+// @Param        vote           body    VoteReq  true  "-1 | 0 | 1"
+// @Success      200  {object}  app.CommentScore         "Comment score"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid value"
+// @Failure      401  {object}  ErrorResponse            "Unknown or missing user"
+// @Failure      403  {object}  ErrorResponse            "Own comment"
+// @Failure      404  {object}  ErrorResponse            "Comment not found"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /comments/{id}/vote [post]
+func (h *ReportHandler) VoteComment(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	var req VoteReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	score, err := h.reportService.Vote(ctx.Param("id"), user.ID.String(), *req.Value)
+	if writeContentError(ctx, err) {
+		return
+	}
+	if errors.Is(err, app.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, wbgin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, score)
+}
+
+// GetReports godoc
+// @Summary      Reported Comments
+// @Description  Комментарии с жалобами: взвешенная сумма, число жалоб по категориям. Самые серьезные сверху.
+// @Tags         admin
+// @Produce      json
+// @Param        page       query  int  false  "Номер страницы" default(1)
+// @Param        page_size  query  int  false  "Размер страницы" default(50)
+// @Success      200  {array}   app.ReportSummary  "Reported comments"
+// @Failure      503  {object}  ErrorResponse      "Service unavailable (DB error)"
+// @Router       /admin/moderation/reports [get]
+func (h *ReportHandler) GetReports(ctx *wbgin.Context) {
+	pageInt, _ := strconv.Atoi(ctx.Query("page"))
+	pageSizeInt, _ := strconv.Atoi(ctx.Query("page_size"))
+
+	summaries, err := h.reportService.GetReports(pageInt, pageSizeInt)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, summaries)
+}
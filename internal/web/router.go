@@ -6,12 +6,74 @@ import (
 	wbgin "github.com/wb-go/wbf/ginext"
 )
 
-func RegisterRoutes(engine *wbgin.Engine, handler *CommentHandler) {
+func RegisterRoutes(engine *wbgin.Engine, handler *CommentHandler, searchHandler *SavedSearchHandler, streamHandler *StreamHandler, changesHandler *ChangesHandler, webhookHandler *WebhookHandler, jobHandler *JobHandler, userHandler *UserHandler, inboxHandler *InboxHandler, moderationHandler *ModerationHandler, reportHandler *ReportHandler, banHandler *BanHandler, challengeHandler *ChallengeHandler, idempotency *Idempotency, rateLimiter *RateLimiter) {
 	api := engine.Group("/api")
+	// Лимиты после Identify: корзина автора ключуется проверенным пользователем, а не заголовком
+	api.Use(userHandler.Identify, rateLimiter.Handle)
 	{
-		api.POST("/comments", handler.CreateComment)
+		api.GET("/challenge", challengeHandler.IssueChallenge)
+		// Повтор с ключом идемпотентности отдается до проверки proof-of-work: решение уже погашено первым запросом
+		api.POST("/comments", idempotency.Handle, challengeHandler.RequireProof, handler.CreateComment)
 		api.GET("/comments", handler.GetComments)
+		api.GET("/comments/stream", streamHandler.StreamComments)
+		api.GET("/comments/changes", changesHandler.GetChanges)
+		api.GET("/comments/delta", changesHandler.GetDelta)
+		api.GET("/comments/:id", handler.GetComment)
+		api.GET("/comments/:id/ancestors", handler.GetAncestors)
+		api.GET("/comments/:id/context", handler.GetContext)
+		api.PUT("/comments/:id", handler.EditComment)
 		api.DELETE("/comments/:id", handler.DeleteComments)
+		api.POST("/comments/:id/subscription", inboxHandler.SubscribeThread)
+		api.DELETE("/comments/:id/subscription", inboxHandler.UnsubscribeThread)
+		api.POST("/comments/:id/report", reportHandler.ReportComment)
+		api.POST("/comments/:id/vote", reportHandler.VoteComment)
+
+		api.POST("/users", userHandler.CreateUser)
+		api.GET("/users/:id", userHandler.GetUser)
+		api.GET("/users/:id/mentions", userHandler.GetMentions)
+		api.GET("/me", userHandler.GetMe)
+		api.PUT("/me/notifications", userHandler.UpdateNotifyMode)
+		api.GET("/me/inbox", inboxHandler.GetInbox)
+		api.POST("/me/inbox/read", inboxHandler.MarkInboxRead)
+		api.GET("/me/mutes", userHandler.GetMutes)
+		api.POST("/me/mutes", userHandler.MuteUser)
+		api.DELETE("/me/mutes/:id", userHandler.UnmuteUser)
+		api.GET("/unsubscribe", userHandler.Unsubscribe)
+		api.POST("/unsubscribe", userHandler.Unsubscribe)
+
+		admin := api.Group("/admin", userHandler.RequireModerator)
+		admin.GET("/jobs", jobHandler.GetFailedJobs)
+		admin.POST("/jobs/:id/retry", jobHandler.RetryJob)
+		admin.DELETE("/jobs/:id", jobHandler.DiscardJob)
+		admin.GET("/moderation/filter-hits", moderationHandler.GetFilterHits)
+		admin.GET("/moderation/queue", moderationHandler.GetQueue)
+		admin.POST("/moderation/decisions", moderationHandler.Moderate)
+		admin.PUT("/moderation/threads/:id", moderationHandler.SetThreadSettings)
+		admin.GET("/moderation/reports", reportHandler.GetReports)
+		admin.POST("/comments/:id/move", moderationHandler.MoveComment)
+		admin.POST("/comments/:id/split", moderationHandler.SplitThread)
+		admin.POST("/comments/:id/merge", moderationHandler.MergeThreads)
+		admin.GET("/moderation/users/:id/trust", moderationHandler.GetUserTrust)
+		admin.POST("/bans", banHandler.CreateBan)
+		admin.GET("/bans", banHandler.GetBans)
+		admin.DELETE("/bans/:id", banHandler.RevokeBan)
+		admin.GET("/users/:id/mutes", userHandler.GetUserMutes)
+		admin.GET("/audit", banHandler.GetAuditLog)
+		// Сохраненные поиски — инструмент модерации: алерты находят и скрытые комментарии
+		admin.POST("/searches", searchHandler.CreateSavedSearch)
+		admin.GET("/searches", searchHandler.GetSavedSearches)
+		admin.GET("/searches/:id", searchHandler.GetSavedSearch)
+		admin.PUT("/searches/:id", searchHandler.UpdateSavedSearch)
+		admin.DELETE("/searches/:id", searchHandler.DeleteSavedSearch)
+		admin.GET("/alerts", searchHandler.GetAlerts)
+		// Вебхуки получают тексты скрытых и отклоненных комментариев, поэтому управляют ими только модераторы
+		admin.POST("/webhooks", webhookHandler.CreateWebhook)
+		admin.GET("/webhooks", webhookHandler.GetWebhooks)
+		admin.GET("/webhooks/:id", webhookHandler.GetWebhook)
+		admin.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+		admin.GET("/webhooks/:id/deliveries", webhookHandler.GetDeliveries)
+		admin.POST("/webhooks/:id/deliveries/:delivery_id/replay", webhookHandler.ReplayDelivery)
+
 		api.GET("/swagger/*any", func(c *wbgin.Context) {
 			httpSwagger.WrapHandler(c.Writer, c.Request)
 		})
@@ -0,0 +1,193 @@
+package web
+
+import (
+	"commentTree/internal/app/domain"
+	"errors"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"net/http"
+	"strconv"
+)
+
+type SavedSearchReq struct {
+	Query      string `json:"query" binding:"required"`
+	ParentId   string `json:"parent_id"`
+	WebhookURL string `json:"webhook_url"`
+}
+
+type SavedSearchHandler struct {
+	searchService SavedSearchService
+}
+
+type SavedSearchService interface {
+	CreateSavedSearch(query, parentID, webhookURL string) (*app.SavedSearch, error)
+	UpdateSavedSearch(id, query, parentID, webhookURL string) (*app.SavedSearch, error)
+	DeleteSavedSearch(id string) error
+	GetSavedSearch(id string) (*app.SavedSearch, error)
+	GetSavedSearches() ([]app.SavedSearch, error)
+	GetAlerts(searchID string, page, pageSize int) ([]app.Alert, error)
+}
+
+func NewSavedSearchHandler(searchService SavedSearchService) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		searchService: searchService,
+	}
+}
+
+// CreateSavedSearch godoc
+// @Summary      Create Saved Search
+// @Description  Сохраняет поисковый запрос; новые комментарии, подходящие под него, создают алерты
+// @Tags         searches
+// @Accept       json
+// @Produce      json
+// @Param        search  body  SavedSearchReq  true  "Saved search to create"
+// @Success      201  {object}  app.SavedSearch  "Created saved search"
+// @Failure      400  {object}  ErrorResponse    "Invalid input data"
+// @Failure      503  {object}  ErrorResponse    "Service unavailable (DB error)"
+// @Router       /admin/searches [post]
+func (h *SavedSearchHandler) CreateSavedSearch(ctx *wbgin.Context) {
+	var req SavedSearchReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	search, err := h.searchService.CreateSavedSearch(req.Query, req.ParentId, req.WebhookURL)
+	if errors.Is(err, app.ErrWebhookTarget) {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, search)
+}
+
+// UpdateSavedSearch godoc
+// @Summary      Update Saved Search
+// @Description  Изменяет сохраненный поисковый запрос
+// @Tags         searches
+// @Accept       json
+// @Produce      json
+// @Param        id      path  string          true  "Saved search ID"
+// @Param        search  body  SavedSearchReq  true  "Saved search data"
+// @Success      200  {object}  app.SavedSearch  "Updated saved search"
+// @Failure      400  {object}  ErrorResponse    "Invalid input data"
+// @Failure      404  {object}  ErrorResponse    "Saved search not found"
+// @Failure      503  {object}  ErrorResponse    "Service unavailable (DB error)"
+// @Router       /admin/searches/{id} [put]
+func (h *SavedSearchHandler) UpdateSavedSearch(ctx *wbgin.Context) {
+	id := ctx.Param("id")
+	if id == "" {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "id is required"})
+		return
+	}
+	var req SavedSearchReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	search, err := h.searchService.UpdateSavedSearch(id, req.Query, req.ParentId, req.WebhookURL)
+	if errors.Is(err, app.ErrWebhookTarget) {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, search)
+}
+
+// DeleteSavedSearch godoc
+// @Summary      Delete Saved Search
+// @Description  Удаляет сохраненный поисковый запрос вместе с его алертами
+// @Tags         searches
+// @Produce      json
+// @Param        id   path   string  true  "Saved search ID"
+// @Success      204  {string}  string  "Saved search deleted successfully"
+// @Failure      400  {object}  ErrorResponse  "Invalid saved search ID"
+// @Failure      404  {object}  ErrorResponse  "Saved search not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /admin/searches/{id} [delete]
+func (h *SavedSearchHandler) DeleteSavedSearch(ctx *wbgin.Context) {
+	id := ctx.Param("id")
+	if id == "" {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "id is required"})
+		return
+	}
+
+	err := h.searchService.DeleteSavedSearch(id)
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetSavedSearch godoc
+// @Summary      Get Saved Search
+// @Description  Возвращает сохраненный поисковый запрос по ID
+// @Tags         searches
+// @Produce      json
+// @Param        id   path   string  true  "Saved search ID"
+// @Success      200  {object}  app.SavedSearch  "Saved search"
+// @Failure      404  {object}  ErrorResponse    "Saved search not found"
+// @Failure      503  {object}  ErrorResponse    "Service unavailable (DB error)"
+// @Router       /admin/searches/{id} [get]
+func (h *SavedSearchHandler) GetSavedSearch(ctx *wbgin.Context) {
+	search, err := h.searchService.GetSavedSearch(ctx.Param("id"))
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, search)
+}
+
+// GetSavedSearches godoc
+// @Summary      List Saved Searches
+// @Description  Возвращает все сохраненные поисковые запросы
+// @Tags         searches
+// @Produce      json
+// @Success      200  {array}   app.SavedSearch  "Saved searches"
+// @Failure      503  {object}  ErrorResponse    "Service unavailable (DB error)"
+// @Router       /admin/searches [get]
+func (h *SavedSearchHandler) GetSavedSearches(ctx *wbgin.Context) {
+	searches, err := h.searchService.GetSavedSearches()
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, searches)
+}
+
+// GetAlerts godoc
+// @Summary      List Alerts
+// @Description  Возвращает срабатывания сохраненных поисков, новые сверху
+// @Tags         searches
+// @Produce      json
+// @Param        search     query  string  false  "Saved search ID"
+// @Param        page       query  int     false  "Номер страницы" default(1)
+// @Param        page_size  query  int     false  "Размер страницы" default(50)
+// @Success      200  {array}   app.Alert      "Alerts"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /admin/alerts [get]
+func (h *SavedSearchHandler) GetAlerts(ctx *wbgin.Context) {
+	searchID := ctx.Query("search")
+	pageInt, _ := strconv.Atoi(ctx.Query("page"))
+	pageSizeInt, _ := strconv.Atoi(ctx.Query("page_size"))
+
+	alerts, err := h.searchService.GetAlerts(searchID, pageInt, pageSizeInt)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, alerts)
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"commentTree/internal/config"
+	"commentTree/internal/events"
+	"errors"
+	"github.com/gin-contrib/sse"
+	"github.com/google/uuid"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type StreamHandler struct {
+	stream    EventStream
+	heartbeat time.Duration
+}
+
+type EventStream interface {
+	Subscribe(rootID *uuid.UUID, lastEventID uint64) (*events.Subscription, []events.Message, error)
+	Unsubscribe(sub *events.Subscription)
+}
+
+func NewStreamHandler(stream EventStream, cfg *config.AppConfig) *StreamHandler {
+	heartbeat := cfg.StreamConfig.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	return &StreamHandler{
+		stream:    stream,
+		heartbeat: heartbeat,
+	}
+}
+
+// StreamComments godoc
+// @Summary      Comment Stream
+// @Description  Поток Server-Sent Events с событиями comment.created, comment.edited, comment.deleted для ветки parent.
+// @Description  Поддерживает возобновление по заголовку Last-Event-ID; если события уже вытеснены из буфера, приходит событие stream.reset.
+// @Tags         comments
+// @Produce      text/event-stream
+// @Param        parent         query   string  false  "ID корня ветки (если не указан — все комментарии)"
+// @Param        Last-Event-ID  header  string  false  "ID последнего полученного события"
+// @Success      200  {string}  string         "Event stream"
+// @Failure      400  {object}  ErrorResponse  "Invalid parent id"
+// @Router       /comments/stream [get]
+func (h *StreamHandler) StreamComments(ctx *wbgin.Context) {
+	var rootID *uuid.UUID
+	if parent := ctx.Query("parent"); parent != "" {
+		id, err := uuid.Parse(parent)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, wbgin.H{"error": "invalid parent id"})
+			return
+		}
+		rootID = &id
+	}
+
+	lastEventHeader := ctx.GetHeader("Last-Event-ID")
+	if lastEventHeader == "" {
+		lastEventHeader = ctx.Query("last_event_id")
+	}
+	lastEventID, _ := strconv.ParseUint(lastEventHeader, 10, 64)
+
+	reset := false
+	sub, missed, err := h.stream.Subscribe(rootID, lastEventID)
+	if errors.Is(err, events.ErrEventExpired) {
+		reset = true
+		sub, missed, err = h.stream.Subscribe(rootID, 0)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	defer h.stream.Unsubscribe(sub)
+
+	ctx.Writer.Header().Set("Content-Type", "text/event-stream")
+	ctx.Writer.Header().Set("Cache-Control", "no-cache")
+	ctx.Writer.Header().Set("Connection", "keep-alive")
+	ctx.Writer.Header().Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	if reset {
+		ctx.Render(-1, sse.Event{Event: "stream.reset", Data: wbgin.H{"reason": events.ErrEventExpired.Error()}})
+	}
+	for _, msg := range missed {
+		writeStreamMessage(ctx, msg)
+	}
+	ctx.Writer.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-sub.Done:
+			// клиент не успевал читать события — он переподключится с Last-Event-ID
+			return
+		case msg := <-sub.C:
+			writeStreamMessage(ctx, msg)
+			ctx.Writer.Flush()
+		case <-ticker.C:
+			if _, err := ctx.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		}
+	}
+}
+
+func writeStreamMessage(ctx *wbgin.Context, msg events.Message) {
+	ctx.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(msg.ID, 10),
+		Event: string(msg.Event.Type),
+		Data:  msg.Event,
+	})
+}
@@ -0,0 +1,361 @@
+package web
+
+import (
+	"commentTree/internal/app/domain"
+	"errors"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// userContextKey — ключ текущего пользователя в контексте запроса
+const userContextKey = "user"
+
+type UserReqCreate struct {
+	Name  string `json:"name" binding:"required"`
+	Email string `json:"email"`
+}
+
+type NotifyModeReq struct {
+	Mode string `json:"mode" binding:"required,oneof=immediate digest off"`
+}
+
+type MuteReq struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// UserCreated — ответ регистрации: token выдается один раз и передается в Authorization: Bearer
+type UserCreated struct {
+	*app.User
+	Token string `json:"token"`
+}
+
+type UserHandler struct {
+	userService UserService
+}
+
+type UserService interface {
+	CreateUser(name, email string) (*app.User, string, error)
+	GetUser(id string) (*app.User, error)
+	Authenticate(token string) (*app.User, error)
+	UpdateNotifyMode(id, mode string) error
+	Unsubscribe(token string) error
+	GetMentions(id string, page, pageSize int) ([]app.Comment, error)
+	Mute(userID, mutedID string) (*app.Mute, error)
+	Unmute(userID, mutedID string) error
+	GetMutes(userID string) ([]app.Mute, error)
+}
+
+func NewUserHandler(userService UserService) *UserHandler {
+	return &UserHandler{
+		userService: userService,
+	}
+}
+
+// Identify определяет пользователя по токену из заголовка Authorization: Bearer. Без заголовка запрос анонимный,
+// с неверным или неизвестным токеном — 401. Id пользователя публичен, поэтому личность по нему не принимается.
+func (h *UserHandler) Identify(ctx *wbgin.Context) {
+	header := ctx.GetHeader("Authorization")
+	if header == "" {
+		ctx.Next()
+		return
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, wbgin.H{"error": app.ErrUnauthorized.Error()})
+		return
+	}
+	user, err := h.userService.Authenticate(strings.TrimSpace(token))
+	if err != nil {
+		if errors.Is(err, app.ErrUnauthorized) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, wbgin.H{"error": app.ErrUnauthorized.Error()})
+			return
+		}
+		ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.Set(userContextKey, user)
+	ctx.Next()
+}
+
+// currentUser возвращает пользователя из Identify или nil для анонимного запроса
+func currentUser(ctx *wbgin.Context) *app.User {
+	if v, ok := ctx.Get(userContextKey); ok {
+		return v.(*app.User)
+	}
+	return nil
+}
+
+// currentUserID — id пользователя из Identify или пусто для анонимного запроса
+func currentUserID(ctx *wbgin.Context) string {
+	if user := currentUser(ctx); user != nil {
+		return user.ID.String()
+	}
+	return ""
+}
+
+// requireUser отвечает 401, если запрос анонимный
+func requireUser(ctx *wbgin.Context) (*app.User, bool) {
+	user := currentUser(ctx)
+	if user == nil {
+		ctx.JSON(http.StatusUnauthorized, wbgin.H{"error": "Authorization: Bearer token is required"})
+		return nil, false
+	}
+	return user, true
+}
+
+// RequireModerator пропускает к маршрутам /admin только модераторов: анонимному запросу — 401, остальным — 403
+func (h *UserHandler) RequireModerator(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		ctx.Abort()
+		return
+	}
+	if !user.IsModerator() {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, wbgin.H{"error": app.ErrForbidden.Error()})
+		return
+	}
+	ctx.Next()
+}
+
+// CreateUser godoc
+// @Summary      Create User
+// @Description  Регистрирует автора. Полученный token передается в заголовке Authorization: Bearer и больше не выдается.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        user  body  UserReqCreate  true  "User to create"
+// @Success      201  {object}  UserCreated    "Created user with access token"
+// @Failure      400  {object}  ErrorResponse  "Invalid input data"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /users [post]
+func (h *UserHandler) CreateUser(ctx *wbgin.Context) {
+	var req UserReqCreate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	user, token, err := h.userService.CreateUser(req.Name, req.Email)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, UserCreated{User: user, Token: token})
+}
+
+// GetUser godoc
+// @Summary      Get User
+// @Description  Публичный профиль пользователя, без email
+// @Tags         users
+// @Produce      json
+// @Param        id   path  string  true  "User ID"
+// @Success      200  {object}  app.User       "User"
+// @Failure      404  {object}  ErrorResponse  "User not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /users/{id} [get]
+func (h *UserHandler) GetUser(ctx *wbgin.Context) {
+	user, err := h.userService.GetUser(ctx.Param("id"))
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	public := *user
+	public.Email = ""
+	ctx.JSON(http.StatusOK, public)
+}
+
+// GetMentions godoc
+// @Summary      User Mentions
+// @Description  Комментарии, в которых упомянут пользователь, новые сверху
+// @Tags         users
+// @Produce      json
+// @Param        id         path   string  true   "User ID"
+// @Param        page       query  int     false  "Номер страницы" default(1)
+// @Param        page_size  query  int     false  "Размер страницы" default(50)
+// @Success      200  {array}   app.Comment    "Comments"
+// @Failure      404  {object}  ErrorResponse  "User not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /users/{id}/mentions [get]
+func (h *UserHandler) GetMentions(ctx *wbgin.Context) {
+	pageInt, _ := strconv.Atoi(ctx.Query("page"))
+	pageSizeInt, _ := strconv.Atoi(ctx.Query("page_size"))
+
+	comments, err := h.userService.GetMentions(ctx.Param("id"), pageInt, pageSizeInt)
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, comments)
+}
+
+// GetMe godoc
+// @Summary      Get Current User
+// @Tags         users
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer <token>"
+// @Success      200  {object}  app.User       "Current user"
+// @Failure      401  {object}  ErrorResponse  "Unknown or missing user"
+// @Router       /me [get]
+func (h *UserHandler) GetMe(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, user)
+}
+
+// UpdateNotifyMode godoc
+// @Summary      Update Notification Preferences
+// @Description  immediate — письмо на каждый ответ, digest — раз в день, off — без писем
+// @Tags         users
+// @Accept       json
+// @Param        Authorization  header  string         true  "Bearer <token>"
+// @Param        mode           body    NotifyModeReq  true  "Notification mode"
+// @Success      204  {string}  string         "Updated"
+// @Failure      400  {object}  ErrorResponse  "Invalid mode"
+// @Failure      401  {object}  ErrorResponse  "Unknown or missing user"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /me/notifications [put]
+func (h *UserHandler) UpdateNotifyMode(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	var req NotifyModeReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.UpdateNotifyMode(user.ID.String(), req.Mode); err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetMutes godoc
+// @Summary      Muted Authors
+// @Description  Авторы, комментарии которых текущий пользователь видит свернутыми
+// @Tags         users
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer <token>"
+// @Success      200  {array}   app.Mute       "Muted authors"
+// @Failure      401  {object}  ErrorResponse  "Unknown or missing user"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /me/mutes [get]
+func (h *UserHandler) GetMutes(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	h.writeMutes(ctx, user.ID.String())
+}
+
+// GetUserMutes godoc
+// @Summary      User Mute List
+// @Description  Список скрытых авторов пользователя для модератора
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "User ID"
+// @Success      200  {array}   app.Mute       "Muted authors"
+// @Failure      404  {object}  ErrorResponse  "User not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /admin/users/{id}/mutes [get]
+func (h *UserHandler) GetUserMutes(ctx *wbgin.Context) {
+	h.writeMutes(ctx, ctx.Param("id"))
+}
+
+func (h *UserHandler) writeMutes(ctx *wbgin.Context, userID string) {
+	mutes, err := h.userService.GetMutes(userID)
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, mutes)
+}
+
+// MuteUser godoc
+// @Summary      Mute Author
+// @Description  Комментарии автора будут приходить текущему пользователю с collapsed: true
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string   true  "Bearer <token>"
+// @Param        mute           body    MuteReq  true  "Author to mute"
+// @Success      201  {object}  app.Mute                 "Muted"
+// @Failure      400  {object}  ValidationErrorResponse  "Invalid input data"
+// @Failure      401  {object}  ErrorResponse            "Unknown or missing user"
+// @Failure      404  {object}  ErrorResponse            "Author not found"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /me/mutes [post]
+func (h *UserHandler) MuteUser(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	var req MuteReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	mute, err := h.userService.Mute(user.ID.String(), req.UserID)
+	if writeContentError(ctx, err) {
+		return
+	}
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, mute)
+}
+
+// UnmuteUser godoc
+// @Summary      Unmute Author
+// @Tags         users
+// @Param        Authorization  header  string  true  "Bearer <token>"
+// @Param        id             path    string  true  "Muted author ID"
+// @Success      204  {string}  string         "Unmuted"
+// @Failure      401  {object}  ErrorResponse  "Unknown or missing user"
+// @Failure      404  {object}  ErrorResponse  "Author is not muted"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /me/mutes/{id} [delete]
+func (h *UserHandler) UnmuteUser(ctx *wbgin.Context) {
+	user, ok := requireUser(ctx)
+	if !ok {
+		return
+	}
+	if err := h.userService.Unmute(user.ID.String(), ctx.Param("id")); err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Unsubscribe godoc
+// @Summary      Unsubscribe
+// @Description  Отключает уведомления по токену из письма. POST — one-click отписка почтового клиента (RFC 8058).
+// @Tags         users
+// @Param        token  query  string  true  "Unsubscribe token"
+// @Success      204  {string}  string         "Unsubscribed"
+// @Failure      400  {object}  ErrorResponse  "Invalid token"
+// @Failure      404  {object}  ErrorResponse  "User not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /unsubscribe [get]
+// @Router       /unsubscribe [post]
+func (h *UserHandler) Unsubscribe(ctx *wbgin.Context) {
+	err := h.userService.Unsubscribe(ctx.Query("token"))
+	if errors.Is(err, app.ErrInvalidUnsubscribe) {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
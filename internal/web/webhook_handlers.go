@@ -0,0 +1,169 @@
+package web
+
+import (
+	"commentTree/internal/app/domain"
+	"errors"
+	wbgin "github.com/wb-go/wbf/ginext"
+	"net/http"
+	"strconv"
+)
+
+type WebhookReqCreate struct {
+	URL       string   `json:"url" binding:"required"`
+	Secret    string   `json:"secret" binding:"required"`
+	Events    []string `json:"events"`
+	SubjectId string   `json:"subject_id"`
+}
+
+type WebhookHandler struct {
+	webhookService WebhookService
+}
+
+type WebhookService interface {
+	CreateSubscription(url, secret string, eventTypes []string, subjectID string) (*app.WebhookSubscription, error)
+	DeleteSubscription(id string) error
+	GetSubscription(id string) (*app.WebhookSubscription, error)
+	GetSubscriptions() ([]app.WebhookSubscription, error)
+	GetDeliveries(subscriptionID string, page, pageSize int) ([]app.WebhookDelivery, error)
+	Replay(subscriptionID, deliveryID string) (*app.WebhookDelivery, error)
+}
+
+func NewWebhookHandler(webhookService WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook godoc
+// @Summary      Create Webhook Subscription
+// @Description  Подписывает URL на события комментариев. Доставки подписываются HMAC-SHA256 секретом (заголовок X-Webhook-Signature).
+// @Description  URL должен вести на публичный адрес: loopback, частные и link-local сети отклоняются (400).
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        webhook  body  WebhookReqCreate  true  "Subscription to create"
+// @Success      201  {object}  app.WebhookSubscription  "Created subscription"
+// @Failure      400  {object}  ErrorResponse            "Invalid input data or internal target"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /admin/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(ctx *wbgin.Context) {
+	var req WebhookReqCreate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.webhookService.CreateSubscription(req.URL, req.Secret, req.Events, req.SubjectId)
+	if errors.Is(err, app.ErrWebhookTarget) {
+		ctx.JSON(http.StatusBadRequest, wbgin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, sub)
+}
+
+// GetWebhooks godoc
+// @Summary      List Webhook Subscriptions
+// @Tags         webhooks
+// @Produce      json
+// @Success      200  {array}   app.WebhookSubscription  "Subscriptions"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /admin/webhooks [get]
+func (h *WebhookHandler) GetWebhooks(ctx *wbgin.Context) {
+	subs, err := h.webhookService.GetSubscriptions()
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, subs)
+}
+
+// GetWebhook godoc
+// @Summary      Get Webhook Subscription
+// @Tags         webhooks
+// @Produce      json
+// @Param        id   path   string  true  "Subscription ID"
+// @Success      200  {object}  app.WebhookSubscription  "Subscription"
+// @Failure      404  {object}  ErrorResponse            "Subscription not found"
+// @Failure      503  {object}  ErrorResponse            "Service unavailable (DB error)"
+// @Router       /admin/webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(ctx *wbgin.Context) {
+	sub, err := h.webhookService.GetSubscription(ctx.Param("id"))
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, sub)
+}
+
+// DeleteWebhook godoc
+// @Summary      Delete Webhook Subscription
+// @Tags         webhooks
+// @Param        id   path   string  true  "Subscription ID"
+// @Success      204  {string}  string         "Subscription deleted"
+// @Failure      404  {object}  ErrorResponse  "Subscription not found"
+// @Failure      503  {object}  ErrorResponse  "Service unavailable (DB error)"
+// @Router       /admin/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(ctx *wbgin.Context) {
+	if err := h.webhookService.DeleteSubscription(ctx.Param("id")); err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetDeliveries godoc
+// @Summary      Webhook Delivery Log
+// @Description  Журнал доставок подписки, новые сверху
+// @Tags         webhooks
+// @Produce      json
+// @Param        id         path   string  true   "Subscription ID"
+// @Param        page       query  int     false  "Номер страницы" default(1)
+// @Param        page_size  query  int     false  "Размер страницы" default(50)
+// @Success      200  {array}   app.WebhookDelivery  "Deliveries"
+// @Failure      503  {object}  ErrorResponse        "Service unavailable (DB error)"
+// @Router       /admin/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(ctx *wbgin.Context) {
+	pageInt, _ := strconv.Atoi(ctx.Query("page"))
+	pageSizeInt, _ := strconv.Atoi(ctx.Query("page_size"))
+
+	deliveries, err := h.webhookService.GetDeliveries(ctx.Param("id"), pageInt, pageSizeInt)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// ReplayDelivery godoc
+// @Summary      Replay Webhook Delivery
+// @Description  Ставит доставку из журнала в очередь повторно с тем же телом и подписью; результат появится в журнале
+// @Tags         webhooks
+// @Produce      json
+// @Param        id           path  string  true  "Subscription ID"
+// @Param        delivery_id  path  string  true  "Delivery ID"
+// @Success      202  {object}  app.WebhookDelivery  "Delivery queued for replay"
+// @Failure      404  {object}  ErrorResponse        "Delivery not found"
+// @Failure      503  {object}  ErrorResponse        "Service unavailable (DB error)"
+// @Router       /admin/webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func (h *WebhookHandler) ReplayDelivery(ctx *wbgin.Context) {
+	delivery, err := h.webhookService.Replay(ctx.Param("id"), ctx.Param("delivery_id"))
+	if err != nil {
+		writeLookupError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, delivery)
+}
+
+// writeLookupError отвечает 404 для ненайденных объектов и 503 для остальных ошибок
+func writeLookupError(ctx *wbgin.Context, err error) {
+	if errors.Is(err, app.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, wbgin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusServiceUnavailable, wbgin.H{"error": err.Error()})
+}
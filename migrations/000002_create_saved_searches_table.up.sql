@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id UUID PRIMARY KEY,
+    query TEXT NOT NULL,
+    parentID UUID,
+    webhookURL TEXT NOT NULL DEFAULT '',
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id UUID PRIMARY KEY,
+    searchID UUID NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    commentID UUID NOT NULL,
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (searchID, commentID)
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS comment_changes;
+DROP TABLE IF EXISTS thread_revisions;
+DROP INDEX IF EXISTS comments_parentid_idx;
+DROP INDEX IF EXISTS comments_rootid_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS rootID;
@@ -0,0 +1,29 @@
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS rootID UUID;
+
+WITH RECURSIVE roots AS (
+    SELECT id, id AS rootID FROM comments WHERE ParentID IS NULL
+    UNION ALL
+    SELECT c.id, r.rootID
+    FROM comments c
+    INNER JOIN roots r ON c.ParentID = r.id
+)
+UPDATE comments c SET rootID = r.rootID FROM roots r WHERE c.id = r.id;
+
+UPDATE comments SET rootID = id WHERE rootID IS NULL;
+
+CREATE INDEX IF NOT EXISTS comments_rootid_idx ON comments (rootID);
+CREATE INDEX IF NOT EXISTS comments_parentid_idx ON comments (ParentID);
+
+CREATE TABLE IF NOT EXISTS thread_revisions (
+    rootID UUID PRIMARY KEY,
+    revision BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS comment_changes (
+    rootID UUID NOT NULL,
+    revision BIGINT NOT NULL,
+    commentID UUID NOT NULL,
+    type TEXT NOT NULL,
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (rootID, revision, commentID)
+);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY,
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    maxAttempts INT NOT NULL,
+    runAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    lockedUntil TIMESTAMP WITH TIME ZONE,
+    lastError TEXT NOT NULL DEFAULT '',
+    uniqueKey TEXT UNIQUE,
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS jobs_ready_idx ON jobs (runAt) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS jobs_status_idx ON jobs (status, updatedAt DESC);
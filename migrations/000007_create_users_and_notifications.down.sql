@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notifications;
+ALTER TABLE comments DROP COLUMN IF EXISTS authorID;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    email TEXT NOT NULL DEFAULT '',
+    notifyMode TEXT NOT NULL DEFAULT 'immediate',
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS authorID UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS comments_authorid_idx ON comments (authorID);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY,
+    userID UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    commentID UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    actorID UUID,
+    excerpt TEXT NOT NULL DEFAULT '',
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    sentAt TIMESTAMP WITH TIME ZONE,
+    UNIQUE (userID, commentID, kind)
+);
+
+CREATE INDEX IF NOT EXISTS notifications_unsent_idx ON notifications (userID) WHERE sentAt IS NULL;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS comment_mentions (
+    commentID UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    userID UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    spanOffset INT NOT NULL,
+    spanLength INT NOT NULL,
+    PRIMARY KEY (commentID, spanOffset)
+);
+
+CREATE INDEX IF NOT EXISTS comment_mentions_userid_idx ON comment_mentions (userID);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS inbox_items;
+DROP TABLE IF EXISTS thread_subscriptions;
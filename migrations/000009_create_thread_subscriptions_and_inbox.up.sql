@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS thread_subscriptions (
+    userID UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    commentID UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (userID, commentID)
+);
+
+CREATE INDEX IF NOT EXISTS thread_subscriptions_commentid_idx ON thread_subscriptions (commentID);
+
+-- Записи раскладываются при создании комментария, поэтому чтение и счетчик не обходят деревья
+CREATE TABLE IF NOT EXISTS inbox_items (
+    userID UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    commentID UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    readAt TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (userID, commentID)
+);
+
+CREATE INDEX IF NOT EXISTS inbox_items_user_createdat_idx ON inbox_items (userID, createdAt DESC);
+CREATE INDEX IF NOT EXISTS inbox_items_unread_idx ON inbox_items (userID) WHERE readAt IS NULL;
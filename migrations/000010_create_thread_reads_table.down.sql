@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS thread_reads;
+ALTER TABLE comments DROP COLUMN IF EXISTS revision;
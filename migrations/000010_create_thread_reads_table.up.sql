@@ -0,0 +1,14 @@
+-- Ревизия ветки, в которой комментарий был создан
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS revision BIGINT NOT NULL DEFAULT 0;
+
+UPDATE comments c SET revision = ch.revision
+FROM comment_changes ch
+WHERE ch.commentID = c.id AND ch.type = 'comment.created';
+
+CREATE TABLE IF NOT EXISTS thread_reads (
+    userID UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rootID UUID NOT NULL,
+    revision BIGINT NOT NULL,
+    updatedAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (userID, rootID)
+);
@@ -0,0 +1,11 @@
+-- Отрендеренный Markdown. Старые комментарии писались как обычный текст,
+-- поэтому переносятся экранированными абзацами без разбора разметки.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS html TEXT;
+
+UPDATE comments SET html = '<p>' || replace(
+    replace(replace(replace(replace(replace(text, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;'),
+    E'\n', '<br>') || '</p>'
+WHERE html IS NULL;
+
+ALTER TABLE comments ALTER COLUMN html SET NOT NULL;
+ALTER TABLE comments ALTER COLUMN html SET DEFAULT '';
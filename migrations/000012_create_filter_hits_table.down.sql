@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS comments_author_createdat_idx;
+DROP TABLE IF EXISTS filter_hits;
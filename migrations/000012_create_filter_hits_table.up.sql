@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS filter_hits (
+    id UUID PRIMARY KEY,
+    commentID UUID REFERENCES comments(id) ON DELETE CASCADE,
+    authorID UUID REFERENCES users(id) ON DELETE SET NULL,
+    filter TEXT NOT NULL,
+    action TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    excerpt TEXT NOT NULL,
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS filter_hits_createdat_idx ON filter_hits (createdAt DESC);
+CREATE INDEX IF NOT EXISTS filter_hits_commentid_idx ON filter_hits (commentID);
+
+-- Поиск дублей автора по тексту
+CREATE INDEX IF NOT EXISTS comments_author_createdat_idx ON comments (authorID, createdAt);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS thread_settings;
+DROP INDEX IF EXISTS comments_moderation_queue_idx;
+UPDATE comments SET status = 'deleted' WHERE status IN ('pending', 'hidden', 'rejected');
+ALTER TABLE comments DROP COLUMN IF EXISTS moderatedAt;
+ALTER TABLE comments DROP COLUMN IF EXISTS moderationReason;
@@ -0,0 +1,12 @@
+-- Жизненный цикл модерации: pending -> active | rejected, active -> hidden, а также deleted
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS moderationReason TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS moderatedAt TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS comments_moderation_queue_idx ON comments (status, createdAt)
+    WHERE status IN ('pending', 'hidden', 'rejected');
+
+CREATE TABLE IF NOT EXISTS thread_settings (
+    rootID UUID PRIMARY KEY,
+    premoderation BOOLEAN NOT NULL DEFAULT false,
+    updatedAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
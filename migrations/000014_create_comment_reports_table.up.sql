@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS comment_reports (
+    id UUID PRIMARY KEY,
+    commentID UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    reporterID UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (commentID, reporterID)
+);
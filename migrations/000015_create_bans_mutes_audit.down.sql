@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS user_mutes;
+DROP TABLE IF EXISTS bans;
+ALTER TABLE comments DROP COLUMN IF EXISTS ipHash;
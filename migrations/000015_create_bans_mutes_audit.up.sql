@@ -0,0 +1,36 @@
+-- Адреса авторов хранятся только в виде хэша
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS ipHash TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS bans (
+    id UUID PRIMARY KEY,
+    kind TEXT NOT NULL,
+    authorID UUID REFERENCES users(id) ON DELETE CASCADE,
+    ipHash TEXT NOT NULL DEFAULT '',
+    rootID UUID,
+    reason TEXT NOT NULL DEFAULT '',
+    expiresAt TIMESTAMP WITH TIME ZONE,
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    revokedAt TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS bans_author_idx ON bans (authorID) WHERE revokedAt IS NULL;
+CREATE INDEX IF NOT EXISTS bans_iphash_idx ON bans (ipHash) WHERE revokedAt IS NULL AND ipHash <> '';
+
+CREATE TABLE IF NOT EXISTS user_mutes (
+    userID UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mutedID UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (userID, mutedID)
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY,
+    actorID UUID,
+    action TEXT NOT NULL,
+    targetID UUID NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_createdat_idx ON audit_log (createdAt DESC);
+CREATE INDEX IF NOT EXISTS audit_log_targetid_idx ON audit_log (targetID);
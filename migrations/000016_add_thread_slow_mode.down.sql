@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS comments_root_author_idx;
+ALTER TABLE thread_settings DROP COLUMN IF EXISTS slowModeSeconds;
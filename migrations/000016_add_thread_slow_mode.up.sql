@@ -0,0 +1,4 @@
+-- Медленный режим: автор может писать в ветку не чаще одного раза за slowModeSeconds; 0 — выключен
+ALTER TABLE thread_settings ADD COLUMN IF NOT EXISTS slowModeSeconds INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS comments_root_author_idx ON comments (rootID, authorID, createdAt);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS comments_anonymous_created_idx;
+DROP TABLE IF EXISTS pow_redeemed;
@@ -0,0 +1,8 @@
+-- Использованные задачи proof-of-work: защита от повторного предъявления до истечения срока задачи
+CREATE TABLE IF NOT EXISTS pow_redeemed (
+    id TEXT PRIMARY KEY,
+    expiresAt TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS pow_redeemed_expires_idx ON pow_redeemed (expiresAt);
+CREATE INDEX IF NOT EXISTS comments_anonymous_created_idx ON comments (createdAt) WHERE authorID IS NULL;
@@ -0,0 +1,14 @@
+-- Ключи идемпотентности: status = 0, пока запрос выполняется; lockedUntil освобождает ключ упавшего запроса
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope TEXT NOT NULL,
+    key TEXT NOT NULL,
+    requestHash TEXT NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    body BYTEA,
+    createdAt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    lockedUntil TIMESTAMP WITH TIME ZONE NOT NULL,
+    expiresAt TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_idx ON idempotency_keys (expiresAt);
@@ -0,0 +1,9 @@
+-- Ключ ветки, слитой в другую: чтение fromRootID перенаправляется на toRootID.
+-- Цепочки не хранятся: при слиянии в ветку старые перенаправления на нее переписываются.
+CREATE TABLE IF NOT EXISTS thread_redirects (
+    fromRootID UUID PRIMARY KEY REFERENCES comments(id) ON DELETE CASCADE,
+    toRootID UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS thread_redirects_to_idx ON thread_redirects (toRootID);
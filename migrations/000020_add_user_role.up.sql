@@ -0,0 +1,4 @@
+-- Роль пользователя: moderator получает доступ к /api/admin. Модераторы назначаются вручную:
+-- UPDATE users SET role = 'moderator' WHERE name = '...';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator'));
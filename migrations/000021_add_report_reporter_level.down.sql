@@ -0,0 +1 @@
+ALTER TABLE comment_reports DROP COLUMN IF EXISTS reporterLevel;
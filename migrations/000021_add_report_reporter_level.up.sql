@@ -0,0 +1,2 @@
+-- Уровень доверия автора жалобы на момент жалобы: автоскрытие учитывает только жалобы от доверенных пользователей
+ALTER TABLE comment_reports ADD COLUMN IF NOT EXISTS reporterLevel INT NOT NULL DEFAULT 0;
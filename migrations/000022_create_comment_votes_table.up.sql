@@ -0,0 +1,9 @@
+-- Голоса пользователей за комментарии: +1 или -1, от пользователя на комментарий один голос.
+-- Сумма голосов за комментарии автора — показатель уровня доверия.
+CREATE TABLE IF NOT EXISTS comment_votes (
+    commentID UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    voterID UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    value SMALLINT NOT NULL CHECK (value IN (-1, 1)),
+    createdAt TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (commentID, voterID)
+);
@@ -0,0 +1,2 @@
+-- Событие, не доставленное за outbox.max_attempts попыток, помечается failedAt и больше не блокирует очередь
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS failedAt TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,4 @@
+-- Хеш токена доступа: POST /users выдает токен один раз, запросы передают его в Authorization: Bearer.
+-- У пользователей, созданных раньше, токена нет — им нужно зарегистрироваться заново
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokenHash TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS users_token_hash_idx ON users (tokenHash);